@@ -2,6 +2,7 @@ package win
 
 import (
 	"log"
+	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.1/glfw"
 )
 
@@ -11,6 +12,7 @@ type Window struct {
 	glfw *glfw.Window
 
 	inputManager *InputManager
+	gammaCorrection bool
 	firstFrame bool
 	dTime float64
 	lastFrameTime float64
@@ -22,6 +24,10 @@ func (w *Window) InputManager() *InputManager {
 
 func NewWindow(width, height int, title string) *Window {
 
+	// ask for a window that can convert the linear colors shaders write to sRGB,
+	// see SetGammaCorrection
+	glfw.WindowHint(glfw.SRGBCapable, glfw.True)
+
 	gWindow, err := glfw.CreateWindow(width, height, title, nil, nil)
 	if err != nil {
 		log.Fatalln(err)
@@ -40,6 +46,7 @@ func NewWindow(width, height int, title string) *Window {
 		height: height,
 		glfw: gWindow,
 		inputManager: im,
+		gammaCorrection: true,
 		firstFrame: true,
 	}
 }
@@ -83,8 +90,39 @@ func (w *Window) StartFrame() {
 	w.lastFrameTime  = curFrameTime
 
 	w.inputManager.CheckpointCursorChange()
+	w.inputManager.CheckpointKeys()
+
+	if w.inputManager.IsTriggered(TOGGLE_GAMMA_CORRECTION) {
+		w.gammaCorrection = !w.gammaCorrection
+	}
+	w.applyGammaCorrection()
 }
 
 func (w *Window) SinceLastFrame() float64 {
 	return w.dTime
 }
+
+// GammaCorrection returns whether colors written to the window are converted to sRGB.
+func (w *Window) GammaCorrection() bool {
+	return w.gammaCorrection
+}
+
+// SetGammaCorrection turns the conversion of colors written to the window from linear to sRGB
+// on or off, it takes effect at the next StartFrame. It starts on and G toggles it to compare.
+//
+// Lighting is only correct with linear values and textures are decoded from sRGB to linear
+// when they are sampled (see gfx/texture.go) but monitors expect sRGB. Without the conversion
+// everything looks too dark and light falls off too quickly.
+func (w *Window) SetGammaCorrection(enabled bool) {
+	w.gammaCorrection = enabled
+}
+
+// applyGammaCorrection sets up GL for the gamma correction setting, it can only be done once
+// GL has been initialized so it is done every frame
+func (w *Window) applyGammaCorrection() {
+	if w.gammaCorrection {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	} else {
+		gl.Disable(gl.FRAMEBUFFER_SRGB)
+	}
+}
@@ -5,8 +5,15 @@ layout (location = 1) in vec3 color;
 
 out vec3 ourColor; // Output a color to the fragment shader
 
+// vertex colors are picked like any other color, in sRGB, but blending them across the
+// triangle and writing them to the sRGB framebuffer both expect linear colors
+vec3 srgbToLinear(vec3 c)
+{
+    return mix(c / 12.92, pow((c + 0.055) / 1.055, vec3(2.4)), step(0.04045, c));
+}
+
 void main()
 {
     gl_Position = vec4(position, 1.0);
-    ourColor = color; // Set ourColor to the input color we got from the vertex data
+    ourColor = srgbToLinear(color); // Set ourColor to the input color we got from the vertex data
 }
@@ -36,6 +36,8 @@ func main() {
 	glfw.WindowHint(glfw.ContextVersionMinor, 1)
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	// lets GL convert the linear colors the shaders output to the sRGB the monitor expects
+	glfw.WindowHint(glfw.SRGBCapable, glfw.True)
 	window, err := glfw.CreateWindow(windowWidth, windowHeight, "basic textures", nil, nil)
 	if err != nil {
 		panic(err)
@@ -47,6 +49,10 @@ func main() {
 		panic(err)
 	}
 
+	// textures are decoded to linear colors when sampled (see gfx/texture.go) so the colors
+	// have to be encoded back to sRGB when they are written or everything looks too dark
+	gl.Enable(gl.FRAMEBUFFER_SRGB)
+
 	window.SetKeyCallback(keyCallback)
 
 	err = programLoop(window)
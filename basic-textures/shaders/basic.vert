@@ -7,9 +7,16 @@ layout (location = 2) in vec2 texCoord;
 out vec3 ourColor;
 out vec2 TexCoord;
 
+// vertex colors are picked like any other color, in sRGB, but blending them across the
+// triangle and writing them to the sRGB framebuffer both expect linear colors
+vec3 srgbToLinear(vec3 c)
+{
+    return mix(c / 12.92, pow((c + 0.055) / 1.055, vec3(2.4)), step(0.04045, c));
+}
+
 void main()
 {
     gl_Position = vec4(position, 1.0);
-    ourColor = color;       // pass the color on to the fragment shader
+    ourColor = srgbToLinear(color); // pass the color on to the fragment shader
     TexCoord = texCoord;    // pass the texture coords on to the fragment shader
 }
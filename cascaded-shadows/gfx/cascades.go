@@ -0,0 +1,328 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/cascaded-shadows/geom"
+)
+
+// MaxCascades must match MAX_CASCADES in shaders/phong.frag.
+const MaxCascades = 4
+
+var errCascadeCount = fmt.Errorf("the number of cascades must be between 1 and %d", MaxCascades)
+
+var errCascadeCamera = errors.New("the camera's far plane must be further than its near plane")
+
+// CascadedShadowMap covers a large area with a directional light's shadows. A single shadow
+// map stretched over everything the camera sees has far too few texels up close, so the view
+// frustum is cut into slices along its depth and each gets its own shadow map (a cascade).
+// Close slices are short so their cascades are sharp, far ones cover more with the same
+// number of texels where that is not noticeable. The cascades are the layers of one 2D array
+// texture.
+//
+//	csm.Fit(&sun, camTransform, lens.Fov, aspect, lens.Near, lens.Far)
+//	for i := 0; i < csm.NumCascades(); i++ {
+//		world.DrawCulled(csm.Begin(i), csm.Frustum(i), casters)
+//		csm.End()
+//	}
+//	program.Use()
+//	csm.SetUniforms(program, "cascades", camTransform, gl.TEXTURE2)
+type CascadedShadowMap struct {
+	// a disabled map is still bound by SetUniforms but the shader does not read it
+	Enabled bool
+
+	Bias ShadowBias
+
+	// how many texels around the one a fragment falls in are compared, see ShadowMap
+	PCFRadius int
+
+	// where the slices are cut, between evenly spaced (0) and spaced so each is the same
+	// number of times longer than the one before (1). Even slices waste texels close to the
+	// camera and logarithmic ones make the first slice tiny so a blend works best, this is
+	// the "practical split scheme" of Zhang et al. 2006, "Parallel-Split Shadow Maps".
+	SplitLambda float32
+
+	// the fraction of each cascade at its far end where it fades into the next one, so the
+	// change in detail does not show as a line. The last cascade fades out the shadows.
+	BlendWidth float32
+
+	// how far from the camera there are shadows, they are not noticeable past some distance
+	// and stopping earlier gives every cascade more detail. 0 goes to the camera's far plane.
+	MaxDistance float32
+
+	// how far towards the light beyond its slice a cascade still draws casters, ex: a tall
+	// building outside the view can shade what is in it
+	CasterDistance float32
+
+	// Stabilize keeps each cascade the same size however the camera turns and only moves it
+	// in whole texels. Otherwise the texels shift a little relative to the scene every frame
+	// and the edges of the shadows crawl ("shimmering").
+	Stabilize bool
+
+	cascades []cascade
+	fb       *Framebuffer
+	depth    *Program
+}
+
+type cascade struct {
+	far        float32 // the slice ends this far in front of the camera
+	lightSpace mgl32.Mat4
+}
+
+// NewCascadedShadowMap makes a map with numCascades cascades of size x size texels.
+func NewCascadedShadowMap(size, numCascades int) (*CascadedShadowMap, error) {
+	if numCascades < 1 || numCascades > MaxCascades {
+		return nil, errCascadeCount
+	}
+
+	csm := CascadedShadowMap{
+		Enabled:        true,
+		Bias:           DefaultShadowBias,
+		PCFRadius:      1,
+		SplitLambda:    0.75,
+		BlendWidth:     0.1,
+		CasterDistance: 50,
+		Stabilize:      true,
+		cascades:       make([]cascade, numCascades),
+	}
+
+	// the same depth only shaders as a single shadow map
+	var err error
+	csm.depth, err = newProgramFromFiles(filepath.Join(ShadowShaderDir, "depth.vert"),
+		filepath.Join(ShadowShaderDir, "depth.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csm.allocate(size); err != nil {
+		csm.Delete()
+		return nil, err
+	}
+	return &csm, nil
+}
+
+// allocate makes the array texture for size and the current number of cascades
+func (csm *CascadedShadowMap) allocate(size int) error {
+	if csm.fb != nil {
+		csm.fb.Delete()
+	}
+
+	var err error
+	csm.fb, err = NewFramebuffer(size, size, FramebufferSpec{
+		Depth: &Attachment{Format: gl.DEPTH_COMPONENT24, Layers: len(csm.cascades)},
+	})
+	if err != nil {
+		csm.fb = nil
+		return err
+	}
+
+	// lookups outside a cascade are lit, like a single shadow map
+	tex := csm.Texture()
+	tex.SetDepthCompare(true)
+	border := [4]float32{1, 1, 1, 1}
+	gl.BindTexture(tex.target, tex.handle)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	gl.TexParameterfv(tex.target, gl.TEXTURE_BORDER_COLOR, &border[0])
+	gl.BindTexture(tex.target, 0)
+	return nil
+}
+
+// NumCascades is how many slices the view is cut into.
+func (csm *CascadedShadowMap) NumCascades() int {
+	return len(csm.cascades)
+}
+
+// SetNumCascades reallocates the map with n cascades, they are empty until drawn again.
+func (csm *CascadedShadowMap) SetNumCascades(n int) error {
+	if n < 1 || n > MaxCascades {
+		return errCascadeCount
+	}
+	csm.cascades = make([]cascade, n)
+	return csm.allocate(csm.Size())
+}
+
+// Size is the width and height of each cascade in texels.
+func (csm *CascadedShadowMap) Size() int {
+	return csm.fb.Width()
+}
+
+// Splits returns the distance in front of the camera where each cascade ends, from the last Fit.
+func (csm *CascadedShadowMap) Splits() []float32 {
+	splits := make([]float32, len(csm.cascades))
+	for i, c := range csm.cascades {
+		splits[i] = c.far
+	}
+	return splits
+}
+
+// Texture is the 2D array depth texture, set up for comparison sampling with a
+// sampler2DArrayShadow. Layer i is cascade i.
+func (csm *CascadedShadowMap) Texture() *Texture {
+	return csm.fb.Depth()
+}
+
+// Fit cuts the view of a camera with the view matrix view and the given perspective (fovY in
+// degrees, like scene.Camera) into slices and points a cascade along light at each of them.
+// It has to be called whenever the camera or the light move, usually every frame.
+func (csm *CascadedShadowMap) Fit(light *DirectionalLight, view mgl32.Mat4, fovY, aspect, near, far float32) error {
+	if far <= near {
+		return errCascadeCamera
+	}
+	if csm.MaxDistance > near && csm.MaxDistance < far {
+		far = csm.MaxDistance
+	}
+
+	// the light's rotation alone, every cascade is a box in this space. It does not depend on
+	// where the cascades are so snapping positions in it to texels is stable.
+	dir := light.Direction.Normalize()
+	lightView := mgl32.LookAtV(mgl32.Vec3{}, dir, upFor(dir))
+
+	camToWorld := view.Inv()
+	tanHalfFov := float32(math.Tan(float64(mgl32.DegToRad(fovY)) / 2))
+
+	// the shader measures the first slice from the camera instead of the near plane
+	sliceNear, prevFar := near, float32(0)
+	blendLength := float32(0)
+	n := float32(len(csm.cascades))
+	for i := range csm.cascades {
+		// the practical split scheme, a blend of logarithmic and even splits
+		t := float32(i+1) / n
+		logSplit := near * float32(math.Pow(float64(far/near), float64(t)))
+		evenSplit := near + (far-near)*t
+		sliceFar := csm.SplitLambda*logSplit + (1-csm.SplitLambda)*evenSplit
+
+		// the previous cascade fades into this one over the end of its slice so this one has to
+		// start there
+		start := sliceNear - blendLength
+		blendLength = (sliceFar - prevFar) * csm.BlendWidth
+
+		center, radius := sliceSphere(start, sliceFar, tanHalfFov, aspect)
+		if !csm.Stabilize {
+			center, radius = sliceBox(start, sliceFar, tanHalfFov, aspect)
+		}
+		center = camToWorld.Mul4x1(center.Vec4(1)).Vec3()
+
+		csm.cascades[i] = cascade{
+			far:        sliceFar,
+			lightSpace: csm.fitCascade(lightView, center, radius),
+		}
+		sliceNear, prevFar = sliceFar, sliceFar
+	}
+	return nil
+}
+
+// fitCascade is the light's view and orthographic projection around the sphere at center
+func (csm *CascadedShadowMap) fitCascade(lightView mgl32.Mat4, center mgl32.Vec3, radius float32) mgl32.Mat4 {
+	c := lightView.Mul4x1(center.Vec4(1)).Vec3()
+
+	if csm.Stabilize {
+		// move in whole texels of this cascade so the scene lands on the same texels
+		texel := 2 * radius / float32(csm.Size())
+		c[0] = float32(math.Floor(float64(c[0]/texel))) * texel
+		c[1] = float32(math.Floor(float64(c[1]/texel))) * texel
+	}
+
+	// the light looks down -z, so the depth of a point is -z
+	depth := -c.Z()
+	project := mgl32.Ortho(c.X()-radius, c.X()+radius, c.Y()-radius, c.Y()+radius,
+		depth-radius-csm.CasterDistance, depth+radius)
+	return project.Mul4(lightView)
+}
+
+// sliceSphere is the smallest sphere around the part of the view between near and far, in
+// view space. It only depends on the distances and the lens so it is the same size however
+// the camera turns, which a box around the corners is not.
+func sliceSphere(near, far, tanHalfFov, aspect float32) (mgl32.Vec3, float32) {
+	// the corners are k times their distance away from the view axis
+	k2 := tanHalfFov * tanHalfFov * (1 + aspect*aspect)
+
+	// the center is on the view axis where it is as far from the near corners as from the
+	// far ones, unless that is past the far plane. Then the far corners are the furthest.
+	z := (far + near) * (1 + k2) / 2
+	if z > far {
+		z = far
+	}
+	radius := float32(math.Sqrt(float64((far-z)*(far-z) + far*far*k2)))
+
+	// rounded up so floating point noise does not change the size of the texels
+	radius = float32(math.Ceil(float64(radius*16))) / 16
+	return mgl32.Vec3{0, 0, -z}, radius
+}
+
+// sliceBox is a sphere around the center of the slice's corners that reaches the furthest
+// corner, it is smaller than sliceSphere for long slices but changes size as the camera turns
+func sliceBox(near, far, tanHalfFov, aspect float32) (mgl32.Vec3, float32) {
+	z := (near + far) / 2
+	halfHeight := far * tanHalfFov
+	halfWidth := halfHeight * aspect
+	radius := mgl32.Vec3{halfWidth, halfHeight, far - z}.Len()
+	return mgl32.Vec3{0, 0, -z}, radius
+}
+
+// Frustum is the box cascade i covers, for culling casters.
+func (csm *CascadedShadowMap) Frustum(i int) *geom.Frustum {
+	frustum := geom.NewFrustum(csm.cascades[i].lightSpace)
+	return &frustum
+}
+
+// Begin clears cascade i and sets up drawing the casters into it, it returns the program to
+// draw them with. The program has a "model" uniform like the lighting programs so the same
+// scene drawing code works for both.
+func (csm *CascadedShadowMap) Begin(i int) *Program {
+	csm.fb.SetLayer(i)
+	csm.fb.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(csm.Bias.Slope, csm.Bias.Constant)
+
+	csm.depth.Use()
+	lightSpace := csm.cascades[i].lightSpace
+	gl.UniformMatrix4fv(csm.depth.GetUniformLocation("lightSpace"), 1, false, &lightSpace[0])
+	return csm.depth
+}
+
+// End goes back to the framebuffer and viewport that were in use before Begin.
+func (csm *CascadedShadowMap) End() {
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	csm.fb.UnBind()
+}
+
+// SetUniforms binds the map to texUnit and sets the Cascades struct called name in prog,
+// which must be in use. view is the camera's view matrix since the shader works in view space.
+func (csm *CascadedShadowMap) SetUniforms(prog *Program, name string, view mgl32.Mat4, texUnit uint32) {
+	tex := csm.Texture()
+	tex.Bind(texUnit)
+	tex.SetUniform(prog.GetUniformLocation(name + ".map"))
+
+	gl.Uniform1i(prog.GetUniformLocation(name+".enabled"), boolToInt(csm.Enabled))
+	gl.Uniform1i(prog.GetUniformLocation(name+".count"), int32(len(csm.cascades)))
+	gl.Uniform1i(prog.GetUniformLocation(name+".pcfRadius"), int32(csm.PCFRadius))
+	gl.Uniform1f(prog.GetUniformLocation(name+".blend"), csm.BlendWidth)
+
+	viewToWorld := view.Inv()
+	for i, c := range csm.cascades {
+		gl.Uniform1f(prog.GetUniformLocation(fmt.Sprintf("%s.splits[%d]", name, i)), c.far)
+
+		// view space -> world space -> the cascade's clip space
+		fromView := c.lightSpace.Mul4(viewToWorld)
+		gl.UniformMatrix4fv(prog.GetUniformLocation(fmt.Sprintf("%s.fromView[%d]", name, i)),
+			1, false, &fromView[0])
+	}
+}
+
+func (csm *CascadedShadowMap) Delete() {
+	if csm.fb != nil {
+		csm.fb.Delete()
+	}
+	if csm.depth != nil {
+		csm.depth.Delete()
+	}
+}
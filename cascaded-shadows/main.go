@@ -0,0 +1,364 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Guest-Articles/2021/CSM
+
+A field of pillars far larger than a single shadow map can cover in detail, lit by a low sun.
+The camera's view is cut into slices along its depth and each slice gets its own shadow map
+(gfx.CascadedShadowMap), the cascades are the layers of one 2D array texture. Slices close to
+the camera are short so their shadows are sharp, the far ones cover a lot more ground where
+the lack of detail is not noticeable. Where one cascade ends it fades into the next so the
+change in detail does not show as a line.
+
+Each cascade is fit around a sphere that contains its slice and is only moved in whole
+texels, so the shadows stay still while the camera moves and turns. Turn that off to see
+their edges crawl.
+
+Press C to tint everything with the color of its cascade, P to toggle shadows, L to toggle
+the stabilization and N to cycle the number of cascades. [ and ] change the PCF radius, hold
+up and down to move the splits between even and logarithmic and left and right to change how
+much of each cascade blends into the next.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/cascaded-shadows/cam"
+	"github.com/cstegel/opengl-samples-golang/cascaded-shadows/gfx"
+	"github.com/cstegel/opengl-samples-golang/cascaded-shadows/scene"
+	"github.com/cstegel/opengl-samples-golang/cascaded-shadows/win"
+)
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 8
+
+const (
+	// the pillars stand on a grid this many cells out from the middle in every direction
+	fieldCells   = 20
+	fieldSpacing = 8.0
+
+	// how far the shadows reach, the field goes on much further
+	shadowDistance = 150
+	cascadeSize    = 2048
+	maxPCFRadius   = 4
+
+	// how fast holding the split and blend keys changes them
+	splitLambdaPerSecond = 0.5
+	blendWidthPerSecond  = 0.2
+)
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes []*scene.Node
+}
+
+func buildScene(cube, floor *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	// low in the sky so the shadows are long
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.6, -0.7, -0.4},
+		Ambient:   mgl32.Vec3{0.08, 0.08, 0.08},
+		Diffuse:   mgl32.Vec3{0.9, 0.85, 0.75},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	ground := s.Add(scene.NewNode("ground"))
+	ground.SetPosition(mgl32.Vec3{0, 0, 0})
+	ground.SetScale(mgl32.Vec3{2 * fieldCells * fieldSpacing * 1.5, 1, 2 * fieldCells * fieldSpacing * 1.5})
+	ground.Mesh = floor
+
+	// pillars of different heights, a little off the grid so the field does not look tiled
+	for x := -fieldCells; x <= fieldCells; x++ {
+		for z := -fieldCells; z <= fieldCells; z++ {
+			if x == 0 && z == 0 {
+				continue
+			}
+			h := hash(x, z)
+			height := 1 + 9*h*h
+			offset := mgl32.Vec3{2 * (hash(z, x) - 0.5), 0, 2 * (hash(x+z, z-x) - 0.5)}
+
+			pillar := s.Add(scene.NewNode(fmt.Sprintf("pillar %d %d", x, z)))
+			pillar.SetPosition(mgl32.Vec3{float32(x) * fieldSpacing, height / 2, float32(z) * fieldSpacing}.Add(offset))
+			pillar.SetScale(mgl32.Vec3{1.5, height, 1.5})
+			pillar.Rotate(mgl32.DegToRad(90*hash(x-z, x)), mgl32.Vec3{0, 1, 0})
+			pillar.Mesh = cube
+		}
+	}
+
+	// a few spinning crates in the middle, where the sharpest cascade is
+	for i := 0; i < 4; i++ {
+		angle := float64(i) * math.Pi / 2
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{2.5 * float32(math.Cos(angle)), 1.5, 2.5 * float32(math.Sin(angle))})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	return s, nodes
+}
+
+// hash is a number in [0, 1) that looks random but is always the same for x and z
+func hash(x, z int) float32 {
+	h := uint32(x)*73856093 ^ uint32(z)*19349663
+	h ^= h >> 13
+	h *= 0x5bd1e995
+	h ^= h >> 15
+	return float32(h%10000) / 10000
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Cascaded shadow maps")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	floor, err := gfx.NewPlaneMesh(2 * fieldCells * fieldSpacing).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	cascades, err := gfx.NewCascadedShadowMap(cascadeSize, gfx.MaxCascades)
+	if err != nil {
+		return err
+	}
+	defer cascades.Delete()
+	cascades.MaxDistance = shadowDistance
+	showCascades := false
+
+	world, nodes := buildScene(cube, floor)
+
+	// lens for the camera, it sees much further than the shadows reach
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 400}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 2, 8}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		dt := float32(window.SinceLastFrame())
+		input := window.InputManager()
+
+		if input.IsTriggered(win.TOGGLE_SHADOWS) {
+			cascades.Enabled = !cascades.Enabled
+		}
+		if input.IsTriggered(win.TOGGLE_CASCADE_COLORS) {
+			showCascades = !showCascades
+		}
+		if input.IsTriggered(win.TOGGLE_STABILIZE) {
+			cascades.Stabilize = !cascades.Stabilize
+		}
+		if input.IsTriggered(win.NEXT_CASCADE_COUNT) {
+			if err := cascades.SetNumCascades(cascades.NumCascades()%gfx.MaxCascades + 1); err != nil {
+				return err
+			}
+		}
+		if input.IsTriggered(win.MORE_PCF) && cascades.PCFRadius < maxPCFRadius {
+			cascades.PCFRadius++
+		}
+		if input.IsTriggered(win.LESS_PCF) && cascades.PCFRadius > 0 {
+			cascades.PCFRadius--
+		}
+		if input.IsActive(win.SPLIT_LAMBDA_UP) {
+			cascades.SplitLambda = clamp(cascades.SplitLambda+splitLambdaPerSecond*dt, 0, 1)
+		}
+		if input.IsActive(win.SPLIT_LAMBDA_DOWN) {
+			cascades.SplitLambda = clamp(cascades.SplitLambda-splitLambdaPerSecond*dt, 0, 1)
+		}
+		if input.IsActive(win.BLEND_WIDER) {
+			cascades.BlendWidth = clamp(cascades.BlendWidth+blendWidthPerSecond*dt, 0, 0.5)
+		}
+		if input.IsActive(win.BLEND_NARROWER) {
+			cascades.BlendWidth = clamp(cascades.BlendWidth-blendWidthPerSecond*dt, 0, 0.5)
+		}
+
+		// animate the nodes
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+
+		lights := world.Lights()
+		camTransform := camera.GetTransform()
+		aspect := float32(window.Width()) / float32(window.Height())
+
+		if cascades.Enabled && len(lights.Directional) > 0 {
+			err := cascades.Fit(&lights.Directional[0], camTransform, lens.Fov, aspect, lens.Near, lens.Far)
+			if err != nil {
+				return err
+			}
+
+			// every cascade only draws what is in its box
+			for i := 0; i < cascades.NumCascades(); i++ {
+				world.DrawCulled(cascades.Begin(i), cascades.Frustum(i), nil)
+				cascades.End()
+			}
+		}
+
+		window.SetTitle(fmt.Sprintf("Cascaded shadow maps - shadows %s, %d cascades split at %s "+
+			"(lambda %.2f, blend %.2f), stabilized %s, PCF radius %d, gamma correction %s",
+			onOff(cascades.Enabled), cascades.NumCascades(), describeSplits(cascades.Splits()),
+			cascades.SplitLambda, cascades.BlendWidth, onOff(cascades.Stabilize), cascades.PCFRadius,
+			onOff(window.GammaCorrection())))
+
+		// background color
+		gl.ClearColor(0.5, 0.7, 0.9, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		program.Use()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		projectTransform := lens.Projection(aspect)
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		setLightUniforms(program, lights, camTransform)
+
+		// bound even when shadows are off since samplers of different types can not share a
+		// texture unit
+		cascades.SetUniforms(program, "cascades", camTransform, gl.TEXTURE2)
+		gl.Uniform1i(program.GetUniformLocation("showCascades"), boolToInt(showCascades))
+
+		world.Draw(program, nil)
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+		cascades.Texture().UnBind()
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+// describeSplits lists the distances where the cascades end, ex: "9.8, 21.6, 46.0, 150.0"
+func describeSplits(splits []float32) string {
+	parts := make([]string, len(splits))
+	for i, split := range splits {
+		parts[i] = fmt.Sprintf("%.1f", split)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sets the lights in view space, a scene may have no sun or flashlight
+func setLightUniforms(program *gfx.Program, lights scene.Lights, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	numPointLights := len(lights.Point)
+	if numPointLights > maxPointLights {
+		numPointLights = maxPointLights
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+	for i := 0; i < numPointLights; i++ {
+		lights.Point[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+	}
+
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+}
+
+func clamp(v, min, max float32) float32 {
+	return float32(math.Max(float64(min), math.Min(float64(max), float64(v))))
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
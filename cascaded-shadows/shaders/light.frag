@@ -0,0 +1,13 @@
+#version 410 core
+
+// special fragment shader that is not affected by lighting
+// useful for debugging like showing locations of lights
+
+out vec4 color;
+
+uniform vec3 lightColor;
+
+void main()
+{
+	color = vec4(lightColor, 1.0f);
+}
@@ -0,0 +1,232 @@
+#version 410 core
+
+// must match maxPointLights in main.go
+#define MAX_POINT_LIGHTS 8
+
+// must match MaxCascades in gfx/cascades.go
+#define MAX_CASCADES 4
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D specular;
+	float shininess;
+};
+
+// these structs match the light types in gfx/light.go
+// all positions and directions are in view space
+
+struct DirLight {
+	vec3 direction;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+struct PointLight {
+	vec3 position;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+struct SpotLight {
+	vec3 position;
+	vec3 direction;
+	float innerCutoff;  // cosine of the angle
+	float outerCutoff;  // cosine of the angle
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+// a directional light's cascaded shadow map, see gfx/cascades.go
+struct Cascades {
+	bool enabled;
+	sampler2DArrayShadow map;  // layer i is cascade i
+	int count;
+
+	// cascade i covers the fragments up to splits[i] in front of the camera, fromView takes
+	// them from view space to its clip space
+	float splits[MAX_CASCADES];
+	mat4 fromView[MAX_CASCADES];
+
+	int pcfRadius;  // compares a (2 * pcfRadius + 1)^2 block of texels
+	float blend;    // the fraction of each cascade at its far end that fades into the next
+};
+
+in vec3 Normal;
+in vec3 FragPos;
+in vec2 TexCoords;
+out vec4 color;
+
+uniform Material material;
+uniform DirLight dirLight;
+uniform PointLight pointLights[MAX_POINT_LIGHTS];
+uniform int numPointLights;
+uniform SpotLight spotLight;
+
+// the scene may not have a sun or flashlight
+uniform bool hasDirLight;
+uniform bool hasSpotLight;
+
+uniform Cascades cascades;
+
+// tints every fragment with the color of the cascade its shadow comes from
+uniform bool showCascades;
+
+const vec3 cascadeColors[MAX_CASCADES] = vec3[](
+	vec3(1.0, 0.2, 0.2), vec3(0.2, 1.0, 0.2), vec3(0.2, 0.4, 1.0), vec3(1.0, 1.0, 0.2)
+);
+
+// the cascade for a fragment dist in front of the camera, or count when there is none
+int cascadeIndex(float dist)
+{
+	for (int i = 0; i < cascades.count; i++) {
+		if (dist < cascades.splits[i]) {
+			return i;
+		}
+	}
+	return cascades.count;
+}
+
+// the fraction of the light that reaches the fragment according to cascade i
+float cascadeShadowed(int i)
+{
+	// the projection is orthographic so w is 1
+	vec3 coords = (cascades.fromView[i] * vec4(FragPos, 1.0)).xyz * 0.5 + 0.5;
+
+	vec2 texelSize = 1.0 / vec2(textureSize(cascades.map, 0).xy);
+	float lit = 0.0;
+	for (int x = -cascades.pcfRadius; x <= cascades.pcfRadius; x++) {
+		for (int y = -cascades.pcfRadius; y <= cascades.pcfRadius; y++) {
+			vec2 offset = vec2(x, y) * texelSize;
+			lit += texture(cascades.map, vec4(coords.xy + offset, float(i), coords.z));
+		}
+	}
+
+	float width = float(2 * cascades.pcfRadius + 1);
+	return lit / (width * width);
+}
+
+// the fraction of the sun's light that reaches the fragment
+float sunShadowed()
+{
+	float dist = -FragPos.z;
+	int i = cascadeIndex(dist);
+	if (!cascades.enabled || i == cascades.count) {
+		return 1.0;
+	}
+
+	float lit = cascadeShadowed(i);
+
+	// fade into the next cascade over the end of this one, the next cascade was made to
+	// cover that part too. After the last one the shadows fade out.
+	float start = i == 0 ? 0.0 : cascades.splits[i - 1];
+	float blendLength = (cascades.splits[i] - start) * cascades.blend;
+	float fade = (cascades.splits[i] - dist) / max(blendLength, 0.0001);
+	if (fade < 1.0) {
+		float next = i + 1 < cascades.count ? cascadeShadowed(i + 1) : 1.0;
+		lit = mix(next, lit, fade);
+	}
+	return lit;
+}
+
+// the parts of phong lighting shared by every light type, dirToLight must be normalized
+// lit scales the diffuse and specular parts, shadows still get the ambient part
+vec3 phong(vec3 dirToLight, vec3 norm, vec3 dirToView, float lit,
+           vec3 lightAmbient, vec3 lightDiffuse, vec3 lightSpecular)
+{
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 specularColor = vec3(texture(material.specular, TexCoords));
+
+	vec3 ambient = lightAmbient * diffuseColor;
+
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = lightDiffuse * lightNormalDiff * diffuseColor;
+
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = lightSpecular * spec * specularColor;
+
+	return ambient + lit * (diffuse + specular);
+}
+
+// intensity left after the light has travelled dist
+float attenuation(float dist, float constant, float linear, float quadratic)
+{
+	return 1.0 / (constant + linear * dist + quadratic * (dist * dist));
+}
+
+vec3 calcDirLight(DirLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(-light.direction);
+	return phong(dirToLight, norm, dirToView, sunShadowed(),
+	             light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcPointLight(PointLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	return decay * phong(dirToLight, norm, dirToView, 1.0,
+	                     light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcSpotLight(SpotLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	// 1 inside the inner cone, 0 outside the outer cone and a smooth blend between them
+	float theta = dot(dirToLight, normalize(-light.direction));
+	float epsilon = light.innerCutoff - light.outerCutoff;
+	float intensity = clamp((theta - light.outerCutoff) / epsilon, 0.0, 1.0);
+
+	// keep the ambient term so the area outside the cone is not pitch black
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 ambient = light.ambient * diffuseColor;
+	vec3 lit = phong(dirToLight, norm, dirToView, 1.0, vec3(0.0), light.diffuse, light.specular);
+
+	return decay * (ambient + intensity * lit);
+}
+
+void main()
+{
+	vec3 norm = normalize(Normal);
+	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
+	vec3 dirToView = normalize(viewPos - FragPos);
+
+	vec3 result = vec3(0.0f);
+	if (hasDirLight) {
+		result += calcDirLight(dirLight, norm, dirToView);
+	}
+	for (int i = 0; i < numPointLights; i++) {
+		result += calcPointLight(pointLights[i], norm, dirToView);
+	}
+	if (hasSpotLight) {
+		result += calcSpotLight(spotLight, norm, dirToView);
+	}
+
+	if (showCascades && cascades.enabled) {
+		int i = cascadeIndex(-FragPos.z);
+		if (i < cascades.count) {
+			result = mix(result, cascadeColors[i], 0.3);
+		}
+	}
+
+	color = vec4(result, 1.0f);
+}
@@ -0,0 +1,14 @@
+#version 410 core
+
+// a single triangle covering the viewport with no vertex data, vertices 0, 1 and 2 are
+// placed at (-1, -1), (3, -1) and (-1, 3) so the part inside is the [-1, 1] square
+
+out vec2 TexCoords;
+
+void main()
+{
+	vec2 corner = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+
+	TexCoords = corner;
+	gl_Position = vec4(corner * 2.0 - 1.0, 0.0, 1.0);
+}
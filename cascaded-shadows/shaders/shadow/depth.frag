@@ -0,0 +1,7 @@
+#version 410 core
+
+// nothing to do, the depth buffer is written without a color output
+
+void main()
+{
+}
@@ -0,0 +1,13 @@
+#version 410 core
+
+// draws the casters into a shadow map from the light's point of view, see gfx/shadow.go
+
+layout (location = 0) in vec3 position;
+
+uniform mat4 model;
+uniform mat4 lightSpace;
+
+void main()
+{
+	gl_Position = lightSpace * model * vec4(position, 1.0);
+}
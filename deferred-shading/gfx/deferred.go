@@ -0,0 +1,319 @@
+package gfx
+
+import (
+	"math"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/deferred-shading/geom"
+)
+
+// light types of shaders/deferred/lighting.frag
+const (
+	deferredDirectional = iota
+	deferredPoint
+	deferredSpot
+)
+
+// how finely the light volumes are tessellated, they only need to cover the lights
+const volumeSegments = 16
+
+// the flat faces of the volumes cut inside the round shapes they approximate, by at most the
+// cosine of half the angle between neighbouring vertices around and from pole to pole, scaling
+// them up by this much makes them cover all of it
+var volumeCover = float32(1 / (math.Cos(math.Pi/volumeSegments) * math.Cos(math.Pi/volumeSegments)))
+
+// LightStats counts what happened to the lights in a DeferredLighting draw.
+type LightStats struct {
+	Drawn  int
+	Culled int // the light volume is outside the view frustum
+}
+
+// DeferredLighting lights the surfaces stored in a GBuffer. A directional light reaches
+// everything so it is a fullscreen pass but point and spot lights only reach so far. They are
+// drawn as light volumes, a sphere around a point light and a cone around a spot light, that
+// only run the lighting shader for the pixels they cover. The lights add up with additive
+// blending.
+//
+// Only the back faces of a volume are drawn and only where they are behind the scene's depth,
+// that is where a surface is in front of the back of the volume. That still works when the
+// camera is inside the volume and the front faces are behind it. The shader skips the pixels
+// whose surface is in front of the volume as well, they are too far from the light.
+type DeferredLighting struct {
+	// the intensity where a light is considered to have faded out and its volume ends, see
+	// PointLight.Range
+	Cutoff float32
+
+	fullscreen *Program
+	volume     *Program
+	outline    *Program
+
+	sphere *VertexArray
+	cone   *VertexArray
+
+	// the fullscreen triangle's positions come from gl_VertexID but the core profile still
+	// requires a vertex array to be bound to draw
+	vao uint32
+}
+
+// NewDeferredLighting loads the lighting shaders and makes the light volume meshes.
+func NewDeferredLighting() (*DeferredLighting, error) {
+	dl := DeferredLighting{Cutoff: 5.0 / 256}
+
+	var err error
+	dl.fullscreen, err = newProgramFromFiles(filepath.Join(DeferredShaderDir, "fullscreen.vert"),
+		filepath.Join(DeferredShaderDir, "lighting.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	dl.volume, err = newProgramFromFiles(filepath.Join(DeferredShaderDir, "volume.vert"),
+		filepath.Join(DeferredShaderDir, "lighting.frag"))
+	if err != nil {
+		dl.Delete()
+		return nil, err
+	}
+
+	dl.outline, err = newProgramFromFiles(filepath.Join(DeferredShaderDir, "volume.vert"),
+		filepath.Join(DeferredShaderDir, "outline.frag"))
+	if err != nil {
+		dl.Delete()
+		return nil, err
+	}
+
+	dl.sphere, err = NewSphereMesh(volumeSegments/2, volumeSegments).Upload()
+	if err != nil {
+		dl.Delete()
+		return nil, err
+	}
+
+	dl.cone, err = NewConeMesh(volumeSegments).Upload()
+	if err != nil {
+		dl.Delete()
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &dl.vao)
+
+	return &dl, nil
+}
+
+// lightVolume is where a light is drawn and how far it reaches
+type lightVolume struct {
+	mesh   *VertexArray
+	model  mgl32.Mat4
+	bounds geom.Sphere
+	reach  float32
+}
+
+func (dl *DeferredLighting) pointVolume(l *PointLight) lightVolume {
+	return dl.sphereVolume(l.Position, l.Range(dl.Cutoff))
+}
+
+func (dl *DeferredLighting) sphereVolume(center mgl32.Vec3, reach float32) lightVolume {
+	size := 2 * reach * volumeCover // the sphere mesh has a diameter of 1
+
+	return lightVolume{
+		mesh:   dl.sphere,
+		model:  mgl32.Translate3D(center.X(), center.Y(), center.Z()).Mul4(mgl32.Scale3D(size, size, size)),
+		bounds: geom.Sphere{Center: center, Radius: reach},
+		reach:  reach,
+	}
+}
+
+func (dl *DeferredLighting) spotVolume(l *SpotLight) lightVolume {
+	reach := l.Range(dl.Cutoff)
+
+	// a cone can not be wider than a half space, a sphere covers the light in every direction
+	if l.OuterCutoff >= 89 {
+		return dl.sphereVolume(l.Position, reach)
+	}
+
+	// the light reaches every point within reach and inside the cone, that is all inside a cone
+	// as tall as reach since none of it is further along the cone's axis
+	radius := reach * float32(math.Tan(float64(mgl32.DegToRad(l.OuterCutoff)))) * volumeCover
+
+	// the mesh's tip is at the top, move it to the origin and point the cone down the light
+	dir := l.Direction.Normalize()
+	model := mgl32.Translate3D(l.Position.X(), l.Position.Y(), l.Position.Z()).
+		Mul4(mgl32.QuatBetweenVectors(mgl32.Vec3{0, -1, 0}, dir).Mat4()).
+		Mul4(mgl32.Scale3D(2*radius, reach, 2*radius)).
+		Mul4(mgl32.Translate3D(0, -0.5, 0))
+
+	return lightVolume{
+		mesh:  dl.cone,
+		model: model,
+		bounds: geom.Sphere{
+			Center: l.Position.Add(dir.Mul(reach / 2)),
+			Radius: float32(math.Hypot(float64(reach/2), float64(radius))),
+		},
+		reach: reach,
+	}
+}
+
+// useProgram puts prog in use with the G-buffer and the camera set
+func (dl *DeferredLighting) useProgram(prog *Program, gb *GBuffer, view, project mgl32.Mat4) {
+	prog.Use()
+	gb.BindTextures(prog, gl.TEXTURE0)
+	gl.Uniform2f(prog.GetUniformLocation("screenSize"), float32(gb.Width()), float32(gb.Height()))
+
+	inverseProject := project.Inv()
+	gl.UniformMatrix4fv(prog.GetUniformLocation("inverseProject"), 1, false, &inverseProject[0])
+	gl.UniformMatrix4fv(prog.GetUniformLocation("view"), 1, false, &view[0])
+	gl.UniformMatrix4fv(prog.GetUniformLocation("project"), 1, false, &project[0])
+}
+
+func drawVolume(prog *Program, vol lightVolume) {
+	gl.UniformMatrix4fv(prog.GetUniformLocation("model"), 1, false, &vol.model[0])
+	vol.mesh.Bind()
+	vol.mesh.Draw()
+}
+
+// Draw adds the light of every light to the bound framebuffer, which must be the size of the
+// G-buffer and have a copy of its depth (see GBuffer.BlitDepth). view and project are the
+// camera's, the same the G-buffer was drawn with. Point and spot lights whose volumes are
+// outside the camera's view are skipped. Blending and face culling are left off.
+func (dl *DeferredLighting) Draw(gb *GBuffer, dirs []DirectionalLight, points []PointLight,
+	spots []SpotLight, view, project mgl32.Mat4) LightStats {
+
+	stats := LightStats{}
+	frustum := geom.NewFrustum(project.Mul4(view))
+
+	// the lights add up and the volumes must not hide each other
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.ONE, gl.ONE)
+	gl.DepthMask(false)
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+
+	gl.Disable(gl.DEPTH_TEST)
+	dl.useProgram(dl.fullscreen, gb, view, project)
+	gl.Uniform1i(dl.fullscreen.GetUniformLocation("lightType"), deferredDirectional)
+	gl.BindVertexArray(dl.vao)
+	for i := range dirs {
+		dirs[i].SetUniforms(dl.fullscreen, "dirLight", view)
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+		stats.Drawn++
+	}
+
+	// back faces behind the scene, see the type's comment. Volumes reaching past the far
+	// plane are clamped to it instead of being clipped.
+	gl.Enable(gl.DEPTH_TEST)
+	gl.DepthFunc(gl.GEQUAL)
+	gl.Enable(gl.CULL_FACE)
+	gl.CullFace(gl.FRONT)
+	gl.Enable(gl.DEPTH_CLAMP)
+
+	dl.useProgram(dl.volume, gb, view, project)
+	typeLoc := dl.volume.GetUniformLocation("lightType")
+	rangeLoc := dl.volume.GetUniformLocation("range")
+
+	gl.Uniform1i(typeLoc, deferredPoint)
+	for i := range points {
+		vol := dl.pointVolume(&points[i])
+		if !frustum.IntersectsSphere(vol.bounds) {
+			stats.Culled++
+			continue
+		}
+		points[i].SetUniforms(dl.volume, "pointLight", view)
+		gl.Uniform1f(rangeLoc, vol.reach)
+		drawVolume(dl.volume, vol)
+		stats.Drawn++
+	}
+
+	gl.Uniform1i(typeLoc, deferredSpot)
+	for i := range spots {
+		vol := dl.spotVolume(&spots[i])
+		if !frustum.IntersectsSphere(vol.bounds) {
+			stats.Culled++
+			continue
+		}
+		spots[i].SetUniforms(dl.volume, "spotLight", view)
+		gl.Uniform1f(rangeLoc, vol.reach)
+		drawVolume(dl.volume, vol)
+		stats.Drawn++
+	}
+	gl.BindVertexArray(0)
+	gb.UnBindTextures()
+
+	gl.Disable(gl.DEPTH_CLAMP)
+	gl.CullFace(gl.BACK)
+	gl.Disable(gl.CULL_FACE)
+	gl.DepthFunc(gl.LESS)
+	gl.DepthMask(true)
+	gl.Disable(gl.BLEND)
+	if !depthTest {
+		gl.Disable(gl.DEPTH_TEST)
+	}
+
+	return stats
+}
+
+// DrawVolumes draws the outlines of the point and spot lights' volumes in the colors of their
+// lights, hidden behind the scene like everything else.
+func (dl *DeferredLighting) DrawVolumes(points []PointLight, spots []SpotLight, view, project mgl32.Mat4) {
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.LINE)
+
+	dl.outline.Use()
+	gl.UniformMatrix4fv(dl.outline.GetUniformLocation("view"), 1, false, &view[0])
+	gl.UniformMatrix4fv(dl.outline.GetUniformLocation("project"), 1, false, &project[0])
+	for i := range points {
+		setUniformVec3(dl.outline, "lightColor", points[i].Diffuse)
+		drawVolume(dl.outline, dl.pointVolume(&points[i]))
+	}
+	for i := range spots {
+		setUniformVec3(dl.outline, "lightColor", spots[i].Diffuse)
+		drawVolume(dl.outline, dl.spotVolume(&spots[i]))
+	}
+	gl.BindVertexArray(0)
+
+	gl.PolygonMode(gl.FRONT_AND_BACK, gl.FILL)
+}
+
+func (dl *DeferredLighting) Delete() {
+	for _, prog := range []*Program{dl.fullscreen, dl.volume, dl.outline} {
+		if prog != nil {
+			prog.Delete()
+		}
+	}
+	for _, mesh := range []*VertexArray{dl.sphere, dl.cone} {
+		if mesh != nil {
+			mesh.Delete()
+		}
+	}
+	gl.DeleteVertexArrays(1, &dl.vao)
+}
+
+var shaderTypes = map[string]uint32{
+	".vert": gl.VERTEX_SHADER,
+	".geom": gl.GEOMETRY_SHADER,
+	".frag": gl.FRAGMENT_SHADER,
+}
+
+// newProgramFromFiles compiles the shaders in files, whose types come from their extensions,
+// and links them
+func newProgramFromFiles(files ...string) (*Program, error) {
+	var shaders []*Shader
+	deleteShaders := func() {
+		for _, shader := range shaders {
+			shader.Delete()
+		}
+	}
+
+	for _, file := range files {
+		shader, err := NewShaderFromFile(file, shaderTypes[filepath.Ext(file)])
+		if err != nil {
+			deleteShaders()
+			return nil, err
+		}
+		shaders = append(shaders, shader)
+	}
+
+	prog, err := NewProgram(shaders...)
+	if err != nil {
+		deleteShaders()
+		return nil, err
+	}
+	return prog, nil
+}
@@ -0,0 +1,150 @@
+package gfx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// VertexAttrib describes a single attribute of an interleaved vertex.
+type VertexAttrib struct {
+	Index      uint32 // matches layout (location = Index) in the vertex shader
+	Size       int32  // number of components, 1-4
+	Type       uint32 // gl.FLOAT, the only type Pack supports
+	Normalized bool   // integer types are read as [0, 1] (unsigned) or [-1, 1] (signed) floats
+}
+
+// FloatAttrib is a plain 32-bit float attribute, ex: FloatAttrib(0, 3) for positions.
+func FloatAttrib(index uint32, size int32) VertexAttrib {
+	return VertexAttrib{Index: index, Size: size, Type: gl.FLOAT}
+}
+
+// ByteSize is the number of bytes the attribute takes up in one vertex.
+func (a VertexAttrib) ByteSize() int {
+	return int(a.Size) * componentSize(a.Type)
+}
+
+func componentSize(glType uint32) int {
+	switch glType {
+	case gl.BYTE, gl.UNSIGNED_BYTE:
+		return 1
+	case gl.SHORT, gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// VertexLayout is the set of attributes making up one interleaved vertex.
+// Every attribute starts on a 4 byte boundary as recommended for vertex fetching.
+type VertexLayout struct {
+	attribs []VertexAttrib
+	offsets []int
+	stride  int
+}
+
+var errVertexDataSize = errors.New("vertex data length does not match the layout")
+
+// NewVertexLayout panics when an attribute does not have 1-4 components, layouts are
+// written in code rather than read from data.
+func NewVertexLayout(attribs ...VertexAttrib) *VertexLayout {
+	for _, attrib := range attribs {
+		if attrib.Size < 1 || attrib.Size > 4 {
+			panic(fmt.Sprintf("gfx: vertex attribute %d has %d components", attrib.Index, attrib.Size))
+		}
+	}
+
+	layout := VertexLayout{
+		attribs: attribs,
+		offsets: make([]int, len(attribs)),
+	}
+
+	for i, attrib := range attribs {
+		layout.offsets[i] = layout.stride
+		layout.stride += align4(attrib.ByteSize())
+	}
+
+	return &layout
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// Stride is the size in bytes of one whole vertex (sum of aligned attrib sizes).
+func (l *VertexLayout) Stride() int {
+	return l.stride
+}
+
+func (l *VertexLayout) Attribs() []VertexAttrib {
+	return l.attribs
+}
+
+// Offset returns the byte offset of the i-th attribute within a vertex.
+func (l *VertexLayout) Offset(i int) int {
+	return l.offsets[i]
+}
+
+// Enable sets up the attribute pointers for this layout.
+// The VAO and the ARRAY_BUFFER holding the vertices must be bound first.
+func (l *VertexLayout) Enable() {
+	for i, attrib := range l.attribs {
+		gl.VertexAttribPointer(attrib.Index, attrib.Size, attrib.Type, attrib.Normalized,
+			int32(l.stride), gl.PtrOffset(l.offsets[i]))
+		gl.EnableVertexAttribArray(attrib.Index)
+	}
+}
+
+// Pack converts float data into interleaved vertices of this layout.
+// There is one stream per attribute holding Size floats per vertex, so
+// Pack(positions, normals) for a position+normal layout.
+func (l *VertexLayout) Pack(streams ...[]float32) ([]byte, error) {
+	if len(streams) != len(l.attribs) {
+		return nil, fmt.Errorf("%v: got %d streams for %d attributes",
+			errVertexDataSize, len(streams), len(l.attribs))
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+
+	numVertices := 0
+	for i, stream := range streams {
+		n := int(l.attribs[i].Size)
+		if len(stream)%n != 0 {
+			return nil, fmt.Errorf("%v: attribute %d has %d floats, not a whole number of %d per vertex",
+				errVertexDataSize, l.attribs[i].Index, len(stream), n)
+		}
+		if i == 0 {
+			numVertices = len(stream) / n
+		} else if len(stream)/n != numVertices {
+			return nil, fmt.Errorf("%v: attribute %d has %d vertices, attribute %d has %d",
+				errVertexDataSize, l.attribs[i].Index, len(stream)/n, l.attribs[0].Index, numVertices)
+		}
+	}
+
+	data := make([]byte, numVertices*l.stride)
+	for v := 0; v < numVertices; v++ {
+		for i, attrib := range l.attribs {
+			n := int(attrib.Size)
+			putComponents(data[v*l.stride+l.offsets[i]:], attrib, streams[i][v*n:(v+1)*n])
+		}
+	}
+
+	return data, nil
+}
+
+// GL reads vertex data in the machine's byte order which is little-endian on every
+// platform that these samples run on.
+func putComponents(dst []byte, attrib VertexAttrib, src []float32) {
+	switch attrib.Type {
+	case gl.FLOAT:
+		for i, f := range src {
+			binary.LittleEndian.PutUint32(dst[i*4:], math.Float32bits(f))
+		}
+	default:
+		panic(fmt.Sprintf("gfx: packing vertex attribute type 0x%x is not supported", attrib.Type))
+	}
+}
@@ -0,0 +1,545 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-Lighting/Deferred-Shading
+
+A courtyard of crates at night lit by a swarm of small colored lights, two sweeping spot lights
+and a dim moon. Forward rendering lights every fragment of every object with every light in
+one pass, so the cost grows with both and the shader only has room for so many lights. Here
+only the nearest ones fit (see maxPointLights), the rest are missing.
+
+Deferred shading splits that up. The opaque scene is drawn once into a G-buffer
+(gfx.GBuffer) that stores the albedo, normal, specular color, shininess and depth of the
+closest surface at each pixel. Each light is then drawn on its own as the shape around what it
+can reach (gfx.DeferredLighting) and only lights the pixels that shape covers, adding to the
+others. The cost of a light is the number of pixels it covers so hundreds of them are cheap.
+
+Blending can not be deferred since the G-buffer only holds one surface per pixel, so the
+panes of glass are drawn afterwards the forward way, sorted back to front and hidden behind
+the opaque scene by its depth.
+
+Press F to switch between forward and deferred shading, V to cycle through the images in the
+G-buffer and L to show the light volumes. Up and down double and halve the number of lights.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/deferred-shading/cam"
+	"github.com/cstegel/opengl-samples-golang/deferred-shading/gfx"
+	"github.com/cstegel/opengl-samples-golang/deferred-shading/scene"
+	"github.com/cstegel/opengl-samples-golang/deferred-shading/win"
+)
+
+// must match MAX_POINT_LIGHTS and MAX_SPOT_LIGHTS in shaders/phong.frag
+const (
+	maxPointLights = 16
+	maxSpotLights  = 4
+)
+
+const (
+	// the crates stand on a grid this many cells out from the middle in every direction
+	gridCells   = 3
+	gridSpacing = 6.0
+
+	// the number of lights in the swarm, up and down change how many of them are on
+	maxSwarm   = 512
+	minSwarm   = 4
+	startSwarm = 128
+)
+
+// a light of the swarm going around in a circle
+type wanderer struct {
+	node   *scene.Node
+	center mgl32.Vec3
+	radius float32
+	speed  float32 // radians per second, negative goes the other way around
+	phase  float32
+}
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes []*scene.Node
+	spots []*scene.Node
+	swarm []wanderer
+	panes []*scene.Node
+}
+
+// buildScene makes the opaque world and the panes of glass, which are kept apart since they
+// are drawn differently
+func buildScene(cube, floor, pane, bulb *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	// so dim that the scene is mostly lit by the swarm
+	moon := s.Add(scene.NewNode("moon"))
+	moon.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{0.3, -1.0, -0.5},
+		Ambient:   mgl32.Vec3{0.02, 0.02, 0.03},
+		Diffuse:   mgl32.Vec3{0.06, 0.07, 0.1},
+		Specular:  mgl32.Vec3{0.05, 0.05, 0.05},
+	}
+
+	ground := s.Add(scene.NewNode("ground"))
+	ground.SetScale(mgl32.Vec3{60, 1, 60})
+	ground.Mesh = floor
+
+	for x := -gridCells; x <= gridCells; x++ {
+		for z := -gridCells; z <= gridCells; z++ {
+			height := float32(1 + (x+z+2*gridCells)%3)
+			node := s.Add(scene.NewNode(fmt.Sprintf("crate %d %d", x, z)))
+			node.SetPosition(mgl32.Vec3{float32(x) * gridSpacing, height / 2, float32(z) * gridSpacing})
+			node.SetScale(mgl32.Vec3{1.5, height, 1.5})
+			node.Mesh = cube
+		}
+	}
+
+	// a few spinning crates in the middle
+	for i := 0; i < 4; i++ {
+		angle := float64(i) * math.Pi / 2
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{
+			gridSpacing/2 + 1.5*float32(math.Cos(angle)), 1.5, gridSpacing/2 + 1.5*float32(math.Sin(angle)),
+		})
+		node.SetScale(mgl32.Vec3{0.8, 0.8, 0.8})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	// the spot lights point down the node's -z like a camera, their gizmos are drawn in their color
+	for i, x := range []float32{-gridSpacing, gridSpacing} {
+		lamp := s.Add(scene.NewNode(fmt.Sprintf("lamp %d", i)))
+		lamp.SetPosition(mgl32.Vec3{x, 8, 0})
+		lamp.SetScale(mgl32.Vec3{0.3, 0.3, 0.3})
+		lamp.Mesh = cube
+		lamp.SpotLight = &gfx.SpotLight{
+			Direction:   mgl32.Vec3{0, 0, -1},
+			InnerCutoff: 15,
+			OuterCutoff: 22,
+			Diffuse:     mgl32.Vec3{1.0, 0.85, 0.6},
+			Specular:    mgl32.Vec3{1.0, 0.85, 0.6},
+			Attenuation: gfx.AttenuationForDistance(32),
+		}
+		nodes.spots = append(nodes.spots, lamp)
+	}
+
+	// the swarm is the same every run, it wanders between the crates close to the ground
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < maxSwarm; i++ {
+		color := hueColor(random.Float32())
+		light := s.Add(scene.NewNode(fmt.Sprintf("point light %d", i)))
+		light.SetScale(mgl32.Vec3{0.1, 0.1, 0.1})
+		light.Mesh = bulb
+		light.PointLight = &gfx.PointLight{
+			Diffuse:     color,
+			Specular:    color,
+			Attenuation: gfx.AttenuationForDistance(7),
+		}
+
+		extent := float32((gridCells + 1) * gridSpacing)
+		speed := 0.3 + 0.7*random.Float32()
+		if random.Intn(2) == 0 {
+			speed = -speed
+		}
+		nodes.swarm = append(nodes.swarm, wanderer{
+			node:   light,
+			center: mgl32.Vec3{extent * (2*random.Float32() - 1), 0.3 + 1.5*random.Float32(), extent * (2*random.Float32() - 1)},
+			radius: 1 + 2*random.Float32(),
+			speed:  speed,
+			phase:  2 * math.Pi * random.Float32(),
+		})
+	}
+
+	// the panes stand in the gaps of the grid facing along z, the plane faces up so it is
+	// turned to stand on its edge
+	for i, pos := range []mgl32.Vec3{{-3, 1.5, 6}, {3, 1.5, 0}, {-3, 1.5, -6}, {9, 1.5, 6}, {-9, 1.5, -12}} {
+		node := scene.NewNode(fmt.Sprintf("pane %d", i))
+		node.SetPosition(pos)
+		node.Rotate(mgl32.DegToRad(90), mgl32.Vec3{1, 0, 0})
+		node.SetScale(mgl32.Vec3{3, 1, 3})
+		node.Mesh = pane
+		nodes.panes = append(nodes.panes, node)
+	}
+
+	return s, nodes
+}
+
+// hueColor is the fully saturated color with hue h in [0, 1), which goes red, yellow, green,
+// cyan, blue, magenta and back to red
+func hueColor(h float32) mgl32.Vec3 {
+	dist := func(center float32) float32 {
+		return float32(math.Abs(float64(6*h - center)))
+	}
+	return mgl32.Vec3{
+		mgl32.Clamp(dist(3)-1, 0, 1),
+		mgl32.Clamp(2-dist(2), 0, 1),
+		mgl32.Clamp(2-dist(4), 0, 1),
+	}
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Deferred shading")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// the forward path, also used for the panes of glass in the deferred path
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+	defer lightProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	floor, err := gfx.NewPlaneMesh(30).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	pane, err := gfx.NewPlaneMesh(1).Upload()
+	if err != nil {
+		return err
+	}
+	defer pane.Delete()
+
+	bulb, err := gfx.NewSphereMesh(6, 12).Upload()
+	if err != nil {
+		return err
+	}
+	defer bulb.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// clamped so the transparent edges do not pick up the other side when filtered
+	glassMap, err := gfx.NewTextureFromFile("../images/trollface-transparent.png", gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+	if err != nil {
+		return err
+	}
+
+	gbuffer, err := gfx.NewGBuffer(window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+	defer gbuffer.Delete()
+
+	lighting, err := gfx.NewDeferredLighting()
+	if err != nil {
+		return err
+	}
+	defer lighting.Delete()
+
+	world, nodes := buildScene(cube, floor, pane, bulb)
+	numLights := startSwarm
+
+	deferred := true
+	showVolumes := false
+
+	// 0 is the lit scene, the rest are the G-buffer's images
+	gbufferView := 0
+
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 100}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 4, 24}, mgl32.Vec3{0, 1, 0}, -90, -10, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		if window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0 {
+			if err := gbuffer.Resize(window.FramebufferWidth(), window.FramebufferHeight()); err != nil {
+				return err
+			}
+		}
+
+		input := window.InputManager()
+		if input.IsTriggered(win.TOGGLE_DEFERRED) {
+			deferred = !deferred
+		}
+		if input.IsTriggered(win.NEXT_GBUFFER_VIEW) {
+			gbufferView = (gbufferView + 1) % (gfx.NumGBufferViews + 1)
+		}
+		if input.IsTriggered(win.TOGGLE_LIGHT_VOLUMES) {
+			showVolumes = !showVolumes
+		}
+		if input.IsTriggered(win.MORE_LIGHTS) && numLights < maxSwarm {
+			numLights *= 2
+		}
+		if input.IsTriggered(win.FEWER_LIGHTS) && numLights > minSwarm {
+			numLights /= 2
+		}
+
+		// animate the nodes, the lights that are off are hidden which also leaves them out of
+		// world.Lights
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+		for i, lamp := range nodes.spots {
+			sweep := mgl32.DegToRad(30) * float32(math.Sin(float64(0.5*time)+float64(i)*math.Pi))
+			lamp.SetRotation(mgl32.QuatRotate(sweep, mgl32.Vec3{0, 0, 1}).Mul(
+				mgl32.QuatRotate(mgl32.DegToRad(-90), mgl32.Vec3{1, 0, 0})))
+		}
+		for i, w := range nodes.swarm {
+			w.node.Hidden = i >= numLights
+			angle := float64(w.phase + w.speed*time)
+			w.node.SetPosition(w.center.Add(mgl32.Vec3{
+				w.radius * float32(math.Cos(angle)), 0, w.radius * float32(math.Sin(angle)),
+			}))
+		}
+
+		lights := world.Lights()
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(float32(window.FramebufferWidth()) / float32(window.FramebufferHeight()))
+
+		// background color
+		gl.ClearColor(0.02, 0.02, 0.04, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		var path string
+		if deferred || gbufferView > 0 {
+			// the opaque scene without lighting into the G-buffer
+			gprog := gbuffer.Begin()
+			setMaterial(gprog, diffuseMap, specularMap, 1)
+			gl.UniformMatrix4fv(gprog.GetUniformLocation("view"), 1, false, &camTransform[0])
+			gl.UniformMatrix4fv(gprog.GetUniformLocation("project"), 1, false, &projectTransform[0])
+			world.Draw(gprog, func(n *scene.Node) bool {
+				return !isLight(n)
+			})
+			gbuffer.End()
+			diffuseMap.UnBind()
+			specularMap.UnBind()
+		}
+
+		if gbufferView > 0 {
+			view := gfx.GBufferView(gbufferView - 1)
+			gbuffer.DrawDebug(view, projectTransform)
+			window.SetTitle(fmt.Sprintf("Deferred shading - G-buffer %s, gamma correction %s",
+				view, onOff(window.GammaCorrection())))
+			continue
+		}
+
+		if deferred {
+			// the lighting is tested against the scene's depth
+			gbuffer.BlitDepth()
+			stats := lighting.Draw(gbuffer, lights.Directional, lights.Point, lights.Spot, camTransform, projectTransform)
+			path = fmt.Sprintf("deferred, %d of %d lights drawn (%d culled)",
+				stats.Drawn, len(lights.Directional)+len(lights.Point)+len(lights.Spot), stats.Culled)
+		} else {
+			program.Use()
+			setMaterial(program, diffuseMap, specularMap, 1)
+			gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+			gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+			setLightUniforms(program, lights, camera.Position(), camTransform)
+			world.Draw(program, func(n *scene.Node) bool {
+				return !isLight(n)
+			})
+			diffuseMap.UnBind()
+			specularMap.UnBind()
+			path = fmt.Sprintf("forward, %d of %d point lights", min(len(lights.Point), maxPointLights), len(lights.Point))
+		}
+
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(lightProgram, func(n *scene.Node) bool {
+			var color mgl32.Vec3
+			switch {
+			case n.SpotLight != nil:
+				color = n.SpotLight.Diffuse
+			case n.PointLight != nil:
+				color = n.PointLight.Diffuse
+			default:
+				return false
+			}
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"), color.X(), color.Y(), color.Z())
+			return true
+		})
+
+		if showVolumes {
+			lighting.DrawVolumes(lights.Point, lights.Spot, camTransform, projectTransform)
+		}
+
+		// the glass is always drawn forward, blended over whatever is behind it which has to be
+		// drawn first. It does not hide what is behind it so it does not write depth.
+		program.Use()
+		setMaterial(program, glassMap, glassMap, 32)
+		gl.Uniform1f(program.GetUniformLocation("material.opacity"), 0.6)
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		setLightUniforms(program, lights, camera.Position(), camTransform)
+		gl.Enable(gl.BLEND)
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+		gl.DepthMask(false)
+		drawBackToFront(program, nodes.panes, camera.Position())
+		gl.DepthMask(true)
+		gl.Disable(gl.BLEND)
+		glassMap.UnBind()
+
+		window.SetTitle(fmt.Sprintf("Deferred shading - %s, light volumes %s, gamma correction %s",
+			path, onOff(showVolumes), onOff(window.GammaCorrection())))
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+// setMaterial binds the maps to the first two texture units and sets the Material struct
+// of prog, which must be in use
+func setMaterial(prog *gfx.Program, diffuse, specular *gfx.Texture, shininess float32) {
+	diffuse.Bind(gl.TEXTURE0)
+	diffuse.SetUniform(prog.GetUniformLocation("material.diffuse"))
+	specular.Bind(gl.TEXTURE1)
+	specular.SetUniform(prog.GetUniformLocation("material.specular"))
+	gl.Uniform1f(prog.GetUniformLocation("material.shininess"), shininess)
+	gl.Uniform1f(prog.GetUniformLocation("material.opacity"), 1)
+}
+
+// drawBackToFront draws nodes starting with the one furthest from eye, so each one is blended
+// over the ones behind it
+func drawBackToFront(prog *gfx.Program, nodes []*scene.Node, eye mgl32.Vec3) {
+	sorted := append([]*scene.Node{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].WorldPosition().Sub(eye).Len() > sorted[j].WorldPosition().Sub(eye).Len()
+	})
+
+	modelLoc := prog.GetUniformLocation("model")
+	for _, n := range sorted {
+		model := n.WorldTransform()
+		gl.UniformMatrix4fv(modelLoc, 1, false, &model[0])
+		n.Mesh.Bind()
+		n.Mesh.Draw()
+	}
+	gl.BindVertexArray(0)
+}
+
+func isLight(n *scene.Node) bool {
+	return n.PointLight != nil || n.SpotLight != nil
+}
+
+// sets the lights in view space for the forward shader. It only has room for so many lights
+// so the ones closest to eye are picked.
+func setLightUniforms(program *gfx.Program, lights scene.Lights, eye mgl32.Vec3, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	points := append([]gfx.PointLight{}, lights.Point...)
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Position.Sub(eye).Len() < points[j].Position.Sub(eye).Len()
+	})
+	numPointLights := min(len(points), maxPointLights)
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+	for i := 0; i < numPointLights; i++ {
+		points[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+	}
+
+	numSpotLights := min(len(lights.Spot), maxSpotLights)
+	gl.Uniform1i(program.GetUniformLocation("numSpotLights"), int32(numSpotLights))
+	for i := 0; i < numSpotLights; i++ {
+		lights.Spot[i].SetUniforms(program, fmt.Sprintf("spotLights[%d]", i), camTransform)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+#version 410 core
+
+// writes the surface's material into the G-buffer instead of lighting it, the lighting
+// passes read it back (see gfx/gbuffer.go)
+
+// must match MaxShininess in gfx/gbuffer.go
+#define MAX_SHININESS 256.0
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D specular;
+	float shininess;
+};
+
+in vec3 Normal;
+in vec2 TexCoords;
+
+// in the order of the framebuffer's color attachments
+layout (location = 0) out vec4 gAlbedo;
+layout (location = 1) out vec4 gNormal;
+layout (location = 2) out vec4 gMaterial;
+
+uniform Material material;
+
+void main()
+{
+	gAlbedo = vec4(texture(material.diffuse, TexCoords).rgb, 1.0);
+
+	// seen from behind the surface faces the other way, like in the forward shader
+	gNormal = vec4(normalize(gl_FrontFacing ? Normal : -Normal), 0.0);
+
+	// the attachment stores [0, 1] so the shininess is scaled down to fit
+	vec3 specular = texture(material.specular, TexCoords).rgb;
+	gMaterial = vec4(specular, clamp(material.shininess / MAX_SHININESS, 0.0, 1.0));
+}
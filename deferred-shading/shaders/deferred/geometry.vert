@@ -0,0 +1,23 @@
+#version 410 core
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 normal;
+layout (location = 2) in vec2 texCoord;
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 project;
+
+out vec3 Normal;
+out vec2 TexCoords;
+
+void main()
+{
+	gl_Position = project * view * model * vec4(position, 1.0);
+	TexCoords = texCoord;
+
+	// the G-buffer stores view space normals like the ones the forward shader lights with,
+	// the positions are not needed since they can be worked out from the depth
+	mat3 normMatrix = mat3(transpose(inverse(view))) * mat3(transpose(inverse(model)));
+	Normal = normMatrix * normal;
+}
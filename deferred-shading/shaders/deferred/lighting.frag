@@ -0,0 +1,177 @@
+#version 410 core
+
+// lights the surfaces stored in the G-buffer with one light, every light is drawn on top of
+// the others with additive blending. A directional light covers the whole screen, point and
+// spot lights only the pixels inside their light volumes (see gfx/deferred.go).
+
+// must match MaxShininess in gfx/gbuffer.go
+#define MAX_SHININESS 256.0
+
+// must match the light types in gfx/deferred.go
+#define DIRECTIONAL 0
+#define POINT 1
+#define SPOT 2
+
+// these structs match the light types in gfx/light.go
+// all positions and directions are in view space
+
+struct DirLight {
+	vec3 direction;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+struct PointLight {
+	vec3 position;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+struct SpotLight {
+	vec3 position;
+	vec3 direction;
+	float innerCutoff;  // cosine of the angle
+	float outerCutoff;  // cosine of the angle
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+out vec4 color;
+
+uniform sampler2D gAlbedo;
+uniform sampler2D gNormal;
+uniform sampler2D gMaterial;
+uniform sampler2D gDepth;
+
+uniform vec2 screenSize;
+uniform mat4 inverseProject;
+
+uniform int lightType;
+uniform DirLight dirLight;
+uniform PointLight pointLight;
+uniform SpotLight spotLight;
+
+// where the light volume ends, surfaces further away are not lit even if they are covered by
+// the volume on screen
+uniform float range;
+
+// what the G-buffer stores for one pixel
+struct Surface {
+	vec3 position;
+	vec3 normal;
+	vec3 albedo;
+	vec3 specular;
+	float shininess;
+};
+
+// the parts of phong lighting shared by every light type, dirToLight must be normalized
+vec3 phong(Surface s, vec3 dirToLight, vec3 dirToView,
+           vec3 lightAmbient, vec3 lightDiffuse, vec3 lightSpecular)
+{
+	vec3 ambient = lightAmbient * s.albedo;
+
+	float lightNormalDiff = max(dot(s.normal, dirToLight), 0.0);
+	vec3 diffuse = lightDiffuse * lightNormalDiff * s.albedo;
+
+	vec3 reflectDir = reflect(-dirToLight, s.normal);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), s.shininess);
+	vec3 specular = lightSpecular * spec * s.specular;
+
+	return ambient + diffuse + specular;
+}
+
+// intensity left after the light has travelled dist
+float attenuation(float dist, float constant, float linear, float quadratic)
+{
+	return 1.0 / (constant + linear * dist + quadratic * (dist * dist));
+}
+
+vec3 calcDirLight(DirLight light, Surface s, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(-light.direction);
+	return phong(s, dirToLight, dirToView, light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcPointLight(PointLight light, Surface s, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - s.position);
+	float decay = attenuation(length(light.position - s.position),
+	                          light.constant, light.linear, light.quadratic);
+
+	return decay * phong(s, dirToLight, dirToView, light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcSpotLight(SpotLight light, Surface s, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - s.position);
+	float decay = attenuation(length(light.position - s.position),
+	                          light.constant, light.linear, light.quadratic);
+
+	// 1 inside the inner cone, 0 outside the outer cone and a smooth blend between them
+	float theta = dot(dirToLight, normalize(-light.direction));
+	float epsilon = light.innerCutoff - light.outerCutoff;
+	float intensity = clamp((theta - light.outerCutoff) / epsilon, 0.0, 1.0);
+
+	// keep the ambient term so the area outside the cone is not pitch black
+	vec3 ambient = light.ambient * s.albedo;
+	vec3 lit = phong(s, dirToLight, dirToView, vec3(0.0), light.diffuse, light.specular);
+
+	return decay * (ambient + intensity * lit);
+}
+
+void main()
+{
+	vec2 uv = gl_FragCoord.xy / screenSize;
+
+	// nothing was drawn here, leave the background alone
+	float depth = texture(gDepth, uv).r;
+	if (depth == 1.0) {
+		discard;
+	}
+
+	// undo the projection to get back the view space position the depth was written from
+	vec4 clip = vec4(vec3(uv, depth) * 2.0 - 1.0, 1.0);
+	vec4 viewPos = inverseProject * clip;
+
+	Surface s;
+	s.position = viewPos.xyz / viewPos.w;
+	s.normal = normalize(texture(gNormal, uv).xyz);
+	s.albedo = texture(gAlbedo, uv).rgb;
+
+	vec4 material = texture(gMaterial, uv);
+	s.specular = material.rgb;
+	s.shininess = material.a * MAX_SHININESS;
+
+	vec3 dirToView = normalize(-s.position);
+
+	vec3 result = vec3(0.0);
+	if (lightType == DIRECTIONAL) {
+		result = calcDirLight(dirLight, s, dirToView);
+	} else if (lightType == POINT) {
+		if (length(pointLight.position - s.position) > range) {
+			discard;
+		}
+		result = calcPointLight(pointLight, s, dirToView);
+	} else if (lightType == SPOT) {
+		if (length(spotLight.position - s.position) > range) {
+			discard;
+		}
+		result = calcSpotLight(spotLight, s, dirToView);
+	}
+
+	color = vec4(result, 1.0);
+}
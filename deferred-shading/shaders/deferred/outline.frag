@@ -0,0 +1,12 @@
+#version 410 core
+
+// draws a light volume as lines in the color of its light
+
+out vec4 color;
+
+uniform vec3 lightColor;
+
+void main()
+{
+	color = vec4(lightColor, 1.0);
+}
@@ -0,0 +1,98 @@
+package gfx
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Built in shapes with positions, normals and texture coordinates.
+// They are centered on the origin and have a size of 1 along each axis they extend in.
+
+// NewCubeMesh makes a cube with 4 separate vertices per face so each face has its own
+// normal and the full [0, 1] texture range.
+func NewCubeMesh() *Mesh {
+	mesh := Mesh{}
+
+	// normal, then the two axes spanning the face chosen so that u x v = normal
+	faces := [6][3]mgl32.Vec3{
+		{{0, 0, 1}, {1, 0, 0}, {0, 1, 0}},
+		{{0, 0, -1}, {-1, 0, 0}, {0, 1, 0}},
+		{{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},
+		{{-1, 0, 0}, {0, 0, 1}, {0, 1, 0}},
+		{{0, 1, 0}, {1, 0, 0}, {0, 0, -1}},
+		{{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},
+	}
+
+	for _, face := range faces {
+		normal, u, v := face[0], face[1], face[2]
+		first := uint32(len(mesh.Positions))
+
+		for _, uv := range [4]mgl32.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+			pos := normal.Mul(0.5).Add(u.Mul(uv[0] - 0.5)).Add(v.Mul(uv[1] - 0.5))
+			mesh.Positions = append(mesh.Positions, pos)
+			mesh.Normals = append(mesh.Normals, normal)
+			mesh.UVs = append(mesh.UVs, uv)
+		}
+		mesh.Indices = append(mesh.Indices, first, first+1, first+2, first+2, first+3, first)
+	}
+
+	return &mesh
+}
+
+// NewPlaneMesh makes a square in the xz plane facing +y.
+// The texture repeats uvScale times across it (use a REPEAT wrap mode).
+func NewPlaneMesh(uvScale float32) *Mesh {
+	mesh := Mesh{}
+	for _, uv := range [4]mgl32.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		mesh.Positions = append(mesh.Positions, mgl32.Vec3{uv[0] - 0.5, 0, 0.5 - uv[1]})
+		mesh.Normals = append(mesh.Normals, mgl32.Vec3{0, 1, 0})
+		mesh.UVs = append(mesh.UVs, uv.Mul(uvScale))
+	}
+	mesh.Indices = []uint32{0, 1, 2, 2, 3, 0}
+	return &mesh
+}
+
+// NewSphereMesh makes a UV sphere with a diameter of 1. rings is the number of horizontal
+// bands from pole to pole and segments the number of slices around the y axis.
+func NewSphereMesh(rings, segments int) *Mesh {
+	if rings < 2 {
+		rings = 2
+	}
+	if segments < 3 {
+		segments = 3
+	}
+
+	mesh := Mesh{}
+
+	// the seam has duplicated vertices so the texture can wrap from u=1 back to u=0
+	for ring := 0; ring <= rings; ring++ {
+		v := float32(ring) / float32(rings)
+		phi := math.Pi * float64(v)
+
+		for seg := 0; seg <= segments; seg++ {
+			u := float32(seg) / float32(segments)
+			theta := 2 * math.Pi * float64(u)
+
+			normal := mgl32.Vec3{
+				float32(math.Sin(phi) * math.Cos(theta)),
+				float32(math.Cos(phi)),
+				float32(-math.Sin(phi) * math.Sin(theta)),
+			}
+			mesh.Positions = append(mesh.Positions, normal.Mul(0.5))
+			mesh.Normals = append(mesh.Normals, normal)
+			mesh.UVs = append(mesh.UVs, mgl32.Vec2{u, 1 - v})
+		}
+	}
+
+	rowLen := uint32(segments + 1)
+	for ring := uint32(0); ring < uint32(rings); ring++ {
+		for seg := uint32(0); seg < uint32(segments); seg++ {
+			top := ring*rowLen + seg
+			bottom := top + rowLen
+			mesh.Indices = append(mesh.Indices, top, bottom, bottom+1, bottom+1, top+1, top)
+		}
+	}
+
+	return &mesh
+}
@@ -0,0 +1,371 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-OpenGL/Framebuffers
+
+A security camera circling the crates renders the scene into an offscreen framebuffer
+(gfx.Framebuffer) with two color attachments, the lit colors and the view space normals,
+and a depth/stencil texture. Its images are then used as a texture on a screen in the
+scene, and the color attachment is also blitted straight to the corner of the window.
+
+Press T to cycle the screen between the color, normal and depth attachments and R to switch
+the framebuffer between full and low resolution.
+*/
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/framebuffers/cam"
+	"github.com/cstegel/opengl-samples-golang/framebuffers/gfx"
+	"github.com/cstegel/opengl-samples-golang/framebuffers/scene"
+	"github.com/cstegel/opengl-samples-golang/framebuffers/win"
+)
+
+var cubePositions = [][]float32{
+	{0.0, 0.0, -3.0},
+	{2.0, 5.0, -15.0},
+	{-1.5, -2.2, -2.5},
+	{-3.8, -2.0, -12.3},
+	{2.4, -0.4, -3.5},
+	{-1.7, 3.0, -7.5},
+	{1.3, -2.0, -2.5},
+	{1.5, 2.0, -2.5},
+	{1.5, 0.2, -1.5},
+	{-1.3, 1.0, -1.5},
+}
+
+// the attachments the screen can show, must match SHOW_* in shaders/screen.frag
+const (
+	showColor = iota
+	showNormals
+	showDepth
+	numShows
+)
+
+var showNames = [numShows]string{"color", "normals", "depth"}
+
+// the low resolution framebuffer is this many times smaller than the window
+const lowResDivisor = 8
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes          []*scene.Node
+	flashlight     *scene.Node
+	cameraOrbit    *scene.Node
+	securityCamera *scene.Node
+}
+
+/*
+ * Builds the scene:
+ *
+ *   root
+ *   ├── sun                   directional light
+ *   ├── flashlight            spot light that follows the free camera
+ *   ├── cube 0..9             spinning crates
+ *   └── camera orbit          spins around the crates
+ *       └── security camera   renders into the framebuffer
+ *           └── housing       small crate behind the lens to show where it is
+ */
+func buildScene(cube *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.1, 0.1, 0.12},
+		Diffuse:   mgl32.Vec3{0.5, 0.5, 0.5},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	// the spot light points down the node's -z like a camera
+	nodes.flashlight = s.Add(scene.NewNode("flashlight"))
+	nodes.flashlight.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	for i, pos := range cubePositions {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{pos[0], pos[1], pos[2]})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	nodes.cameraOrbit = s.Add(scene.NewNode("camera orbit"))
+	nodes.cameraOrbit.SetPosition(mgl32.Vec3{0, 0, -7})
+
+	nodes.securityCamera = nodes.cameraOrbit.AddChild(scene.NewNode("security camera"))
+	nodes.securityCamera.SetPosition(mgl32.Vec3{10, 4, 0})
+	nodes.securityCamera.LookAt(mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	nodes.securityCamera.Camera = &scene.Camera{Fov: 60, Near: 0.1, Far: 50}
+
+	housing := nodes.securityCamera.AddChild(scene.NewNode("housing"))
+	housing.SetPosition(mgl32.Vec3{0, 0, 0.5})
+	housing.SetScale(mgl32.Vec3{0.4, 0.4, 0.6})
+	housing.Mesh = cube
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Framebuffers")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	screenFragShader, err := gfx.NewShaderFromFile("shaders/screen.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// draws the screen showing the framebuffer without any lighting
+	screenProgram, err := gfx.NewProgram(vertShader, screenFragShader)
+	if err != nil {
+		return err
+	}
+	defer screenProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	screen, err := gfx.NewPlaneMesh(1).Upload()
+	if err != nil {
+		return err
+	}
+	defer screen.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// colors, normals and a depth/stencil texture so every attachment can be shown
+	offscreen, err := gfx.NewFramebuffer(window.FramebufferWidth(), window.FramebufferHeight(), gfx.FramebufferSpec{
+		Color: []gfx.Attachment{
+			{Format: gl.RGBA8},
+			{Format: gl.RGBA8},
+		},
+		Depth: &gfx.Attachment{Format: gl.DEPTH24_STENCIL8},
+	})
+	if err != nil {
+		return err
+	}
+	defer offscreen.Delete()
+
+	world, nodes := buildScene(cube)
+
+	// the plane faces +y, standing it up puts texture coordinate (0, 0) at the bottom left
+	// which is where framebuffer textures start
+	screenModel := mgl32.Translate3D(0, 4, -22).
+		Mul4(mgl32.HomogRotate3DX(mgl32.DegToRad(90))).
+		Mul4(mgl32.Scale3D(16, 1, 9))
+
+	// lens for the free camera
+	freeCamera := scene.Camera{Fov: 60, Near: 0.1, Far: 100}
+
+	show := showColor
+	lowRes := false
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		if window.InputManager().IsTriggered(win.SHOW_NEXT_ATTACHMENT) {
+			show = (show + 1) % numShows
+		}
+		toggledLowRes := window.InputManager().IsTriggered(win.TOGGLE_LOW_RES)
+		if toggledLowRes {
+			lowRes = !lowRes
+		}
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		resized := window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0
+		if toggledLowRes || resized {
+			width, height := window.FramebufferWidth(), window.FramebufferHeight()
+			if lowRes {
+				width, height = width/lowResDivisor, height/lowResDivisor
+			}
+			if err := offscreen.Resize(width, height); err != nil {
+				return err
+			}
+		}
+		window.SetTitle(fmt.Sprintf("Framebuffers - showing %s, %dx%d", showNames[show],
+			offscreen.Width(), offscreen.Height()))
+
+		// animate the nodes, their children follow along
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+		nodes.cameraOrbit.SetRotation(mgl32.QuatRotate(time/4, mgl32.Vec3{0, 1, 0}))
+
+		// the flashlight is held by the free camera
+		nodes.flashlight.SetPosition(camera.Position())
+		nodes.flashlight.LookAt(camera.Position().Add(camera.Front()), mgl32.Vec3{0, 1, 0})
+
+		program.Use()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		lights := world.Lights()
+
+		// first the security camera's view into the framebuffer
+		lens := nodes.securityCamera.Camera
+		offscreen.Bind()
+		gl.ClearColor(0.05, 0.05, 0.1, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		drawScene(program, world, lights, nodes.securityCamera.ViewTransform(),
+			lens.Projection(float32(offscreen.Width())/float32(offscreen.Height())))
+		offscreen.UnBind()
+
+		// then the free camera's view into the window
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		camTransform := camera.GetTransform()
+		projectTransform := freeCamera.Projection(float32(window.FramebufferWidth()) / float32(window.FramebufferHeight()))
+		drawScene(program, world, lights, camTransform, projectTransform)
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// the screen shows one of the framebuffer's textures
+		shown := offscreen.Color(0)
+		switch show {
+		case showNormals:
+			shown = offscreen.Color(1)
+		case showDepth:
+			shown = offscreen.Depth()
+		}
+
+		screenProgram.Use()
+		gl.UniformMatrix4fv(screenProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(screenProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		gl.UniformMatrix4fv(screenProgram.GetUniformLocation("model"), 1, false, &screenModel[0])
+		gl.Uniform1i(screenProgram.GetUniformLocation("show"), int32(show))
+		gl.Uniform1f(screenProgram.GetUniformLocation("near"), lens.Near)
+		gl.Uniform1f(screenProgram.GetUniformLocation("far"), lens.Far)
+		shown.Bind(gl.TEXTURE0)
+		shown.SetUniform(screenProgram.GetUniformLocation("screenTexture"))
+
+		screen.Bind()
+		screen.Draw()
+		screen.UnBind()
+		shown.UnBind()
+
+		// and the colors copied to the bottom right corner of the window as they are
+		w, h := window.FramebufferWidth(), window.FramebufferHeight()
+		corner := gfx.Rect{X0: w - w/4 - 10, Y0: 10, X1: w - 10, Y1: 10 + h/4}
+		offscreen.BlitColor(0, nil, offscreen.Bounds(), corner, gl.LINEAR)
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+/*
+ * Draws the lit nodes of the scene from the point of view given by the view and projection.
+ * program must be in use with its material already set.
+ */
+func drawScene(program *gfx.Program, world *scene.Scene, lights scene.Lights,
+	camTransform, projectTransform mgl32.Mat4) {
+
+	gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+	gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+	// the lights convert themselves to the view space of this camera
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), 0)
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+
+	world.Draw(program, nil)
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+#version 410 core
+
+// must match maxPointLights in main.go
+#define MAX_POINT_LIGHTS 8
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D specular;
+	float shininess;
+};
+
+// these structs match the light types in gfx/light.go
+// all positions and directions are in view space
+
+struct DirLight {
+	vec3 direction;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+struct PointLight {
+	vec3 position;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+struct SpotLight {
+	vec3 position;
+	vec3 direction;
+	float innerCutoff;  // cosine of the angle
+	float outerCutoff;  // cosine of the angle
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+in vec3 Normal;
+in vec3 FragPos;
+in vec2 TexCoords;
+layout (location = 0) out vec4 color;
+
+// only kept when drawing to a framebuffer with a second color attachment
+layout (location = 1) out vec4 normalColor;
+
+uniform Material material;
+uniform DirLight dirLight;
+uniform PointLight pointLights[MAX_POINT_LIGHTS];
+uniform int numPointLights;
+uniform SpotLight spotLight;
+
+// the scene may not have a sun or flashlight
+uniform bool hasDirLight;
+uniform bool hasSpotLight;
+
+// the parts of phong lighting shared by every light type, dirToLight must be normalized
+vec3 phong(vec3 dirToLight, vec3 norm, vec3 dirToView,
+           vec3 lightAmbient, vec3 lightDiffuse, vec3 lightSpecular)
+{
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 specularColor = vec3(texture(material.specular, TexCoords));
+
+	vec3 ambient = lightAmbient * diffuseColor;
+
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = lightDiffuse * lightNormalDiff * diffuseColor;
+
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = lightSpecular * spec * specularColor;
+
+	return ambient + diffuse + specular;
+}
+
+// intensity left after the light has travelled dist
+float attenuation(float dist, float constant, float linear, float quadratic)
+{
+	return 1.0 / (constant + linear * dist + quadratic * (dist * dist));
+}
+
+vec3 calcDirLight(DirLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(-light.direction);
+	return phong(dirToLight, norm, dirToView, light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcPointLight(PointLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	return decay * phong(dirToLight, norm, dirToView, light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcSpotLight(SpotLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	// 1 inside the inner cone, 0 outside the outer cone and a smooth blend between them
+	float theta = dot(dirToLight, normalize(-light.direction));
+	float epsilon = light.innerCutoff - light.outerCutoff;
+	float intensity = clamp((theta - light.outerCutoff) / epsilon, 0.0, 1.0);
+
+	// keep the ambient term so the area outside the cone is not pitch black
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 ambient = light.ambient * diffuseColor;
+	vec3 lit = phong(dirToLight, norm, dirToView, vec3(0.0), light.diffuse, light.specular);
+
+	return decay * (ambient + intensity * lit);
+}
+
+void main()
+{
+	vec3 norm = normalize(Normal);
+	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
+	vec3 dirToView = normalize(viewPos - FragPos);
+
+	vec3 result = vec3(0.0f);
+	if (hasDirLight) {
+		result += calcDirLight(dirLight, norm, dirToView);
+	}
+	for (int i = 0; i < numPointLights; i++) {
+		result += calcPointLight(pointLights[i], norm, dirToView);
+	}
+	if (hasSpotLight) {
+		result += calcSpotLight(spotLight, norm, dirToView);
+	}
+
+	color = vec4(result, 1.0f);
+
+	// view space normal mapped from [-1, 1] to [0, 1] so it can be seen
+	normalColor = vec4(norm * 0.5 + 0.5, 1.0f);
+}
@@ -0,0 +1,34 @@
+#version 410 core
+
+// shows one of the offscreen framebuffer's attachments on the screen in the scene
+
+in vec2 TexCoords;
+out vec4 color;
+
+// must match the attachment order in main.go
+#define SHOW_COLOR   0
+#define SHOW_NORMALS 1
+#define SHOW_DEPTH   2
+
+uniform sampler2D screenTexture;
+uniform int show;
+
+// of the camera that rendered the framebuffer, to undo the depth buffer's perspective
+uniform float near;
+uniform float far;
+
+void main()
+{
+	vec4 texel = texture(screenTexture, TexCoords);
+
+	if (show == SHOW_DEPTH) {
+		// depth is stored non-linearly (most precision near the camera) so convert it back
+		// to a distance and show that as a fraction of the far plane
+		float ndcZ = texel.r * 2.0 - 1.0;
+		float dist = (2.0 * near * far) / (far + near - ndcZ * (far - near));
+		color = vec4(vec3(dist / far), 1.0);
+		return;
+	}
+
+	color = vec4(texel.rgb, 1.0);
+}
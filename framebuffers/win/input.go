@@ -0,0 +1,169 @@
+package win
+
+import (
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl64"
+)
+
+// Action is a configurable abstraction of a key press
+type Action int
+
+const (
+	PLAYER_FORWARD Action = iota
+	PLAYER_BACKWARD Action = iota
+	PLAYER_LEFT Action = iota
+	PLAYER_RIGHT Action = iota
+	PROGRAM_QUIT Action = iota
+	TOGGLE_GAMMA_CORRECTION Action = iota
+	SHOW_NEXT_ATTACHMENT Action = iota
+	TOGGLE_LOW_RES Action = iota
+)
+
+type InputManager struct {
+	actionToKeyMap map[Action]glfw.Key
+	keysPressed [glfw.KeyLast]bool
+
+	// presses since the last checkpoint, see CheckpointKeys()
+	keysTriggered [glfw.KeyLast]bool
+	bufferedKeysTriggered [glfw.KeyLast]bool
+
+	// mouse buttons are tracked the same way as keys
+	buttonsPressed [glfw.MouseButtonLast+1]bool
+	buttonsTriggered [glfw.MouseButtonLast+1]bool
+	bufferedButtonsTriggered [glfw.MouseButtonLast+1]bool
+
+	// while the cursor is not captured by the window moving it does not count as a change
+	// so the camera does not turn when the cursor is used for pointing at things
+	cursorCaptured bool
+
+	firstCursorAction bool
+	cursor mgl64.Vec2
+	cursorChange mgl64.Vec2
+	cursorLast mgl64.Vec2
+	bufferedCursorChange mgl64.Vec2
+}
+
+func NewInputManager() *InputManager {
+	actionToKeyMap := map[Action]glfw.Key{
+		PLAYER_FORWARD: glfw.KeyW,
+		PLAYER_BACKWARD: glfw.KeyS,
+		PLAYER_LEFT: glfw.KeyA,
+		PLAYER_RIGHT: glfw.KeyD,
+		PROGRAM_QUIT: glfw.KeyEscape,
+		TOGGLE_GAMMA_CORRECTION: glfw.KeyG,
+		SHOW_NEXT_ATTACHMENT: glfw.KeyT,
+		TOGGLE_LOW_RES: glfw.KeyR,
+	}
+
+	return &InputManager{
+		actionToKeyMap: actionToKeyMap,
+		firstCursorAction: false,
+		cursorCaptured: true,
+	}
+}
+
+// IsActive returns whether the given Action is currently active
+func (im *InputManager) IsActive(a Action) bool {
+	return im.keysPressed[im.actionToKeyMap[a]]
+}
+
+// IsTriggered returns whether the key for the given Action was pressed down
+// between the last two calls to CheckpointKeys(). Unlike IsActive this is only true
+// for one frame per press which is what toggles need.
+func (im *InputManager) IsTriggered(a Action) bool {
+	return im.keysTriggered[im.actionToKeyMap[a]]
+}
+
+// IsButtonActive returns whether the mouse button is currently held down
+func (im *InputManager) IsButtonActive(b glfw.MouseButton) bool {
+	return im.buttonsPressed[b]
+}
+
+// IsButtonTriggered is IsTriggered for mouse buttons, true for one frame per click.
+func (im *InputManager) IsButtonTriggered(b glfw.MouseButton) bool {
+	return im.buttonsTriggered[b]
+}
+
+// CheckpointKeys updates the publicly available IsTriggered() and IsButtonTriggered()
+// methods to report the key and button presses since the last time this method was called.
+func (im *InputManager) CheckpointKeys() {
+	im.keysTriggered = im.bufferedKeysTriggered
+	im.bufferedKeysTriggered = [glfw.KeyLast]bool{}
+
+	im.buttonsTriggered = im.bufferedButtonsTriggered
+	im.bufferedButtonsTriggered = [glfw.MouseButtonLast+1]bool{}
+}
+
+// Cursor returns the value of the cursor at the last time that CheckpointCursorChange() was called.
+func (im *InputManager) Cursor() mgl64.Vec2 {
+	return im.cursor
+}
+
+// CursorChange returns the amount of change in the underlying cursor
+// since the last time CheckpointCursorChange was called
+func (im *InputManager) CursorChange() mgl64.Vec2 {
+	return im.cursorChange
+}
+
+// CheckpointCursorChange updates the publicly available Cursor() and CursorChange()
+// methods to return the current Cursor and change since last time this method was called.
+func (im *InputManager) CheckpointCursorChange() {
+	im.cursorChange[0] = im.bufferedCursorChange[0]
+	im.cursorChange[1] = im.bufferedCursorChange[1]
+	im.cursor[0] = im.cursorLast[0]
+	im.cursor[1] = im.cursorLast[1]
+
+	im.bufferedCursorChange[0] = 0
+	im.bufferedCursorChange[1] = 0
+}
+
+func (im *InputManager) keyCallback(window *glfw.Window, key glfw.Key, scancode int,
+	action glfw.Action, mods glfw.ModifierKey) {
+
+	// timing for key events occurs differently from what the program loop requires
+	// so just track what key actions occur and then access them in the program loop
+	switch action {
+	case glfw.Press:
+		im.keysPressed[key] = true
+		im.bufferedKeysTriggered[key] = true
+	case glfw.Release:
+		im.keysPressed[key] = false
+	}
+}
+
+func (im *InputManager) mouseButtonCallback(window *glfw.Window, button glfw.MouseButton,
+	action glfw.Action, mods glfw.ModifierKey) {
+
+	switch action {
+	case glfw.Press:
+		im.buttonsPressed[button] = true
+		im.bufferedButtonsTriggered[button] = true
+	case glfw.Release:
+		im.buttonsPressed[button] = false
+	}
+}
+
+// setCursorCaptured is called by the window when it changes the cursor mode
+func (im *InputManager) setCursorCaptured(captured bool) {
+	im.cursorCaptured = captured
+
+	// the cursor can jump when it is captured or released, don't turn that into a change
+	im.firstCursorAction = true
+}
+
+func (im *InputManager) mouseCallback(window *glfw.Window, xpos, ypos float64) {
+
+	if im.firstCursorAction {
+		im.cursorLast[0] = xpos
+		im.cursorLast[1] = ypos
+		im.firstCursorAction = false
+	}
+
+	if im.cursorCaptured {
+		im.bufferedCursorChange[0] += xpos - im.cursorLast[0]
+		im.bufferedCursorChange[1] += ypos - im.cursorLast[1]
+	}
+
+	im.cursorLast[0] = xpos
+	im.cursorLast[1] = ypos
+}
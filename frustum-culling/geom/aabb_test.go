@@ -0,0 +1,160 @@
+package geom
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func boxApproxEqual(a, b AABB) bool {
+	return vecNear(a.Min, b.Min) && vecNear(a.Max, b.Max)
+}
+
+func TestEmptyAABB(t *testing.T) {
+	empty := EmptyAABB()
+	if !empty.IsEmpty() {
+		t.Fatal("EmptyAABB is not empty")
+	}
+	if empty.Contains(mgl32.Vec3{}) {
+		t.Error("empty box contains the origin")
+	}
+	if empty.SurfaceArea() != 0 {
+		t.Errorf("empty box has a surface area of %v", empty.SurfaceArea())
+	}
+	if !empty.Transform(mgl32.Translate3D(1, 2, 3)).IsEmpty() {
+		t.Error("transformed empty box is not empty")
+	}
+
+	p := mgl32.Vec3{1, 2, 3}
+	if got := empty.Extend(p); got != (AABB{Min: p, Max: p}) {
+		t.Errorf("extending an empty box by %v gave %v", p, got)
+	}
+
+	box := AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}
+	if got := box.Union(empty); got != box {
+		t.Errorf("union with an empty box gave %v, want %v", got, box)
+	}
+	if got := empty.Union(box); got != box {
+		t.Errorf("union of an empty box gave %v, want %v", got, box)
+	}
+}
+
+func TestNewAABBFromPoints(t *testing.T) {
+	box := NewAABBFromPoints([]mgl32.Vec3{{1, -2, 0}, {-1, 3, 0.5}, {0, 0, -4}})
+	want := AABB{Min: mgl32.Vec3{-1, -2, -4}, Max: mgl32.Vec3{1, 3, 0.5}}
+	if box != want {
+		t.Errorf("got %v, want %v", box, want)
+	}
+
+	if got, want := box.Center(), (mgl32.Vec3{0, 0.5, -1.75}); got != want {
+		t.Errorf("center is %v, want %v", got, want)
+	}
+	if got, want := box.Extents(), (mgl32.Vec3{1, 2.5, 2.25}); got != want {
+		t.Errorf("extents are %v, want %v", got, want)
+	}
+	if got := box.SurfaceArea(); got != 2*(2*5+5*4.5+4.5*2) {
+		t.Errorf("surface area is %v", got)
+	}
+}
+
+func TestAABBContainsAndIntersects(t *testing.T) {
+	box := AABB{Min: mgl32.Vec3{0, 0, 0}, Max: mgl32.Vec3{2, 2, 2}}
+
+	for _, test := range []struct {
+		p    mgl32.Vec3
+		want bool
+	}{
+		{mgl32.Vec3{1, 1, 1}, true},
+		{mgl32.Vec3{2, 2, 2}, true}, // on the corner
+		{mgl32.Vec3{2.1, 1, 1}, false},
+		{mgl32.Vec3{1, -0.1, 1}, false},
+	} {
+		if got := box.Contains(test.p); got != test.want {
+			t.Errorf("Contains(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+
+	for _, test := range []struct {
+		name                 string
+		other                AABB
+		intersects, contains bool
+	}{
+		{"inside", AABB{Min: mgl32.Vec3{0.5, 0.5, 0.5}, Max: mgl32.Vec3{1, 1, 1}}, true, true},
+		{"overlapping", AABB{Min: mgl32.Vec3{1, 1, 1}, Max: mgl32.Vec3{3, 3, 3}}, true, false},
+		{"touching", AABB{Min: mgl32.Vec3{2, 0, 0}, Max: mgl32.Vec3{3, 2, 2}}, true, false},
+		{"apart on one axis", AABB{Min: mgl32.Vec3{0, 0, 3}, Max: mgl32.Vec3{2, 2, 4}}, false, false},
+	} {
+		if got := box.Intersects(test.other); got != test.intersects {
+			t.Errorf("%s: Intersects = %v, want %v", test.name, got, test.intersects)
+		}
+		if got := box.ContainsAABB(test.other); got != test.contains {
+			t.Errorf("%s: ContainsAABB = %v, want %v", test.name, got, test.contains)
+		}
+	}
+}
+
+func TestAABBTransform(t *testing.T) {
+	box := AABB{Min: mgl32.Vec3{-1, -1, -1}, Max: mgl32.Vec3{1, 1, 1}}
+	r := float32(math.Sqrt2)
+
+	tests := []struct {
+		name string
+		m    mgl32.Mat4
+		want AABB
+	}{
+		{"identity", mgl32.Ident4(), box},
+		{"translated", mgl32.Translate3D(5, 0, -1),
+			AABB{Min: mgl32.Vec3{4, -1, -2}, Max: mgl32.Vec3{6, 1, 0}}},
+		{"scaled", mgl32.Scale3D(2, 1, 0.5),
+			AABB{Min: mgl32.Vec3{-2, -1, -0.5}, Max: mgl32.Vec3{2, 1, 0.5}}},
+		{"rotated", mgl32.HomogRotate3DY(mgl32.DegToRad(45)),
+			AABB{Min: mgl32.Vec3{-r, -1, -r}, Max: mgl32.Vec3{r, 1, r}}},
+		{"rotated a quarter turn", mgl32.HomogRotate3DZ(mgl32.DegToRad(90)), box},
+		{"scaled, rotated and translated",
+			mgl32.Translate3D(0, 3, 0).Mul4(mgl32.HomogRotate3DZ(mgl32.DegToRad(90))).Mul4(mgl32.Scale3D(2, 1, 1)),
+			AABB{Min: mgl32.Vec3{-1, 1, -1}, Max: mgl32.Vec3{1, 5, 1}}},
+	}
+
+	for _, test := range tests {
+		if got := box.Transform(test.m); !boxApproxEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	// the result is the box around the transformed corners, not only for the cases above
+	m := mgl32.Translate3D(1, 2, 3).Mul4(mgl32.HomogRotate3D(0.7, mgl32.Vec3{1, 2, 3}.Normalize())).
+		Mul4(mgl32.Scale3D(1, 2, 3))
+	var corners []mgl32.Vec3
+	for _, corner := range [8]mgl32.Vec3{
+		{-1, -1, -1}, {1, -1, -1}, {-1, 1, -1}, {1, 1, -1},
+		{-1, -1, 1}, {1, -1, 1}, {-1, 1, 1}, {1, 1, 1},
+	} {
+		corners = append(corners, m.Mul4x1(corner.Vec4(1)).Vec3())
+	}
+	if got, want := box.Transform(m), NewAABBFromPoints(corners); !boxApproxEqual(got, want) {
+		t.Errorf("got %v, want the box around the corners %v", got, want)
+	}
+}
+
+func TestAABBClosestPoint(t *testing.T) {
+	box := AABB{Min: mgl32.Vec3{0, 0, 0}, Max: mgl32.Vec3{2, 2, 2}}
+
+	if got := box.ClosestPoint(mgl32.Vec3{1, 1, 1}); got != (mgl32.Vec3{1, 1, 1}) {
+		t.Errorf("closest point to an inside point is %v", got)
+	}
+	if got := box.ClosestPoint(mgl32.Vec3{5, 1, -3}); got != (mgl32.Vec3{2, 1, 0}) {
+		t.Errorf("closest point is %v, want [2 1 0]", got)
+	}
+	if got := box.Distance(mgl32.Vec3{5, 6, 1}); got != 5 {
+		t.Errorf("distance is %v, want 5", got)
+	}
+	if got := box.Distance(mgl32.Vec3{1, 1, 1}); got != 0 {
+		t.Errorf("distance to an inside point is %v, want 0", got)
+	}
+
+	s := box.BoundingSphere()
+	if s.Center != (mgl32.Vec3{1, 1, 1}) || !floatNear(s.Radius, float32(math.Sqrt(3))) {
+		t.Errorf("bounding sphere is %v", s)
+	}
+}
@@ -0,0 +1,147 @@
+package geom
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Plane is the set of points p where Normal.Dot(p) + D = 0.
+// Points on the side the normal points to have a positive distance.
+type Plane struct {
+	Normal mgl32.Vec3
+	D      float32
+}
+
+// NewPlane makes the plane through point facing normal (which must be normalized).
+func NewPlane(normal, point mgl32.Vec3) Plane {
+	return Plane{Normal: normal, D: -normal.Dot(point)}
+}
+
+// Distance is the signed distance from the plane to p. It is only a true distance
+// when the normal is normalized.
+func (pl Plane) Distance(p mgl32.Vec3) float32 {
+	return pl.Normal.Dot(p) + pl.D
+}
+
+func (pl Plane) Normalize() Plane {
+	length := pl.Normal.Len()
+	if length == 0 {
+		return pl
+	}
+	return Plane{Normal: pl.Normal.Mul(1 / length), D: pl.D / length}
+}
+
+// Containment is the result of testing a volume against a frustum.
+type Containment int
+
+const (
+	Outside Containment = iota
+	Intersecting
+	Inside
+)
+
+func (c Containment) String() string {
+	switch c {
+	case Outside:
+		return "Outside"
+	case Intersecting:
+		return "Intersecting"
+	case Inside:
+		return "Inside"
+	}
+	return fmt.Sprintf("Containment(%d)", int(c))
+}
+
+// planes of a Frustum, in the order they are stored
+const (
+	LeftPlane = iota
+	RightPlane
+	BottomPlane
+	TopPlane
+	NearPlane
+	FarPlane
+)
+
+// Frustum is the volume visible to a camera, bounded by 6 planes facing inwards.
+type Frustum struct {
+	Planes [6]Plane
+}
+
+// NewFrustum extracts the planes from a combined projection and view matrix
+// (project.Mul4(view)) so the planes are in world space. Passing only the projection gives
+// planes in view space, and project * view * model gives them in model space.
+//
+// A point p is inside when -w <= x, y, z <= w for (x, y, z, w) = m * p. Each of those
+// 6 inequalities is a plane made from the rows of m (Gribb and Hartmann, 2001).
+func NewFrustum(m mgl32.Mat4) Frustum {
+	row := func(i int) mgl32.Vec4 { return m.Row(i) }
+	w := row(3)
+
+	f := Frustum{}
+	for i, v := range [6]mgl32.Vec4{
+		w.Add(row(0)), // left:   -w <= x
+		w.Sub(row(0)), // right:   x <= w
+		w.Add(row(1)), // bottom: -w <= y
+		w.Sub(row(1)), // top:     y <= w
+		w.Add(row(2)), // near:   -w <= z
+		w.Sub(row(2)), // far:     z <= w
+	} {
+		f.Planes[i] = Plane{Normal: v.Vec3(), D: v.W()}.Normalize()
+	}
+	return f
+}
+
+func (f *Frustum) ContainsPoint(p mgl32.Vec3) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(p) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsSphere returns whether any part of s could be inside the frustum.
+// Like all plane tests it can report spheres near the corners that are just outside.
+func (f *Frustum) IntersectsSphere(s Sphere) bool {
+	for _, plane := range f.Planes {
+		if plane.Distance(s.Center) < -s.Radius {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectsAABB returns whether any part of b could be inside the frustum.
+func (f *Frustum) IntersectsAABB(b AABB) bool {
+	return f.ClassifyAABB(b) != Outside
+}
+
+// ClassifyAABB tells whether b is completely outside, completely inside or crosses
+// the frustum. Knowing a box is completely inside lets a hierarchy skip testing its children.
+func (f *Frustum) ClassifyAABB(b AABB) Containment {
+	// an empty box has negative extents that would pass every plane
+	if b.IsEmpty() {
+		return Outside
+	}
+
+	center := b.Center()
+	extents := b.Extents()
+	result := Inside
+
+	for _, plane := range f.Planes {
+		// how far the box reaches towards the plane normal from its center
+		reach := abs(plane.Normal.X())*extents.X() +
+			abs(plane.Normal.Y())*extents.Y() +
+			abs(plane.Normal.Z())*extents.Z()
+		dist := plane.Distance(center)
+
+		if dist < -reach {
+			return Outside
+		}
+		if dist < reach {
+			result = Intersecting
+		}
+	}
+	return result
+}
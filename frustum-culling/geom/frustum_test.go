@@ -0,0 +1,189 @@
+package geom
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// mgl32's approximate comparisons are relative, which is too strict for results near 0
+func vecNear(a, b mgl32.Vec3) bool {
+	return a.Sub(b).Len() < 1e-5
+}
+
+func floatNear(a, b float32) bool {
+	return abs(a-b) < 1e-5
+}
+
+func planeApproxEqual(a, b Plane) bool {
+	return vecNear(a.Normal, b.Normal) && floatNear(a.D, b.D)
+}
+
+func checkPlanes(t *testing.T, name string, f Frustum, want [6]Plane) {
+	names := [6]string{"left", "right", "bottom", "top", "near", "far"}
+	for i := range want {
+		if !planeApproxEqual(f.Planes[i], want[i]) {
+			t.Errorf("%s: %s plane is %v, want %v", name, names[i], f.Planes[i], want[i])
+		}
+	}
+}
+
+func TestNewFrustumPerspective(t *testing.T) {
+	// 90 degrees both ways so the side planes are at 45 degrees to the view direction
+	f := NewFrustum(mgl32.Perspective(mgl32.DegToRad(90), 1, 1, 10))
+
+	s := float32(math.Sqrt2 / 2)
+	checkPlanes(t, "perspective", f, [6]Plane{
+		LeftPlane:   {Normal: mgl32.Vec3{s, 0, -s}},
+		RightPlane:  {Normal: mgl32.Vec3{-s, 0, -s}},
+		BottomPlane: {Normal: mgl32.Vec3{0, s, -s}},
+		TopPlane:    {Normal: mgl32.Vec3{0, -s, -s}},
+		NearPlane:   {Normal: mgl32.Vec3{0, 0, -1}, D: -1},
+		FarPlane:    {Normal: mgl32.Vec3{0, 0, 1}, D: 10},
+	})
+}
+
+func TestNewFrustumOrtho(t *testing.T) {
+	f := NewFrustum(mgl32.Ortho(-2, 2, -1, 1, 0.5, 20))
+
+	checkPlanes(t, "ortho", f, [6]Plane{
+		LeftPlane:   {Normal: mgl32.Vec3{1, 0, 0}, D: 2},
+		RightPlane:  {Normal: mgl32.Vec3{-1, 0, 0}, D: 2},
+		BottomPlane: {Normal: mgl32.Vec3{0, 1, 0}, D: 1},
+		TopPlane:    {Normal: mgl32.Vec3{0, -1, 0}, D: 1},
+		NearPlane:   {Normal: mgl32.Vec3{0, 0, -1}, D: -0.5},
+		FarPlane:    {Normal: mgl32.Vec3{0, 0, 1}, D: 20},
+	})
+}
+
+func TestNewFrustumWorldSpace(t *testing.T) {
+	// a camera at x = 5 looking back at the origin
+	view := mgl32.LookAtV(mgl32.Vec3{5, 0, 0}, mgl32.Vec3{}, mgl32.Vec3{0, 1, 0})
+	f := NewFrustum(mgl32.Ortho(-2, 2, -1, 1, 0.5, 20).Mul4(view))
+
+	checkPlanes(t, "world space", f, [6]Plane{
+		LeftPlane:   {Normal: mgl32.Vec3{0, 0, -1}, D: 2},
+		RightPlane:  {Normal: mgl32.Vec3{0, 0, 1}, D: 2},
+		BottomPlane: {Normal: mgl32.Vec3{0, 1, 0}, D: 1},
+		TopPlane:    {Normal: mgl32.Vec3{0, -1, 0}, D: 1},
+		NearPlane:   {Normal: mgl32.Vec3{-1, 0, 0}, D: 4.5},
+		FarPlane:    {Normal: mgl32.Vec3{1, 0, 0}, D: 15},
+	})
+
+	for _, test := range []struct {
+		p    mgl32.Vec3
+		want bool
+	}{
+		{mgl32.Vec3{0, 0, 0}, true},
+		{mgl32.Vec3{4.6, 0, 0}, false}, // in front of the near plane
+		{mgl32.Vec3{-16, 0, 0}, false}, // behind the far plane
+		{mgl32.Vec3{0, 0, 2.5}, false}, // off to the camera's left
+		{mgl32.Vec3{0, 0.9, -1.9}, true},
+	} {
+		if got := f.ContainsPoint(test.p); got != test.want {
+			t.Errorf("ContainsPoint(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+}
+
+func boxAround(center mgl32.Vec3, extent float32) AABB {
+	e := mgl32.Vec3{extent, extent, extent}
+	return AABB{Min: center.Sub(e), Max: center.Add(e)}
+}
+
+// volumes around each plane of a perspective frustum looking down -z from 1 to 10 with a
+// 90 degree field of view, so at z = -5 the sides are at x, y = +-5
+var planeTests = []struct {
+	name     string
+	outside  mgl32.Vec3
+	crossing mgl32.Vec3
+}{
+	{"left", mgl32.Vec3{-8, 0, -5}, mgl32.Vec3{-5, 0, -5}},
+	{"right", mgl32.Vec3{8, 0, -5}, mgl32.Vec3{5, 0, -5}},
+	{"bottom", mgl32.Vec3{0, -8, -5}, mgl32.Vec3{0, -5, -5}},
+	{"top", mgl32.Vec3{0, 8, -5}, mgl32.Vec3{0, 5, -5}},
+	{"near", mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 0, -1}},
+	{"far", mgl32.Vec3{0, 0, -12}, mgl32.Vec3{0, 0, -10}},
+}
+
+func testFrustum() Frustum {
+	return NewFrustum(mgl32.Perspective(mgl32.DegToRad(90), 1, 1, 10))
+}
+
+func TestClassifyAABB(t *testing.T) {
+	f := testFrustum()
+
+	if got := f.ClassifyAABB(boxAround(mgl32.Vec3{0, 0, -5}, 0.5)); got != Inside {
+		t.Errorf("box in the middle is %v, want Inside", got)
+	}
+	for _, test := range planeTests {
+		if got := f.ClassifyAABB(boxAround(test.outside, 0.25)); got != Outside {
+			t.Errorf("box past the %s plane is %v, want Outside", test.name, got)
+		}
+		if got := f.ClassifyAABB(boxAround(test.crossing, 0.25)); got != Intersecting {
+			t.Errorf("box across the %s plane is %v, want Intersecting", test.name, got)
+		}
+	}
+
+	if got := f.ClassifyAABB(boxAround(mgl32.Vec3{}, 100)); got != Intersecting {
+		t.Errorf("box around the whole frustum is %v, want Intersecting", got)
+	}
+	if got := f.ClassifyAABB(EmptyAABB()); got != Outside {
+		t.Errorf("empty box is %v, want Outside", got)
+	}
+	if f.IntersectsAABB(EmptyAABB()) {
+		t.Error("empty box intersects")
+	}
+}
+
+func TestClassifyTransformedAABB(t *testing.T) {
+	f := testFrustum()
+	cube := boxAround(mgl32.Vec3{}, 1)
+
+	tests := []struct {
+		name  string
+		model mgl32.Mat4
+		want  Containment
+	}{
+		{"in the middle", mgl32.Translate3D(0, 0, -5), Inside},
+		{"behind the camera", mgl32.Translate3D(0, 0, 5), Outside},
+		{"scaled across the far plane", mgl32.Translate3D(0, 0, -8).Mul4(mgl32.Scale3D(1, 1, 3)), Intersecting},
+		// rotated 45 degrees the cube's box reaches sqrt(2) along x and z, across the right side
+		{"close to the right plane", mgl32.Translate3D(2.5, 0, -5), Inside},
+		{"rotated across the right plane",
+			mgl32.Translate3D(2.5, 0, -5).Mul4(mgl32.HomogRotate3DY(mgl32.DegToRad(45))), Intersecting},
+	}
+
+	for _, test := range tests {
+		if got := f.ClassifyAABB(cube.Transform(test.model)); got != test.want {
+			t.Errorf("cube %s is %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIntersectsSphere(t *testing.T) {
+	f := testFrustum()
+
+	if !f.IntersectsSphere(Sphere{Center: mgl32.Vec3{0, 0, -5}, Radius: 0.5}) {
+		t.Error("sphere in the middle does not intersect")
+	}
+	for _, test := range planeTests {
+		if f.IntersectsSphere(Sphere{Center: test.outside, Radius: 0.25}) {
+			t.Errorf("sphere past the %s plane intersects", test.name)
+		}
+		if !f.IntersectsSphere(Sphere{Center: test.crossing, Radius: 0.25}) {
+			t.Errorf("sphere across the %s plane does not intersect", test.name)
+		}
+	}
+
+	// the sphere reaches past the near plane once it has been scaled up, the radius grows by
+	// the largest scale even if it is not towards the frustum
+	s := Sphere{Center: mgl32.Vec3{0, 0, 1.5}, Radius: 1}
+	if f.IntersectsSphere(s) {
+		t.Error("sphere behind the camera intersects")
+	}
+	if !f.IntersectsSphere(s.Transform(mgl32.Scale3D(1, 3, 1))) {
+		t.Error("scaled sphere does not intersect")
+	}
+}
@@ -0,0 +1,76 @@
+package geom
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Ray is a half line starting at Origin. Distances along it are in units of Direction's
+// length, which is 1 for rays made with NewRay.
+type Ray struct {
+	Origin    mgl32.Vec3
+	Direction mgl32.Vec3
+}
+
+func NewRay(origin, direction mgl32.Vec3) Ray {
+	return Ray{Origin: origin, Direction: direction.Normalize()}
+}
+
+// At is the point dist along the ray.
+func (r Ray) At(dist float32) mgl32.Vec3 {
+	return r.Origin.Add(r.Direction.Mul(dist))
+}
+
+// IntersectAABB returns the distance along the ray to where it enters b, or 0 when the
+// origin is already inside.
+func (r Ray) IntersectAABB(b AABB) (float32, bool) {
+	near, far := float32(0), float32(math.Inf(1))
+
+	// the ray is inside the box between where it has crossed all 3 pairs of planes (slabs)
+	for axis := 0; axis < 3; axis++ {
+		if r.Direction[axis] == 0 {
+			// parallel to the slab so it is either always or never between its planes
+			if r.Origin[axis] < b.Min[axis] || r.Origin[axis] > b.Max[axis] {
+				return 0, false
+			}
+			continue
+		}
+
+		inv := 1 / r.Direction[axis]
+		t0 := (b.Min[axis] - r.Origin[axis]) * inv
+		t1 := (b.Max[axis] - r.Origin[axis]) * inv
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > near {
+			near = t0
+		}
+		if t1 < far {
+			far = t1
+		}
+		if near > far {
+			return 0, false
+		}
+	}
+	return near, true
+}
+
+// IntersectSphere returns the distance along the ray to where it enters s, or 0 when the
+// origin is already inside.
+func (r Ray) IntersectSphere(s Sphere) (float32, bool) {
+	// solve |origin + t*dir - center|^2 = radius^2 for t
+	toOrigin := r.Origin.Sub(s.Center)
+	a := r.Direction.LenSqr()
+	halfB := toOrigin.Dot(r.Direction)
+	c := toOrigin.LenSqr() - s.Radius*s.Radius
+
+	if c <= 0 {
+		return 0, true
+	}
+	discriminant := halfB*halfB - a*c
+	if halfB > 0 || discriminant < 0 {
+		return 0, false
+	}
+	return (-halfB - float32(math.Sqrt(float64(discriminant)))) / a, true
+}
@@ -0,0 +1,112 @@
+package geom
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestNewSphereFromPoints(t *testing.T) {
+	if s := NewSphereFromPoints(nil); s != (Sphere{}) {
+		t.Errorf("sphere around no points is %v", s)
+	}
+
+	// the two furthest apart points are found first and the rest are already inside
+	s := NewSphereFromPoints([]mgl32.Vec3{{-2, 0, 0}, {0, 1, 0}, {2, 0, 0}, {0, 0, -1}})
+	if !vecNear(s.Center, mgl32.Vec3{}) || !floatNear(s.Radius, 2) {
+		t.Errorf("got %v, want radius 2 around the origin", s)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	points := make([]mgl32.Vec3, 200)
+	for i := range points {
+		points[i] = mgl32.Vec3{random.Float32()*10 - 5, random.Float32()*4 - 2, random.Float32() * 3}
+	}
+	s = NewSphereFromPoints(points)
+	for _, p := range points {
+		if p.Sub(s.Center).Len() > s.Radius*(1+1e-5) {
+			t.Fatalf("%v is outside of %v", p, s)
+		}
+	}
+
+	// no sphere around the points is smaller than half the longest side of the box around them
+	// and the best one is at most half its diagonal, Ritter's is within about 20% of the best
+	extents := NewAABBFromPoints(points).Extents()
+	longest := extents.X()
+	for _, e := range []float32{extents.Y(), extents.Z()} {
+		if e > longest {
+			longest = e
+		}
+	}
+	if s.Radius < longest || s.Radius > 1.2*extents.Len() {
+		t.Errorf("radius %v is not between %v and %v", s.Radius, longest, 1.2*extents.Len())
+	}
+}
+
+func TestSphereExtend(t *testing.T) {
+	s := Sphere{Center: mgl32.Vec3{0, 0, 0}, Radius: 1}
+
+	if got := s.Extend(mgl32.Vec3{0.5, 0, 0}); got != s {
+		t.Errorf("extending by an inside point gave %v", got)
+	}
+
+	// the new sphere touches the far side of the old one at x = -1 and the point at x = 3
+	got := s.Extend(mgl32.Vec3{3, 0, 0})
+	if !vecNear(got.Center, mgl32.Vec3{1, 0, 0}) || !floatNear(got.Radius, 2) {
+		t.Errorf("got %v, want radius 2 around [1 0 0]", got)
+	}
+}
+
+func TestSphereIntersections(t *testing.T) {
+	s := Sphere{Center: mgl32.Vec3{0, 0, 0}, Radius: 1}
+
+	tests := []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"contains a point inside", s.Contains(mgl32.Vec3{0, 0.5, 0.5}), true},
+		{"contains a point on the surface", s.Contains(mgl32.Vec3{0, 1, 0}), true},
+		{"contains a point outside", s.Contains(mgl32.Vec3{0.8, 0.8, 0}), false},
+		{"touching sphere", s.Intersects(Sphere{Center: mgl32.Vec3{3, 0, 0}, Radius: 2}), true},
+		{"sphere apart", s.Intersects(Sphere{Center: mgl32.Vec3{3, 0, 0}, Radius: 1.5}), false},
+		{"box around the center", s.IntersectsAABB(boxAround(mgl32.Vec3{}, 5)), true},
+		{"box next to the sphere", s.IntersectsAABB(AABB{Min: mgl32.Vec3{0.5, -1, -1}, Max: mgl32.Vec3{2, 1, 1}}), true},
+		// within reach of the sphere along x and y but not diagonally
+		{"box past the sphere's corner", s.IntersectsAABB(AABB{Min: mgl32.Vec3{0.8, 0.8, -1}, Max: mgl32.Vec3{2, 2, 1}}), false},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
+
+func TestSphereTransform(t *testing.T) {
+	s := Sphere{Center: mgl32.Vec3{1, 0, 0}, Radius: 1}
+
+	tests := []struct {
+		name string
+		m    mgl32.Mat4
+		want Sphere
+	}{
+		{"translated", mgl32.Translate3D(0, 2, 0), Sphere{Center: mgl32.Vec3{1, 2, 0}, Radius: 1}},
+		{"rotated", mgl32.HomogRotate3DZ(mgl32.DegToRad(90)), Sphere{Center: mgl32.Vec3{0, 1, 0}, Radius: 1}},
+		// the radius grows by the largest scale so the stretched shape stays inside
+		{"scaled unevenly", mgl32.Scale3D(0.5, 3, 1), Sphere{Center: mgl32.Vec3{0.5, 0, 0}, Radius: 3}},
+	}
+
+	for _, test := range tests {
+		got := s.Transform(test.m)
+		if !vecNear(got.Center, test.want.Center) || !floatNear(got.Radius, test.want.Radius) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	box := s.AABB()
+	if box != (AABB{Min: mgl32.Vec3{0, -1, -1}, Max: mgl32.Vec3{2, 1, 1}}) {
+		t.Errorf("box around the sphere is %v", box)
+	}
+}
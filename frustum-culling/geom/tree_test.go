@@ -0,0 +1,360 @@
+package geom
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func randomBox(random *rand.Rand, extent float32) AABB {
+	center := mgl32.Vec3{
+		(random.Float32()*2 - 1) * extent,
+		(random.Float32()*2 - 1) * extent / 4,
+		(random.Float32()*2 - 1) * extent,
+	}
+	return boxAround(center, 0.2+random.Float32())
+}
+
+// bruteForce keeps the real bounds of every item in the tree to check the tree's answers
+type bruteForce map[int]AABB
+
+func (bf bruteForce) queryAABB(b AABB) []int {
+	var ids []int
+	for id, bounds := range bf {
+		if bounds.Intersects(b) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (bf bruteForce) queryFrustum(f *Frustum) []int {
+	var ids []int
+	for id, bounds := range bf {
+		if f.IntersectsAABB(bounds) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (bf bruteForce) raycast(r Ray) (float32, bool) {
+	best, found := float32(math.Inf(1)), false
+	for _, bounds := range bf {
+		if dist, ok := r.IntersectAABB(bounds); ok && dist < best {
+			best, found = dist, true
+		}
+	}
+	return best, found
+}
+
+func (bf bruteForce) nearest(p mgl32.Vec3) (float32, bool) {
+	best, found := float32(math.Inf(1)), false
+	for _, bounds := range bf {
+		if dist := bounds.Distance(p); dist < best {
+			best, found = dist, true
+		}
+	}
+	return best, found
+}
+
+// the tree answers with fat boxes, these narrow it down to the real bounds like a caller would
+
+func treeQueryAABB(tree *Tree, bf bruteForce, b AABB) []int {
+	var ids []int
+	tree.QueryAABB(b, func(id int) bool {
+		if bf[id].Intersects(b) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	sort.Ints(ids)
+	return ids
+}
+
+func treeQueryFrustum(tree *Tree, bf bruteForce, f *Frustum) []int {
+	var ids []int
+	tree.QueryFrustum(f, func(id int) bool {
+		if f.IntersectsAABB(bf[id]) {
+			ids = append(ids, id)
+		}
+		return true
+	})
+	sort.Ints(ids)
+	return ids
+}
+
+func treeRaycast(tree *Tree, bf bruteForce, r Ray) (float32, bool) {
+	_, dist, ok := tree.QueryRay(r, float32(math.Inf(1)), func(id int) (float32, bool) {
+		return r.IntersectAABB(bf[id])
+	})
+	return dist, ok
+}
+
+func treeNearest(tree *Tree, bf bruteForce, p mgl32.Vec3) (float32, bool) {
+	_, dist, ok := tree.Nearest(p, float32(math.Inf(1)), func(id int) float32 {
+		return bf[id].Distance(p)
+	})
+	return dist, ok
+}
+
+func sameIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func randomFrustum(random *rand.Rand, extent float32) Frustum {
+	eye := mgl32.Vec3{(random.Float32()*2 - 1) * extent, 2, (random.Float32()*2 - 1) * extent}
+	target := mgl32.Vec3{(random.Float32()*2 - 1) * extent, 0, (random.Float32()*2 - 1) * extent}
+	view := mgl32.LookAtV(eye, target, mgl32.Vec3{0, 1, 0})
+	return NewFrustum(mgl32.Perspective(mgl32.DegToRad(60), 16.0/9, 0.1, extent).Mul4(view))
+}
+
+func randomRay(random *rand.Rand, extent float32) Ray {
+	origin := mgl32.Vec3{(random.Float32()*2 - 1) * extent, 1, (random.Float32()*2 - 1) * extent}
+	dir := mgl32.Vec3{random.Float32()*2 - 1, random.Float32() - 0.5, random.Float32()*2 - 1}
+	return NewRay(origin, dir)
+}
+
+// checkTree compares every kind of query against testing each item's bounds
+func checkTree(t *testing.T, random *rand.Rand, tree *Tree, bf bruteForce, extent float32) {
+	t.Helper()
+
+	if tree.Len() != len(bf) {
+		t.Fatalf("tree has %d items, want %d", tree.Len(), len(bf))
+	}
+	for id, bounds := range bf {
+		if !tree.Bounds(id).ContainsAABB(bounds) {
+			t.Fatalf("item %d: fat box %v does not contain its bounds %v", id, tree.Bounds(id), bounds)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		b := randomBox(random, extent)
+		b.Max = b.Max.Add(mgl32.Vec3{5, 5, 5})
+		if got, want := treeQueryAABB(tree, bf, b), bf.queryAABB(b); !sameIDs(got, want) {
+			t.Fatalf("QueryAABB(%v) found %v, want %v", b, got, want)
+		}
+
+		f := randomFrustum(random, extent)
+		if got, want := treeQueryFrustum(tree, bf, &f), bf.queryFrustum(&f); !sameIDs(got, want) {
+			t.Fatalf("QueryFrustum found %d items, want %d", len(got), len(want))
+		}
+
+		r := randomRay(random, extent)
+		gotDist, gotOK := treeRaycast(tree, bf, r)
+		wantDist, wantOK := bf.raycast(r)
+		if gotOK != wantOK || gotDist != wantDist {
+			t.Fatalf("QueryRay(%v) = %v, %v, want %v, %v", r, gotDist, gotOK, wantDist, wantOK)
+		}
+
+		p := randomRay(random, extent).Origin
+		gotDist, gotOK = treeNearest(tree, bf, p)
+		wantDist, wantOK = bf.nearest(p)
+		if gotOK != wantOK || gotDist != wantDist {
+			t.Fatalf("Nearest(%v) = %v, %v, want %v, %v", p, gotDist, gotOK, wantDist, wantOK)
+		}
+	}
+}
+
+func TestTreeMatchesBruteForce(t *testing.T) {
+	const extent = 50
+	random := rand.New(rand.NewSource(1))
+	tree := NewTree(0.5)
+	bf := bruteForce{}
+
+	checkTree(t, random, tree, bf, extent)
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 50; i++ {
+			b := randomBox(random, extent)
+			bf[tree.Insert(b, i)] = b
+		}
+
+		// most moves stay inside the fat box, some jump far away
+		for id, b := range bf {
+			if random.Intn(2) == 0 {
+				continue
+			}
+			offset := mgl32.Vec3{random.Float32() - 0.5, 0, random.Float32() - 0.5}
+			if random.Intn(4) == 0 {
+				offset = offset.Mul(40)
+			}
+			moved := AABB{Min: b.Min.Add(offset), Max: b.Max.Add(offset)}
+			tree.Update(id, moved)
+			bf[id] = moved
+		}
+
+		for id := range bf {
+			if random.Intn(3) == 0 {
+				tree.Remove(id)
+				delete(bf, id)
+			}
+		}
+
+		checkTree(t, random, tree, bf, extent)
+	}
+
+	// an AVL balanced tree of n leaves is at most about 1.44 log2(n) high
+	if limit := int(1.44*math.Log2(float64(tree.Len()))) + 2; tree.Height() > limit {
+		t.Errorf("tree of %d items is %d high, want at most %d", tree.Len(), tree.Height(), limit)
+	}
+
+	for id := range bf {
+		tree.Remove(id)
+		delete(bf, id)
+	}
+	checkTree(t, random, tree, bf, extent)
+	if tree.Height() != 0 {
+		t.Errorf("empty tree is %d high", tree.Height())
+	}
+}
+
+func TestTreeRemoveTwice(t *testing.T) {
+	tree := NewTree(0.5)
+	a := tree.Insert(boxAround(mgl32.Vec3{0, 0, 0}, 1), "a")
+	b := tree.Insert(boxAround(mgl32.Vec3{5, 0, 0}, 1), "b")
+	inner := tree.root
+
+	tree.Remove(a)
+	for _, test := range []struct {
+		name string
+		op   func()
+	}{
+		{"removing a removed id", func() { tree.Remove(a) }},
+		{"updating a removed id", func() { tree.Update(a, boxAround(mgl32.Vec3{}, 1)) }},
+		{"removing an inner node", func() { tree.Remove(inner) }},
+		{"removing an id never handed out", func() { tree.Remove(10) }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", test.name)
+				}
+			}()
+			test.op()
+		}()
+	}
+
+	// the freed node is handed out once and the tree is still intact
+	c := tree.Insert(boxAround(mgl32.Vec3{0, 5, 0}, 1), "c")
+	d := tree.Insert(boxAround(mgl32.Vec3{0, -5, 0}, 1), "d")
+	if c == d || c == b || d == b {
+		t.Errorf("ids %d, %d and %d are not distinct", b, c, d)
+	}
+	if tree.Len() != 3 {
+		t.Errorf("tree has %d items, want 3", tree.Len())
+	}
+	for id, want := range map[int]string{b: "b", c: "c", d: "d"} {
+		if tree.Item(id) != want {
+			t.Errorf("item %d is %v, want %s", id, tree.Item(id), want)
+		}
+	}
+}
+
+// a field of boxes like the frustum-culling sample's, 80 x 80 objects 3 units apart
+const (
+	benchFieldSize    = 80
+	benchFieldSpacing = 3
+)
+
+func benchField() bruteForce {
+	bf := bruteForce{}
+	for x := 0; x < benchFieldSize; x++ {
+		for z := 0; z < benchFieldSize; z++ {
+			center := mgl32.Vec3{
+				float32(x-benchFieldSize/2) * benchFieldSpacing,
+				0,
+				float32(z-benchFieldSize/2) * benchFieldSpacing,
+			}
+			bf[len(bf)] = boxAround(center, 0.5)
+		}
+	}
+	return bf
+}
+
+// the view of a camera standing in the field, looking along it
+func benchQueries() (Frustum, Ray) {
+	eye := mgl32.Vec3{0, 2, 0}
+	front := mgl32.Vec3{1, -0.05, 0.3}.Normalize()
+	view := mgl32.LookAtV(eye, eye.Add(front), mgl32.Vec3{0, 1, 0})
+	frustum := NewFrustum(mgl32.Perspective(mgl32.DegToRad(60), 16.0/9, 0.1, 100).Mul4(view))
+	return frustum, NewRay(eye, front)
+}
+
+func BenchmarkTreeQuery(b *testing.B) {
+	bf := benchField()
+	tree := NewTree(0.2)
+	ids := map[int]int{} // tree id to field index
+	for i := 0; i < len(bf); i++ {
+		ids[tree.Insert(bf[i], i)] = i
+	}
+	bounds := func(id int) AABB { return bf[ids[id]] }
+	frustum, ray := benchQueries()
+
+	b.Run("frustum", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree.QueryFrustum(&frustum, func(id int) bool {
+				frustum.IntersectsAABB(bounds(id))
+				return true
+			})
+		}
+	})
+	b.Run("ray", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree.QueryRay(ray, float32(math.Inf(1)), func(id int) (float32, bool) {
+				return ray.IntersectAABB(bounds(id))
+			})
+		}
+	})
+	b.Run("nearest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tree.Nearest(ray.Origin, float32(math.Inf(1)), func(id int) float32 {
+				return bounds(id).Distance(ray.Origin)
+			})
+		}
+	})
+}
+
+func BenchmarkBruteForce(b *testing.B) {
+	field := benchField()
+	boxes := make([]AABB, len(field))
+	for i := range boxes {
+		boxes[i] = field[i]
+	}
+	frustum, ray := benchQueries()
+
+	b.Run("frustum", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, box := range boxes {
+				frustum.IntersectsAABB(box)
+			}
+		}
+	})
+	b.Run("ray", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, box := range boxes {
+				ray.IntersectAABB(box)
+			}
+		}
+	})
+	b.Run("nearest", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, box := range boxes {
+				box.Distance(ray.Origin)
+			}
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package main
+
+/*
+Builds on scene-graph, filling a large field with crates and balls and only drawing the
+ones whose bounding volumes are inside the camera's view frustum (see the geom package).
+The frustum's six planes are pulled straight out of the project * view matrix each frame.
+
+The nodes are kept in a bounding volume hierarchy (geom.Tree, see scene.Index) so whole
+groups of them can be culled with a single test. A few of the objects float around to
+show that the tree keeps up with moving nodes.
+
+The title shows how many nodes were drawn and culled. Press C to turn culling off and
+compare frame times, or F to freeze the frustum used for culling and fly out of it to see
+what is being skipped. B switches between culling with the tree and testing every node.
+`go test -bench . ./geom` times the tree's queries against testing every box.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/cam"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/geom"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/gfx"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/scene"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/win"
+)
+
+// the objects fill a fieldSize x fieldSize grid around the origin
+const (
+	fieldSize    = 80
+	fieldSpacing = 3.0
+)
+
+// one in every floaterRate objects floats around, the rest stay still
+const floaterRate = 16
+
+// how far a node can move before the index has to reinsert it
+const indexMargin = 0.5
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	floaters      []*scene.Node
+	floaterStarts []mgl32.Vec3
+	flashlight    *scene.Node
+}
+
+/*
+ * Builds the scene: a sun, a flashlight that follows the camera and a grid of
+ * crates and balls at slightly different heights, sizes and rotations.
+ */
+func buildScene(cube, sphere *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.15, 0.15, 0.18},
+		Diffuse:   mgl32.Vec3{0.6, 0.6, 0.6},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	// the spot light points down the node's -z like a camera
+	nodes.flashlight = s.Add(scene.NewNode("flashlight"))
+	nodes.flashlight.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	half := float32(fieldSize-1) * fieldSpacing / 2
+	for z := 0; z < fieldSize; z++ {
+		for x := 0; x < fieldSize; x++ {
+			i := z*fieldSize + x
+			node := s.Add(scene.NewNode(fmt.Sprintf("object %d", i)))
+			node.SetPosition(mgl32.Vec3{
+				float32(x)*fieldSpacing - half,
+				float32((x*7+z*3)%5) * 0.5,
+				float32(z)*fieldSpacing - half,
+			})
+
+			size := 0.8 + float32((x+z*5)%4)*0.3
+			node.SetScale(mgl32.Vec3{size, size, size})
+
+			angle := mgl32.DegToRad(float32(i % 90))
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, 0, mgl32.XYZ))
+
+			node.Mesh = cube
+			if (x+z)%3 == 0 {
+				node.Mesh = sphere
+			}
+
+			if i%floaterRate == 0 {
+				nodes.floaters = append(nodes.floaters, node)
+				nodes.floaterStarts = append(nodes.floaterStarts, node.Position())
+			}
+		}
+	}
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Frustum culling")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	// don't wait for vsync so the frame times show the cost of what is drawn
+	glfw.SwapInterval(0)
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	sphere, err := gfx.NewSphereMesh(24, 48).Upload()
+	if err != nil {
+		return err
+	}
+	defer sphere.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	world, nodes := buildScene(cube, sphere)
+	index := scene.NewIndex(world, indexMargin)
+
+	lens := scene.Camera{Fov: 60, Near: 0.1, Far: 150}
+
+	culling := true
+	useIndex := true
+	frozen := false
+	var cullTransform mgl32.Mat4 // project * view the frustum was frozen with
+
+	// counts are from the last frame, frame time is averaged once per second
+	stats := scene.DrawStats{}
+	frames := 0
+	frameTime := 0.0
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 5, 0}, mgl32.Vec3{0, 1, 0}, -90, -10, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		if window.InputManager().IsTriggered(win.TOGGLE_CULLING) {
+			culling = !culling
+		}
+		if window.InputManager().IsTriggered(win.FREEZE_FRUSTUM) {
+			frozen = !frozen
+		}
+		if window.InputManager().IsTriggered(win.TOGGLE_INDEX) {
+			useIndex = !useIndex
+		}
+
+		frames++
+		frameTime += window.SinceLastFrame()
+		if frameTime >= 1.0 {
+			mode := "culling on"
+			if !culling {
+				mode = "culling off"
+			} else if frozen {
+				mode = "culling with frozen frustum"
+			}
+			if culling && useIndex {
+				mode += " (tree)"
+			} else if culling {
+				mode += " (every node)"
+			}
+			window.SetTitle(fmt.Sprintf("Frustum culling - %s - %d drawn, %d culled - %.2f ms/frame",
+				mode, stats.Drawn, stats.Culled, 1000*frameTime/float64(frames)))
+			frames = 0
+			frameTime = 0
+		}
+
+		// the floaters spin and drift in circles, far enough that the index has to move them
+		time := float32(glfw.GetTime())
+		for i, node := range nodes.floaters {
+			phase := time + float32(i)
+			offset := mgl32.Vec3{
+				3 * float32(math.Cos(float64(phase/2))),
+				2 + 2*float32(math.Sin(float64(phase))),
+				3 * float32(math.Sin(float64(phase/2))),
+			}
+			node.SetPosition(nodes.floaterStarts[i].Add(offset))
+
+			angle := mgl32.DegToRad(-30 * phase)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, 0, mgl32.XYZ))
+		}
+		index.Update()
+
+		// the flashlight is held by the camera
+		nodes.flashlight.SetPosition(camera.Position())
+		nodes.flashlight.LookAt(camera.Position().Add(camera.Front()), mgl32.Vec3{0, 1, 0})
+
+		// background color
+		gl.ClearColor(0.05, 0.05, 0.08, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		aspect := float32(window.Width()) / float32(window.Height())
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(aspect)
+
+		// the frustum is in world space since the node bounds are
+		if !frozen {
+			cullTransform = projectTransform.Mul4(camTransform)
+		}
+		frustum := geom.NewFrustum(cullTransform)
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		// the scene gives back the lights in world space, they then convert themselves
+		// to view space using the camera transform
+		lights := world.Lights()
+
+		gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+		if len(lights.Directional) > 0 {
+			lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+		}
+		gl.Uniform1i(program.GetUniformLocation("numPointLights"), 0)
+
+		gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+		if len(lights.Spot) > 0 {
+			lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+		}
+
+		if !culling {
+			stats = world.DrawCulled(program, nil, nil)
+		} else if useIndex {
+			stats = index.DrawCulled(program, &frustum, nil)
+		} else {
+			stats = world.DrawCulled(program, &frustum, nil)
+		}
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package scene
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/geom"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/gfx"
+)
+
+// Index keeps the world bounds of a scene's visible nodes with meshes in a geom.Tree so
+// culling and picking only look at the nodes near what they are after instead of all of them.
+//
+// Only one Index should track a scene at a time since nodes remember whether they moved
+// since the index last saw them.
+type Index struct {
+	scene      *Scene
+	tree       *geom.Tree
+	proxies    map[*Node]*proxy
+	generation uint
+}
+
+// proxy is a node's entry in the tree
+type proxy struct {
+	id   int
+	mesh *gfx.VertexArray // the mesh the bounds came from
+	seen uint             // generation of the last Update that found the node
+}
+
+// NewIndex indexes the nodes of s. margin is how far a node can move before it has to be
+// reinserted in the tree, see geom.Tree.
+func NewIndex(s *Scene, margin float32) *Index {
+	idx := &Index{
+		scene:   s,
+		tree:    geom.NewTree(margin),
+		proxies: make(map[*Node]*proxy),
+	}
+	idx.Update()
+	return idx
+}
+
+// Tree is the hierarchy holding the nodes, its items are *Node.
+func (idx *Index) Tree() *geom.Tree {
+	return idx.tree
+}
+
+// Update brings the index up to date with the scene: new nodes are added, nodes that moved
+// are refit and nodes that were removed, hidden or lost their mesh are dropped.
+// Call it after animating the scene and before querying.
+func (idx *Index) Update() {
+	idx.generation++
+
+	idx.scene.Walk(func(n *Node) {
+		if n.Mesh == nil {
+			return
+		}
+		p, ok := idx.proxies[n]
+		if !ok {
+			p = &proxy{id: idx.tree.Insert(n.WorldBounds(), n), mesh: n.Mesh}
+			idx.proxies[n] = p
+		} else if n.moved || p.mesh != n.Mesh {
+			idx.tree.Update(p.id, n.WorldBounds())
+			p.mesh = n.Mesh
+		}
+		n.moved = false
+		p.seen = idx.generation
+	})
+
+	for n, p := range idx.proxies {
+		if p.seen != idx.generation {
+			idx.tree.Remove(p.id)
+			delete(idx.proxies, n)
+		}
+	}
+}
+
+func (idx *Index) node(id int) *Node {
+	return idx.tree.Item(id).(*Node)
+}
+
+// Visible calls visit for every indexed node whose world bounds are at least partly inside
+// frustum until visit returns false.
+func (idx *Index) Visible(frustum *geom.Frustum, visit func(*Node) bool) {
+	idx.tree.QueryFrustum(frustum, func(id int) bool {
+		// the tree only knows the fat bounds, check the real ones
+		n := idx.node(id)
+		if !frustum.IntersectsAABB(n.WorldBounds()) {
+			return true
+		}
+		return visit(n)
+	})
+}
+
+// DrawCulled draws the indexed nodes inside frustum the same way as Scene.DrawCulled.
+// Nodes are drawn in the order the tree finds them rather than parents first.
+func (idx *Index) DrawCulled(prog *gfx.Program, frustum *geom.Frustum, before func(*Node) bool) DrawStats {
+	modelLoc := prog.GetUniformLocation("model")
+	stats := DrawStats{}
+
+	found := 0
+	idx.Visible(frustum, func(n *Node) bool {
+		found++
+		if before != nil && !before(n) {
+			return true
+		}
+
+		model := n.WorldTransform()
+		gl.UniformMatrix4fv(modelLoc, 1, false, &model[0])
+
+		n.Mesh.Bind()
+		n.Mesh.Draw()
+		stats.Drawn++
+		return true
+	})
+	gl.BindVertexArray(0)
+
+	stats.Culled = idx.tree.Len() - found
+	return stats
+}
+
+// Raycast returns the closest node whose world bounds r passes through within maxDist,
+// and the distance along r to them.
+func (idx *Index) Raycast(r geom.Ray, maxDist float32) (*Node, float32, bool) {
+	id, dist, ok := idx.tree.QueryRay(r, maxDist, func(id int) (float32, bool) {
+		return r.IntersectAABB(idx.node(id).WorldBounds())
+	})
+	if !ok {
+		return nil, 0, false
+	}
+	return idx.node(id), dist, true
+}
+
+// Nearest returns the node whose world bounds are closest to p, and how far away they are.
+func (idx *Index) Nearest(p mgl32.Vec3) (*Node, float32, bool) {
+	id, dist, ok := idx.tree.Nearest(p, float32(math.Inf(1)), func(id int) float32 {
+		return idx.node(id).WorldBounds().Distance(p)
+	})
+	if !ok {
+		return nil, 0, false
+	}
+	return idx.node(id), dist, true
+}
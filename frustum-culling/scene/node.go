@@ -0,0 +1,294 @@
+package scene
+
+import (
+	"errors"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/geom"
+	"github.com/cstegel/opengl-samples-golang/frustum-culling/gfx"
+)
+
+// Node is a point in the scene hierarchy with a transform relative to its parent
+// and optionally things attached to it (a mesh, lights, a camera).
+//
+// The local transform is stored as translation, rotation and scale (TRS) and applied
+// in that order: scale first, then rotate, then translate. World transforms are cached and
+// only recomputed after the node or one of its ancestors changes.
+type Node struct {
+	Name string
+
+	// Hidden nodes and everything below them are skipped when drawing and collecting lights
+	Hidden bool
+
+	// attachments, all optional. Light positions and directions are relative to the node.
+	Mesh             *gfx.VertexArray
+	PointLight       *gfx.PointLight
+	SpotLight        *gfx.SpotLight
+	DirectionalLight *gfx.DirectionalLight
+	Camera           *Camera
+
+	parent   *Node
+	children []*Node
+
+	position mgl32.Vec3
+	rotation mgl32.Quat
+	scale    mgl32.Vec3
+
+	local      mgl32.Mat4
+	world      mgl32.Mat4
+	localDirty bool
+	worldDirty bool // if a node is dirty then so are all of its descendants
+
+	// set along with worldDirty but only cleared by the Index, which uses it to skip nodes
+	// that have not moved since it last looked at them
+	moved bool
+}
+
+var errNodeCycle = errors.New("a node can not be a descendant of itself")
+
+func NewNode(name string) *Node {
+	return &Node{
+		Name:       name,
+		rotation:   mgl32.QuatIdent(),
+		scale:      mgl32.Vec3{1, 1, 1},
+		localDirty: true,
+		worldDirty: true,
+		moved:      true,
+	}
+}
+
+func (n *Node) Parent() *Node {
+	return n.parent
+}
+
+// Children returns the node's children, the slice must not be modified.
+func (n *Node) Children() []*Node {
+	return n.children
+}
+
+// AddChild attaches child below n, keeping child's local transform so it moves with n.
+// The child is detached from its previous parent first. It returns child for chaining ex:
+// moon := planet.AddChild(scene.NewNode("moon"))
+//
+// Adding a node below itself is a programming error and panics, use Reparent when the
+// hierarchy comes from user input.
+func (n *Node) AddChild(child *Node) *Node {
+	if err := n.checkCycle(child); err != nil {
+		panic(err)
+	}
+	child.Detach()
+	child.parent = n
+	n.children = append(n.children, child)
+	child.invalidate()
+	return child
+}
+
+// Reparent moves n below parent (nil for no parent) keeping its world transform so it
+// does not jump on screen. This is exact unless an ancestor has non-uniform scale combined
+// with rotation since that shears the world transform which TRS can not represent.
+func (n *Node) Reparent(parent *Node) error {
+	if parent != nil {
+		if err := parent.checkCycle(n); err != nil {
+			return err
+		}
+	}
+
+	world := n.WorldTransform()
+	n.Detach()
+	if parent != nil {
+		world = parent.WorldTransform().Inv().Mul4(world)
+		n.parent = parent
+		parent.children = append(parent.children, n)
+	}
+	n.SetLocalTransform(world)
+	return nil
+}
+
+// checkCycle makes sure that n is not child or below it
+func (n *Node) checkCycle(child *Node) error {
+	for ancestor := n; ancestor != nil; ancestor = ancestor.parent {
+		if ancestor == child {
+			return errNodeCycle
+		}
+	}
+	return nil
+}
+
+// Detach removes n from its parent, making it the root of its own tree.
+func (n *Node) Detach() {
+	if n.parent == nil {
+		return
+	}
+	siblings := n.parent.children
+	for i, sibling := range siblings {
+		if sibling == n {
+			n.parent.children = append(siblings[:i], siblings[i+1:]...)
+			break
+		}
+	}
+	n.parent = nil
+	n.invalidate()
+}
+
+// Walk visits n and its descendants depth first, parents before children.
+// Returning false from visit skips the children of that node.
+func (n *Node) Walk(visit func(*Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for _, child := range n.children {
+		child.Walk(visit)
+	}
+}
+
+// Find returns the first node named name at or below n, or nil.
+func (n *Node) Find(name string) *Node {
+	var found *Node
+	n.Walk(func(node *Node) bool {
+		if found == nil && node.Name == name {
+			found = node
+		}
+		return found == nil
+	})
+	return found
+}
+
+func (n *Node) Position() mgl32.Vec3 {
+	return n.position
+}
+
+func (n *Node) Rotation() mgl32.Quat {
+	return n.rotation
+}
+
+func (n *Node) Scale() mgl32.Vec3 {
+	return n.scale
+}
+
+func (n *Node) SetPosition(position mgl32.Vec3) {
+	n.position = position
+	n.localChanged()
+}
+
+func (n *Node) SetRotation(rotation mgl32.Quat) {
+	n.rotation = rotation.Normalize()
+	n.localChanged()
+}
+
+func (n *Node) SetScale(scale mgl32.Vec3) {
+	n.scale = scale
+	n.localChanged()
+}
+
+// Translate moves the node by delta in its parent's space.
+func (n *Node) Translate(delta mgl32.Vec3) {
+	n.SetPosition(n.position.Add(delta))
+}
+
+// Rotate turns the node by angle (radians) around axis in its own space.
+func (n *Node) Rotate(angle float32, axis mgl32.Vec3) {
+	n.SetRotation(n.rotation.Mul(mgl32.QuatRotate(angle, axis.Normalize())))
+}
+
+// LookAt rotates the node so that its -z axis points at target. target and up are in the
+// parent's space. This matches the convention of cameras and spot lights.
+func (n *Node) LookAt(target, up mgl32.Vec3) {
+	view := mgl32.LookAtV(n.position, target, up)
+	n.SetRotation(mgl32.Mat4ToQuat(view.Inv()))
+}
+
+// SetLocalTransform replaces the node's TRS with one decomposed from m.
+// m must be made of a translation, rotation and scale (no shear or projection).
+func (n *Node) SetLocalTransform(m mgl32.Mat4) {
+	n.position = m.Col(3).Vec3()
+
+	x, y, z := m.Col(0).Vec3(), m.Col(1).Vec3(), m.Col(2).Vec3()
+	n.scale = mgl32.Vec3{x.Len(), y.Len(), z.Len()}
+
+	// a mirrored transform, put the flip in the scale so the rest is a proper rotation
+	if m.Mat3().Det() < 0 {
+		n.scale[0] = -n.scale[0]
+	}
+
+	rot := mgl32.Ident4()
+	for col, axis := range [3]mgl32.Vec3{x, y, z} {
+		if n.scale[col] != 0 {
+			axis = axis.Mul(1 / n.scale[col])
+		}
+		rot.SetCol(col, axis.Vec4(0))
+	}
+	n.rotation = mgl32.Mat4ToQuat(rot).Normalize()
+
+	n.localChanged()
+}
+
+// LocalTransform converts from the node's space to its parent's space.
+func (n *Node) LocalTransform() mgl32.Mat4 {
+	if n.localDirty {
+		n.local = mgl32.Translate3D(n.position.X(), n.position.Y(), n.position.Z()).
+			Mul4(n.rotation.Mat4()).
+			Mul4(mgl32.Scale3D(n.scale.X(), n.scale.Y(), n.scale.Z()))
+		n.localDirty = false
+	}
+	return n.local
+}
+
+// WorldTransform converts from the node's space to world space (ex: the model matrix).
+func (n *Node) WorldTransform() mgl32.Mat4 {
+	if n.worldDirty {
+		if n.parent != nil {
+			n.world = n.parent.WorldTransform().Mul4(n.LocalTransform())
+		} else {
+			n.world = n.LocalTransform()
+		}
+		n.worldDirty = false
+	}
+	return n.world
+}
+
+// WorldPosition is the node's origin in world space.
+func (n *Node) WorldPosition() mgl32.Vec3 {
+	return n.WorldTransform().Col(3).Vec3()
+}
+
+// WorldBounds is the box around the node's mesh in world space, empty without a mesh.
+func (n *Node) WorldBounds() geom.AABB {
+	if n.Mesh == nil {
+		return geom.EmptyAABB()
+	}
+	return n.Mesh.Bounds().Transform(n.WorldTransform())
+}
+
+// WorldBoundingSphere is the sphere around the node's mesh in world space.
+func (n *Node) WorldBoundingSphere() geom.Sphere {
+	if n.Mesh == nil {
+		return geom.Sphere{Center: n.WorldPosition()}
+	}
+	return n.Mesh.BoundingSphere().Transform(n.WorldTransform())
+}
+
+// ViewTransform converts from world space to the node's space. For a node with a camera
+// this is the view matrix.
+func (n *Node) ViewTransform() mgl32.Mat4 {
+	return n.WorldTransform().Inv()
+}
+
+func (n *Node) localChanged() {
+	n.localDirty = true
+	n.invalidate()
+}
+
+// invalidate marks the world transform of n and its descendants as out of date
+func (n *Node) invalidate() {
+	// already dirty means the descendants are too, this keeps moving a node with many
+	// descendants every frame cheap when nothing reads the transforms in between
+	if n.worldDirty {
+		return
+	}
+	n.worldDirty = true
+	n.moved = true
+	for _, child := range n.children {
+		child.invalidate()
+	}
+}
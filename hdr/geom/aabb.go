@@ -0,0 +1,144 @@
+package geom
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// AABB is an axis aligned bounding box.
+// An empty box (containing nothing) has Min > Max, see EmptyAABB.
+type AABB struct {
+	Min mgl32.Vec3
+	Max mgl32.Vec3
+}
+
+// EmptyAABB is a box that contains nothing, extending it by a point gives a box around just that point.
+func EmptyAABB() AABB {
+	inf := float32(math.Inf(1))
+	return AABB{
+		Min: mgl32.Vec3{inf, inf, inf},
+		Max: mgl32.Vec3{-inf, -inf, -inf},
+	}
+}
+
+// NewAABBFromPoints is the smallest box containing all of points.
+func NewAABBFromPoints(points []mgl32.Vec3) AABB {
+	box := EmptyAABB()
+	for _, p := range points {
+		box = box.Extend(p)
+	}
+	return box
+}
+
+func (b AABB) IsEmpty() bool {
+	return b.Min.X() > b.Max.X() || b.Min.Y() > b.Max.Y() || b.Min.Z() > b.Max.Z()
+}
+
+// Extend returns a box that also contains p.
+func (b AABB) Extend(p mgl32.Vec3) AABB {
+	for axis := 0; axis < 3; axis++ {
+		if p[axis] < b.Min[axis] {
+			b.Min[axis] = p[axis]
+		}
+		if p[axis] > b.Max[axis] {
+			b.Max[axis] = p[axis]
+		}
+	}
+	return b
+}
+
+// Union returns a box containing both boxes.
+func (b AABB) Union(other AABB) AABB {
+	if other.IsEmpty() {
+		return b
+	}
+	return b.Extend(other.Min).Extend(other.Max)
+}
+
+func (b AABB) Center() mgl32.Vec3 {
+	return b.Min.Add(b.Max).Mul(0.5)
+}
+
+// Extents is half the size of the box along each axis.
+func (b AABB) Extents() mgl32.Vec3 {
+	return b.Max.Sub(b.Min).Mul(0.5)
+}
+
+func (b AABB) Size() mgl32.Vec3 {
+	return b.Max.Sub(b.Min)
+}
+
+// SurfaceArea is used to compare how good boxes are at enclosing things, ex: building a BVH.
+func (b AABB) SurfaceArea() float32 {
+	if b.IsEmpty() {
+		return 0
+	}
+	s := b.Size()
+	return 2 * (s.X()*s.Y() + s.Y()*s.Z() + s.Z()*s.X())
+}
+
+func (b AABB) Contains(p mgl32.Vec3) bool {
+	return p.X() >= b.Min.X() && p.X() <= b.Max.X() &&
+		p.Y() >= b.Min.Y() && p.Y() <= b.Max.Y() &&
+		p.Z() >= b.Min.Z() && p.Z() <= b.Max.Z()
+}
+
+// ContainsAABB returns whether other is completely inside b.
+func (b AABB) ContainsAABB(other AABB) bool {
+	return b.Contains(other.Min) && b.Contains(other.Max)
+}
+
+func (b AABB) Intersects(other AABB) bool {
+	return b.Min.X() <= other.Max.X() && b.Max.X() >= other.Min.X() &&
+		b.Min.Y() <= other.Max.Y() && b.Max.Y() >= other.Min.Y() &&
+		b.Min.Z() <= other.Max.Z() && b.Max.Z() >= other.Min.Z()
+}
+
+// Transform returns the box around b after transforming it by m, ex: from model to world space.
+// The result is not as tight as transforming the original geometry since the box grows
+// when it is rotated.
+func (b AABB) Transform(m mgl32.Mat4) AABB {
+	if b.IsEmpty() {
+		return b
+	}
+
+	// transform the center and then find how far the rotated extents reach along each axis
+	// (Arvo, Graphics Gems 1990) which is cheaper than transforming all 8 corners
+	center := m.Mul4x1(b.Center().Vec4(1)).Vec3()
+	extents := b.Extents()
+
+	var reach mgl32.Vec3
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			reach[row] += abs(m.At(row, col)) * extents[col]
+		}
+	}
+
+	return AABB{Min: center.Sub(reach), Max: center.Add(reach)}
+}
+
+// BoundingSphere is the sphere through the corners of the box.
+func (b AABB) BoundingSphere() Sphere {
+	return Sphere{Center: b.Center(), Radius: b.Extents().Len()}
+}
+
+// ClosestPoint is the point in or on the box nearest to p.
+func (b AABB) ClosestPoint(p mgl32.Vec3) mgl32.Vec3 {
+	for axis := 0; axis < 3; axis++ {
+		p[axis] = mgl32.Clamp(p[axis], b.Min[axis], b.Max[axis])
+	}
+	return p
+}
+
+// Distance is how far p is from the box, 0 when p is inside.
+func (b AABB) Distance(p mgl32.Vec3) float32 {
+	return b.ClosestPoint(p).Sub(p).Len()
+}
+
+func abs(f float32) float32 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
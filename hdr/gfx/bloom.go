@@ -0,0 +1,173 @@
+package gfx
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Bloom is how a bloom pass makes bright parts of an HDR scene glow, like light scattering in
+// a camera lens or the eye. It is read every time the pass is drawn so it can be changed at
+// any time.
+type Bloom struct {
+	// the brightness where glowing starts, 1 is the brightest a monitor shows before tone
+	// mapping so only things brighter than that glow
+	Threshold float32
+
+	// the fraction of Threshold below it where glowing fades in instead of starting suddenly
+	Knee float32
+
+	// how much of the glow is added to the scene
+	Intensity float32
+
+	// how far the blur reaches out on each level, in texels of that level
+	Radius float32
+}
+
+// DefaultBloom only makes things brighter than white glow.
+var DefaultBloom = Bloom{
+	Threshold: 1,
+	Knee:      0.5,
+	Intensity: 0.5,
+	Radius:    1,
+}
+
+const (
+	// the chain of half sized levels stops at this many or before a level gets smaller than
+	// minBloomSize pixels across
+	maxBloomLevels = 6
+	minBloomSize   = 8
+)
+
+// bloomChain is everything a bloom pass draws with besides its composite program
+type bloomChain struct {
+	settings *Bloom
+
+	// each level is half the size of the one before, starting at half the screen
+	levels []*Framebuffer
+
+	prefilter  *Pass
+	downsample *Pass
+	upsample   *Pass
+}
+
+// NewBloomPass makes a pass that adds a glow around the bright parts of its input. It should
+// go before the tone map pass so it works on HDR colors. width and height are the size of the
+// PostProcessor it is added to.
+//
+// The bright parts are found with a threshold and then blurred by repeatedly halving their
+// size and growing them back (see Jimenez 2014, "Next Generation Post Processing in Call of
+// Duty: Advanced Warfare"). Each level adds a wider blur so the glow is strong right around
+// a light and fades slowly with distance. That is much cheaper than a single blur that wide.
+func NewBloomPass(settings *Bloom, width, height int) (*Pass, error) {
+	chain := bloomChain{settings: settings}
+
+	var err error
+	chain.prefilter, err = NewPass("bloom prefilter", postShader("bloom_prefilter.frag"), func(prog *Program) {
+		gl.Uniform1f(prog.GetUniformLocation("threshold"), settings.Threshold)
+		gl.Uniform1f(prog.GetUniformLocation("knee"), settings.Knee*settings.Threshold)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chain.downsample, err = NewPass("bloom downsample", postShader("bloom_downsample.frag"), nil)
+	if err != nil {
+		chain.delete()
+		return nil, err
+	}
+
+	chain.upsample, err = NewPass("bloom upsample", postShader("bloom_upsample.frag"), func(prog *Program) {
+		gl.Uniform1f(prog.GetUniformLocation("radius"), settings.Radius)
+	})
+	if err != nil {
+		chain.delete()
+		return nil, err
+	}
+
+	if err := chain.resize(width, height); err != nil {
+		chain.delete()
+		return nil, err
+	}
+
+	pass, err := NewPass("bloom", postShader("bloom_composite.frag"), func(prog *Program) {
+		glow := chain.levels[0].Color(0)
+		glow.Bind(gl.TEXTURE1)
+		glow.SetUniform(prog.GetUniformLocation("bloomTexture"))
+
+		// every level adds its blur on top of the ones below so divide to keep the
+		// intensity the same no matter how many levels there are
+		gl.Uniform1f(prog.GetUniformLocation("intensity"), settings.Intensity/float32(len(chain.levels)))
+	})
+	if err != nil {
+		chain.delete()
+		return nil, err
+	}
+
+	pass.prepare = chain.draw
+	pass.resize = chain.resize
+	pass.release = chain.delete
+	return pass, nil
+}
+
+// draw fills levels[0] with the blurred bright parts of input
+func (c *bloomChain) draw(input *Texture, vao uint32) {
+	c.levels[0].Bind()
+	c.prefilter.draw(input, vao)
+	c.levels[0].UnBind()
+
+	for i := 1; i < len(c.levels); i++ {
+		c.levels[i].Bind()
+		c.downsample.draw(c.levels[i-1].Color(0), vao)
+		c.levels[i].UnBind()
+	}
+
+	// going back up each level is blurred again and added to the one above
+	blend := gl.IsEnabled(gl.BLEND)
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.ONE, gl.ONE)
+	for i := len(c.levels) - 2; i >= 0; i-- {
+		c.levels[i].Bind()
+		c.upsample.draw(c.levels[i+1].Color(0), vao)
+		c.levels[i].UnBind()
+	}
+	if !blend {
+		gl.Disable(gl.BLEND)
+	}
+}
+
+// resize remakes the levels for a screen of the given size
+func (c *bloomChain) resize(width, height int) error {
+	c.deleteLevels()
+
+	for w, h := width/2, height/2; len(c.levels) < maxBloomLevels; w, h = w/2, h/2 {
+		if len(c.levels) > 0 && (w < minBloomSize || h < minBloomSize) {
+			break
+		}
+
+		// no alpha and less precision than RGBA16F is plenty for a blur, at half the memory
+		level, err := NewFramebuffer(w, h, FramebufferSpec{
+			Color: []Attachment{{Format: gl.R11F_G11F_B10F}},
+		})
+		if err != nil {
+			c.deleteLevels()
+			return err
+		}
+		c.levels = append(c.levels, level)
+	}
+	return nil
+}
+
+func (c *bloomChain) deleteLevels() {
+	for _, level := range c.levels {
+		level.Delete()
+	}
+	c.levels = nil
+}
+
+func (c *bloomChain) delete() {
+	c.deleteLevels()
+	for _, pass := range []*Pass{c.prefilter, c.downsample, c.upsample} {
+		if pass != nil {
+			pass.Delete()
+		}
+	}
+}
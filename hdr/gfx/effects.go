@@ -0,0 +1,74 @@
+package gfx
+
+import (
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// 3x3 convolution kernels for NewKernelPass. They are listed row by row starting at the top
+// left as they appear on screen. Each one sums to 1 (or 0 for edge detection) so the overall
+// brightness does not change.
+var (
+	SharpenKernel = [9]float32{
+		0, -1, 0,
+		-1, 5, -1,
+		0, -1, 0,
+	}
+
+	BlurKernel = [9]float32{
+		1.0 / 16, 2.0 / 16, 1.0 / 16,
+		2.0 / 16, 4.0 / 16, 2.0 / 16,
+		1.0 / 16, 2.0 / 16, 1.0 / 16,
+	}
+
+	EdgeDetectKernel = [9]float32{
+		1, 1, 1,
+		1, -8, 1,
+		1, 1, 1,
+	}
+
+	EmbossKernel = [9]float32{
+		-2, -1, 0,
+		-1, 1, 1,
+		0, 1, 2,
+	}
+)
+
+func postShader(file string) string {
+	return filepath.Join(PostShaderDir, file)
+}
+
+// NewGrayscalePass makes a pass that keeps only the brightness of each pixel.
+func NewGrayscalePass() (*Pass, error) {
+	return NewPass("grayscale", postShader("grayscale.frag"), nil)
+}
+
+// NewInvertPass makes a pass that turns every color into its opposite.
+func NewInvertPass() (*Pass, error) {
+	return NewPass("invert", postShader("invert.frag"), nil)
+}
+
+// NewKernelPass makes a pass that replaces each pixel with the sum of it and its 8 neighbours
+// weighted by the kernel.
+func NewKernelPass(name string, kernel [9]float32) (*Pass, error) {
+	return NewPass(name, postShader("kernel.frag"), func(prog *Program) {
+		gl.Uniform1fv(prog.GetUniformLocation("kernel"), 9, &kernel[0])
+	})
+}
+
+// NewSharpenPass makes a kernel pass that exaggerates the difference between neighbouring
+// pixels.
+func NewSharpenPass() (*Pass, error) {
+	return NewKernelPass("sharpen", SharpenKernel)
+}
+
+// NewVignettePass makes a pass that darkens the corners of the screen. radius is the distance
+// from the center, where the screen's half height is 0.5, at which darkening starts and it
+// reaches black softness further out.
+func NewVignettePass(radius, softness float32) (*Pass, error) {
+	return NewPass("vignette", postShader("vignette.frag"), func(prog *Program) {
+		gl.Uniform1f(prog.GetUniformLocation("radius"), radius)
+		gl.Uniform1f(prog.GetUniformLocation("softness"), softness)
+	})
+}
@@ -0,0 +1,303 @@
+package gfx
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// ExposureMetering is how AutoExposure turns the brightness of every pixel into the one
+// brightness the exposure is set for.
+type ExposureMetering int
+
+const (
+	// the geometric mean of every pixel, a few very bright or dark pixels move it a lot
+	MeterAverage ExposureMetering = iota
+
+	// the mean of a histogram with the darkest and brightest pixels left out
+	// (see AutoExposure.LowPercent and HighPercent) so small lights or shadows are ignored
+	MeterHistogram
+
+	NumExposureMeterings
+)
+
+var meteringNames = [NumExposureMeterings]string{"average", "histogram"}
+
+func (m ExposureMetering) String() string {
+	if m < 0 || m >= NumExposureMeterings {
+		return "unknown"
+	}
+	return meteringNames[m]
+}
+
+const (
+	// the scene is first drawn as log luminance at this size, mipmapping it averages blocks of
+	// pixels and the level of size luminanceReadSize is read back for metering
+	luminanceSize     = 256
+	luminanceReadLvl  = 2
+	luminanceReadSize = luminanceSize >> luminanceReadLvl
+
+	// the range of log2 luminance the histogram covers, anything outside goes in the end bins
+	HistogramBins    = 64
+	HistogramMinLog2 = -12.0
+	HistogramMaxLog2 = 6.0
+
+	// the scene brightness a camera exposes for, 18% gray
+	middleGray = 0.18
+)
+
+var errLuminanceReadFailed = errors.New("failed waiting for the luminance read")
+
+// AutoExposure adapts the exposure to the brightness of the scene like the eye does, ex: a
+// ToneMapping's Exposure can be set from Exposure every frame. The brightness is measured on
+// the GPU and read back without stalling so the result is a frame or two behind, which the
+// gradual adaptation hides.
+//
+//	post.End()
+//	autoExposure.Measure(post.Scene().Color(0))
+//	autoExposure.Update(dt)
+//	toneMapping.Exposure = autoExposure.Exposure
+type AutoExposure struct {
+	Metering ExposureMetering
+
+	// added to the exposure the metering asks for, in stops, to make the scene brighter or darker
+	Compensation float32
+
+	// limits of the exposure in stops so very dark or bright scenes still look dark or bright
+	MinExposure float32
+	MaxExposure float32
+
+	// how quickly the exposure moves towards the target in stops per second for every stop
+	// it is away, going into the dark (exposure going up) is usually slower like the eye
+	SpeedUp   float32
+	SpeedDown float32
+
+	// for MeterHistogram, the percentage of the darkest pixels and the percentage up to which
+	// the brightest pixels are used, ex: 50 and 95 meter the brighter half of the scene
+	// without its brightest 5%
+	LowPercent  float32
+	HighPercent float32
+
+	// the current exposure in stops and the one it is adapting to
+	Exposure float32
+	Target   float32
+
+	// the results of the last measurement, for debugging
+	Luminance float32 // what the metering decided the scene's brightness is
+	Histogram [HistogramBins]int
+
+	measured bool // false until the first measurement, which is jumped to without adapting
+
+	target *Framebuffer
+	pass   *Pass
+	vao    uint32
+
+	pbo   *Buffer
+	fence uintptr // the read in flight, 0 when there is none
+}
+
+// NewAutoExposure makes an AutoExposure with settings that work for most scenes.
+func NewAutoExposure() (*AutoExposure, error) {
+	ae := AutoExposure{
+		Metering:    MeterHistogram,
+		MinExposure: -8,
+		MaxExposure: 8,
+		SpeedUp:     1.5,
+		SpeedDown:   3,
+		LowPercent:  50,
+		HighPercent: 95,
+	}
+
+	var err error
+	ae.target, err = NewFramebuffer(luminanceSize, luminanceSize, FramebufferSpec{
+		Color: []Attachment{{Format: gl.R16F}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ae.pass, err = NewPass("luminance", postShader("luminance.frag"), nil)
+	if err != nil {
+		ae.Delete()
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &ae.vao)
+
+	ae.pbo = NewBuffer(gl.PIXEL_PACK_BUFFER, gl.STREAM_READ)
+	ae.pbo.Data(luminanceReadSize*luminanceReadSize*4, nil)
+	ae.pbo.UnBind()
+
+	return &ae, nil
+}
+
+// Measure starts measuring the brightness of scene, a texture of linear HDR colors. It does
+// nothing while the previous measurement has not been collected by Update yet.
+func (ae *AutoExposure) Measure(scene *Texture) {
+	if ae.fence != 0 {
+		return
+	}
+
+	ae.target.Bind()
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	ae.pass.draw(scene, ae.vao)
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+	ae.target.UnBind()
+
+	// each mip level averages 2x2 pixels of the one above, since the values are logarithms
+	// that makes a geometric mean
+	lum := ae.target.Color(0)
+	gl.BindTexture(lum.target, lum.handle)
+	gl.GenerateMipmap(lum.target)
+
+	// with a PIXEL_PACK_BUFFER bound GetTexImage writes to it instead of client memory so it
+	// returns right away
+	ae.pbo.Bind()
+	gl.GetTexImage(lum.target, luminanceReadLvl, gl.RED, gl.FLOAT, gl.PtrOffset(0))
+	ae.pbo.UnBind()
+	gl.BindTexture(lum.target, 0)
+
+	ae.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+}
+
+// Update collects the last measurement if it is ready, picks the target exposure from it and
+// moves Exposure towards it for dt seconds.
+func (ae *AutoExposure) Update(dt float32) error {
+	if err := ae.collect(); err != nil {
+		return err
+	}
+	if !ae.measured {
+		return nil
+	}
+
+	speed := ae.SpeedDown
+	if ae.Target > ae.Exposure {
+		speed = ae.SpeedUp
+	}
+
+	// exponential approach so it is frame rate independent and slows down close to the target
+	ae.Exposure += (ae.Target - ae.Exposure) * (1 - float32(math.Exp(float64(-dt*speed))))
+	return nil
+}
+
+// collect reads back the measurement in flight, if the GPU is done with it
+func (ae *AutoExposure) collect() error {
+	if ae.fence == 0 {
+		return nil
+	}
+
+	// a timeout of 0 only checks, flushing makes sure the fence is going to be reached
+	status := gl.ClientWaitSync(ae.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 0)
+	if status == gl.TIMEOUT_EXPIRED {
+		return nil
+	}
+	gl.DeleteSync(ae.fence)
+	ae.fence = 0
+	if status == gl.WAIT_FAILED {
+		return errLuminanceReadFailed
+	}
+
+	numPixels := luminanceReadSize * luminanceReadSize
+	data, err := ae.pbo.MapRange(0, numPixels*4, gl.MAP_READ_BIT)
+	if err != nil {
+		return err
+	}
+	logLums := make([]float32, numPixels)
+	copy(logLums, unsafe.Slice((*float32)(unsafe.Pointer(&data[0])), numPixels))
+	if err := ae.pbo.Unmap(); err != nil {
+		return err
+	}
+	ae.pbo.UnBind()
+
+	ae.meter(logLums)
+
+	target := float32(math.Log2(middleGray/float64(ae.Luminance))) + ae.Compensation
+	ae.Target = float32(math.Max(float64(ae.MinExposure), math.Min(float64(ae.MaxExposure), float64(target))))
+
+	if !ae.measured {
+		ae.Exposure = ae.Target
+		ae.measured = true
+	}
+	return nil
+}
+
+// meter sets Luminance and Histogram from the log2 luminance of every measured pixel
+func (ae *AutoExposure) meter(logLums []float32) {
+	ae.Histogram = [HistogramBins]int{}
+	for _, l := range logLums {
+		ae.Histogram[histogramBin(l)]++
+	}
+
+	if ae.Metering == MeterAverage {
+		var sum float64
+		for _, l := range logLums {
+			sum += float64(l)
+		}
+		ae.Luminance = float32(math.Exp2(sum / float64(len(logLums))))
+		return
+	}
+
+	// the average of the pixels between the low and high percentiles, sorting is simpler
+	// than walking the histogram and exact for the few thousand pixels measured
+	sort.Slice(logLums, func(i, j int) bool { return logLums[i] < logLums[j] })
+	low := int(float32(len(logLums)) * ae.LowPercent / 100)
+	high := int(float32(len(logLums)) * ae.HighPercent / 100)
+	if high > len(logLums) {
+		high = len(logLums)
+	}
+	if low >= high {
+		low = high - 1
+	}
+	if low < 0 {
+		low = 0
+	}
+
+	var sum float64
+	for _, l := range logLums[low:high] {
+		sum += float64(l)
+	}
+	ae.Luminance = float32(math.Exp2(sum / float64(high-low)))
+}
+
+// HistogramBinLog2 returns the log2 luminance at the start of bin i of Histogram.
+func HistogramBinLog2(i int) float32 {
+	return HistogramMinLog2 + float32(i)*(HistogramMaxLog2-HistogramMinLog2)/HistogramBins
+}
+
+func histogramBin(logLum float32) int {
+	bin := int((logLum - HistogramMinLog2) / (HistogramMaxLog2 - HistogramMinLog2) * HistogramBins)
+	return clampInt(bin, 0, HistogramBins-1)
+}
+
+func (ae *AutoExposure) Delete() {
+	if ae.fence != 0 {
+		gl.DeleteSync(ae.fence)
+		ae.fence = 0
+	}
+	if ae.pbo != nil {
+		ae.pbo.Delete()
+	}
+	if ae.pass != nil {
+		ae.pass.Delete()
+	}
+	if ae.target != nil {
+		ae.target.Delete()
+	}
+	gl.DeleteVertexArrays(1, &ae.vao)
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
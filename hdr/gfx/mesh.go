@@ -0,0 +1,260 @@
+package gfx
+
+import (
+	"errors"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/hdr/geom"
+)
+
+// Mesh is triangle vertex data kept on the CPU so that it can be processed
+// (ex: generating tangents) before it is uploaded with Upload.
+// Every attribute slice that is not empty has one entry per vertex.
+type Mesh struct {
+	Positions []mgl32.Vec3
+	Normals   []mgl32.Vec3
+	UVs       []mgl32.Vec2
+	Tangents  []mgl32.Vec4 // xyz is the tangent, w is the handedness (+1 or -1) of the bitangent
+
+	// Indices of the triangle corners. When empty every 3 consecutive vertices are a triangle.
+	Indices []uint32
+}
+
+// attribute locations used by Mesh.Upload, these match the layout in the vertex shaders
+const (
+	PositionAttrib uint32 = 0
+	NormalAttrib   uint32 = 1
+	UVAttrib       uint32 = 2
+	TangentAttrib  uint32 = 3
+)
+
+var errMeshNoPositions = errors.New("mesh has no positions")
+
+var errMeshAttribCount = errors.New("mesh attributes do not all have one entry per vertex")
+
+// NewMeshFromInterleaved splits interleaved float vertices like the cubeVertices arrays in the
+// samples into a Mesh. normals and uvs say whether each vertex has those after its position.
+func NewMeshFromInterleaved(vertices []float32, normals, uvs bool) *Mesh {
+	stride := 3
+	if normals {
+		stride += 3
+	}
+	if uvs {
+		stride += 2
+	}
+
+	mesh := Mesh{}
+	for i := 0; i+stride <= len(vertices); i += stride {
+		v := vertices[i : i+stride]
+		mesh.Positions = append(mesh.Positions, mgl32.Vec3{v[0], v[1], v[2]})
+		v = v[3:]
+		if normals {
+			mesh.Normals = append(mesh.Normals, mgl32.Vec3{v[0], v[1], v[2]})
+			v = v[3:]
+		}
+		if uvs {
+			mesh.UVs = append(mesh.UVs, mgl32.Vec2{v[0], v[1]})
+		}
+	}
+
+	return &mesh
+}
+
+func (m *Mesh) NumVertices() int {
+	return len(m.Positions)
+}
+
+func (m *Mesh) NumTriangles() int {
+	if len(m.Indices) > 0 {
+		return len(m.Indices) / 3
+	}
+	return len(m.Positions) / 3
+}
+
+// Triangle returns the vertex indices of the i-th triangle.
+func (m *Mesh) Triangle(i int) (uint32, uint32, uint32) {
+	if len(m.Indices) > 0 {
+		return m.Indices[3*i], m.Indices[3*i+1], m.Indices[3*i+2]
+	}
+	return uint32(3 * i), uint32(3*i + 1), uint32(3*i + 2)
+}
+
+// Bounds is the box around the mesh's positions.
+func (m *Mesh) Bounds() geom.AABB {
+	return geom.NewAABBFromPoints(m.Positions)
+}
+
+// BoundingSphere is a sphere around the mesh's positions.
+func (m *Mesh) BoundingSphere() geom.Sphere {
+	return geom.NewSphereFromPoints(m.Positions)
+}
+
+// TriangleNormal is the normal of the i-th triangle's face, following the winding order.
+func (m *Mesh) TriangleNormal(i int) mgl32.Vec3 {
+	a, b, c := m.Triangle(i)
+	pa, pb, pc := m.Positions[a], m.Positions[b], m.Positions[c]
+	return pb.Sub(pa).Cross(pc.Sub(pa)).Normalize()
+}
+
+// Bitangent reconstructs the bitangent of vertex i the same way the shaders do.
+func (m *Mesh) Bitangent(i int) mgl32.Vec3 {
+	t := m.Tangents[i]
+	return m.Normals[i].Cross(t.Vec3()).Mul(t.W())
+}
+
+func (m *Mesh) validate() error {
+	n := len(m.Positions)
+	if n == 0 {
+		return errMeshNoPositions
+	}
+	for _, count := range []int{len(m.Normals), len(m.UVs), len(m.Tangents)} {
+		if count != 0 && count != n {
+			return errMeshAttribCount
+		}
+	}
+	return nil
+}
+
+// VertexLayout is the interleaved layout Upload uses for this mesh:
+// position, then normal, uv and tangent if the mesh has them.
+func (m *Mesh) VertexLayout() *VertexLayout {
+	attribs := []VertexAttrib{FloatAttrib(PositionAttrib, 3)}
+	if len(m.Normals) > 0 {
+		attribs = append(attribs, FloatAttrib(NormalAttrib, 3))
+	}
+	if len(m.UVs) > 0 {
+		attribs = append(attribs, FloatAttrib(UVAttrib, 2))
+	}
+	if len(m.Tangents) > 0 {
+		attribs = append(attribs, FloatAttrib(TangentAttrib, 4))
+	}
+	return NewVertexLayout(attribs...)
+}
+
+// Interleave packs the mesh vertices using the given layout.
+// The layout must have the same attributes in the same order as VertexLayout
+// but can use more compact types for them.
+func (m *Mesh) Interleave(layout *VertexLayout) ([]byte, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	streams := [][]float32{flatten3(m.Positions)}
+	if len(m.Normals) > 0 {
+		streams = append(streams, flatten3(m.Normals))
+	}
+	if len(m.UVs) > 0 {
+		uvs := make([]float32, 0, 2*len(m.UVs))
+		for _, uv := range m.UVs {
+			uvs = append(uvs, uv[0], uv[1])
+		}
+		streams = append(streams, uvs)
+	}
+	if len(m.Tangents) > 0 {
+		tangents := make([]float32, 0, 4*len(m.Tangents))
+		for _, t := range m.Tangents {
+			tangents = append(tangents, t[0], t[1], t[2], t[3])
+		}
+		streams = append(streams, tangents)
+	}
+
+	return layout.Pack(streams...)
+}
+
+func flatten3(vecs []mgl32.Vec3) []float32 {
+	data := make([]float32, 0, 3*len(vecs))
+	for _, v := range vecs {
+		data = append(data, v[0], v[1], v[2])
+	}
+	return data
+}
+
+// Upload copies the mesh to the GPU using its default VertexLayout.
+func (m *Mesh) Upload() (*VertexArray, error) {
+	return m.UploadWithLayout(m.VertexLayout())
+}
+
+// UploadWithLayout copies the mesh to the GPU, converting attributes to the types in layout.
+func (m *Mesh) UploadWithLayout(layout *VertexLayout) (*VertexArray, error) {
+	data, err := m.Interleave(layout)
+	if err != nil {
+		return nil, err
+	}
+
+	va := VertexArray{
+		mesh:   m,
+		count:  int32(m.NumVertices()),
+		bounds: m.Bounds(),
+		sphere: m.BoundingSphere(),
+	}
+	gl.GenVertexArrays(1, &va.handle)
+	gl.BindVertexArray(va.handle)
+
+	va.vbo = NewBufferWithData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, len(data), gl.Ptr(data))
+	layout.Enable()
+
+	if len(m.Indices) > 0 {
+		// the element buffer binding is part of the VAO state
+		va.ebo = NewBufferWithData(gl.ELEMENT_ARRAY_BUFFER, gl.STATIC_DRAW, len(m.Indices)*4,
+			gl.Ptr(m.Indices))
+		va.count = int32(len(m.Indices))
+	}
+
+	gl.BindVertexArray(0)
+	return &va, nil
+}
+
+// VertexArray is a mesh that lives on the GPU, ready to be drawn.
+type VertexArray struct {
+	handle uint32
+	vbo    *Buffer
+	ebo    *Buffer // nil when the mesh is not indexed
+	count  int32   // number of vertices or indices to draw
+
+	// the mesh this was uploaded from, kept for picking
+	mesh *Mesh
+
+	// bounding volumes of the mesh in model space, kept for culling
+	bounds geom.AABB
+	sphere geom.Sphere
+}
+
+// Mesh is the CPU side copy of the vertex data, it must not be modified.
+func (va *VertexArray) Mesh() *Mesh {
+	return va.mesh
+}
+
+func (va *VertexArray) Bounds() geom.AABB {
+	return va.bounds
+}
+
+func (va *VertexArray) BoundingSphere() geom.Sphere {
+	return va.sphere
+}
+
+func (va *VertexArray) Bind() {
+	gl.BindVertexArray(va.handle)
+}
+
+func (va *VertexArray) UnBind() {
+	gl.BindVertexArray(0)
+}
+
+// Draw draws all triangles. The VertexArray must be bound.
+func (va *VertexArray) Draw() {
+	if va.ebo != nil {
+		gl.DrawElements(gl.TRIANGLES, va.count, gl.UNSIGNED_INT, gl.PtrOffset(0))
+	} else {
+		gl.DrawArrays(gl.TRIANGLES, 0, va.count)
+	}
+}
+
+func (va *VertexArray) Delete() {
+	gl.DeleteVertexArrays(1, &va.handle)
+	va.vbo.Delete()
+	if va.ebo != nil {
+		va.ebo.Delete()
+	}
+}
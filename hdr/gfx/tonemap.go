@@ -0,0 +1,79 @@
+package gfx
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// ToneMapOperator is a curve that squeezes the unlimited range of HDR colors into the [0, 1]
+// a monitor can show. Must match the TONE_MAP_* values in shaders/post/tonemap.frag.
+type ToneMapOperator int32
+
+const (
+	// colors above 1 are cut off like when rendering straight to an 8-bit framebuffer
+	ToneMapClamp ToneMapOperator = iota
+
+	// x / (1 + x), never reaches white so bright areas look washed out
+	ToneMapReinhard
+
+	// Krzysztof Narkowicz's fit of the ACES filmic curve, punchy with saturated highlights
+	ToneMapACES
+
+	// John Hable's filmic curve from Uncharted 2, with a toe that deepens the shadows
+	ToneMapUncharted2
+
+	NumToneMapOperators
+)
+
+var toneMapNames = [NumToneMapOperators]string{"clamp", "Reinhard", "ACES", "Uncharted 2"}
+
+func (op ToneMapOperator) String() string {
+	if op < 0 || op >= NumToneMapOperators {
+		return "unknown"
+	}
+	return toneMapNames[op]
+}
+
+// ToneMapping is how a tone map pass turns HDR colors into the final image. It is read every
+// time the pass is drawn so it can be changed at any time.
+type ToneMapping struct {
+	Operator ToneMapOperator
+
+	// in stops, each one doubles the brightness of the scene before the curve is applied
+	Exposure float32
+
+	// the scene brightness that becomes pure white for the Reinhard and Uncharted 2 curves,
+	// anything brighter is clipped
+	WhitePoint float32
+
+	// makes the shader convert the linear colors lighting produces to sRGB itself, only for
+	// drawing into a framebuffer that does not do it (see win.Window.SetGammaCorrection),
+	// otherwise the colors are converted twice and look washed out
+	EncodeSRGB bool
+}
+
+// DefaultToneMapping is a good starting point for scenes lit by lights of intensity around 1.
+var DefaultToneMapping = ToneMapping{
+	Operator:   ToneMapACES,
+	Exposure:   0,
+	WhitePoint: 4,
+	EncodeSRGB: false,
+}
+
+// NewToneMapPass makes a pass that applies settings. It should be the last pass of a chain
+// that runs on floating point framebuffers since the colors it outputs are only meant for
+// the window.
+func NewToneMapPass(settings *ToneMapping) (*Pass, error) {
+	return NewPass("tone map", postShader("tonemap.frag"), func(prog *Program) {
+		gl.Uniform1i(prog.GetUniformLocation("operator"), int32(settings.Operator))
+		gl.Uniform1f(prog.GetUniformLocation("exposure"), settings.Exposure)
+		gl.Uniform1f(prog.GetUniformLocation("whitePoint"), settings.WhitePoint)
+		gl.Uniform1i(prog.GetUniformLocation("encodeSRGB"), boolToInt(settings.EncodeSRGB))
+	})
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
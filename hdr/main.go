@@ -0,0 +1,488 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-Lighting/HDR
+
+Lights can be much brighter than 1 but an 8-bit framebuffer cuts every color off at 1 so
+bright areas lose all detail. Here the scene is drawn into a floating point (RGBA16F)
+framebuffer through gfx.PostProcessor and a tone map pass at the end of its chain squeezes the
+colors into what the window can show. The window's sRGB framebuffer encodes them as sRGB.
+
+The bright light at the back pulses from almost nothing to several times its brightness.
+With automatic exposure (gfx.AutoExposure) the brightness of every frame is measured and the
+exposure slowly adapts to it like the eye does, so the scene stays visible without blowing
+out. The title shows what was measured.
+
+Press T to cycle the tone mapping operator (clamp shows what 8-bit rendering looks like),
+hold = and - to change the exposure (or the exposure compensation when it is automatic) and
+press G to toggle gamma correction. E toggles automatic exposure, M switches between
+average and histogram metering and H prints the last luminance histogram.
+
+Anything brighter than white, like the light gizmos, glows with bloom (gfx.NewBloomPass).
+Press B to toggle it, hold up and down to change its intensity and left and right to change
+how far it spreads.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/hdr/cam"
+	"github.com/cstegel/opengl-samples-golang/hdr/gfx"
+	"github.com/cstegel/opengl-samples-golang/hdr/scene"
+	"github.com/cstegel/opengl-samples-golang/hdr/win"
+)
+
+var cubePositions = [][]float32{
+	{0.0, 0.0, -3.0},
+	{2.0, 5.0, -15.0},
+	{-1.5, -2.2, -2.5},
+	{-3.8, -2.0, -12.3},
+	{2.4, -0.4, -3.5},
+	{-1.7, 3.0, -7.5},
+	{1.3, -2.0, -2.5},
+	{1.5, 2.0, -2.5},
+	{1.5, 0.2, -1.5},
+	{-1.3, 1.0, -1.5},
+}
+
+// one very bright light at the back and a few dimmer colored ones near the camera,
+// far outside the [0, 1] an 8-bit framebuffer can hold
+var pointLightPositions = []mgl32.Vec3{
+	{0.0, 1.0, -16.0},
+	{-3.0, -1.0, -4.0},
+	{3.0, 1.5, -6.0},
+	{0.5, -2.0, 0.0},
+}
+
+var pointLightColors = []mgl32.Vec3{
+	{12.0, 12.0, 12.0},
+	{3.0, 0.3, 0.2},
+	{0.3, 0.5, 3.0},
+	{0.3, 2.0, 0.3},
+}
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 8
+
+// how fast holding the exposure and bloom keys changes them
+const (
+	exposureStopsPerSecond  = 1.5
+	bloomIntensityPerSecond = 0.5
+	bloomRadiusPerSecond    = 1.0
+)
+
+// the brightest the pulsing light gets as a multiple of its color, and the dimmest
+const (
+	pulseMax = 4.0
+	pulseMin = 0.01
+)
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes      []*scene.Node
+	flashlight *scene.Node
+	pulsing    *scene.Node
+}
+
+func buildScene(cube, floor *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.02, 0.02, 0.02},
+		Diffuse:   mgl32.Vec3{0.1, 0.1, 0.1},
+		Specular:  mgl32.Vec3{0.1, 0.1, 0.1},
+	}
+
+	ground := s.Add(scene.NewNode("ground"))
+	ground.SetPosition(mgl32.Vec3{0, -3.5, -8})
+	ground.SetScale(mgl32.Vec3{30, 1, 30})
+	ground.Mesh = floor
+
+	// the gizmo cubes are drawn in the light's color so they are as bright as the light
+	for i, pos := range pointLightPositions {
+		light := s.Add(scene.NewNode(fmt.Sprintf("point light %d", i)))
+		light.SetPosition(pos)
+		light.SetScale(mgl32.Vec3{0.2, 0.2, 0.2})
+		light.Mesh = cube
+		light.PointLight = &gfx.PointLight{
+			Ambient:     pointLightColors[i].Mul(0.01),
+			Diffuse:     pointLightColors[i],
+			Specular:    pointLightColors[i],
+			Attenuation: gfx.AttenuationForDistance(20),
+		}
+		if i == 0 {
+			nodes.pulsing = light
+		}
+	}
+
+	// the spot light points down the node's -z like a camera
+	nodes.flashlight = s.Add(scene.NewNode("flashlight"))
+	nodes.flashlight.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.5, 1.5, 1.5},
+		Specular:    mgl32.Vec3{1.5, 1.5, 1.5},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	for i, pos := range cubePositions {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{pos[0], pos[1], pos[2]})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "HDR")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+	defer lightProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	floor, err := gfx.NewPlaneMesh(15).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// half floats have plenty of range for lighting and take half the memory of full floats
+	post, err := gfx.NewPostProcessor(window.FramebufferWidth(), window.FramebufferHeight(), gl.RGBA16F)
+	if err != nil {
+		return err
+	}
+	defer post.Delete()
+
+	// bloom works on the HDR colors so it goes before tone mapping
+	bloom := gfx.DefaultBloom
+	bloomPass, err := gfx.NewBloomPass(&bloom, window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+
+	toneMapping := gfx.DefaultToneMapping
+	toneMap, err := gfx.NewToneMapPass(&toneMapping)
+	if err != nil {
+		bloomPass.Delete()
+		return err
+	}
+	post.Add(bloomPass, toneMap)
+
+	autoExposure, err := gfx.NewAutoExposure()
+	if err != nil {
+		return err
+	}
+	defer autoExposure.Delete()
+	useAutoExposure := true
+
+	world, nodes := buildScene(cube, floor)
+
+	// lens for the camera
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 100}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		if window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0 {
+			if err := post.Resize(window.FramebufferWidth(), window.FramebufferHeight()); err != nil {
+				return err
+			}
+		}
+
+		dt := float32(window.SinceLastFrame())
+
+		if window.InputManager().IsTriggered(win.NEXT_TONE_MAP) {
+			toneMapping.Operator = (toneMapping.Operator + 1) % gfx.NumToneMapOperators
+		}
+		if window.InputManager().IsTriggered(win.TOGGLE_AUTO_EXPOSURE) {
+			useAutoExposure = !useAutoExposure
+
+			// adapt from the manual exposure instead of jumping back to the old automatic one
+			autoExposure.Exposure = toneMapping.Exposure
+		}
+		if window.InputManager().IsTriggered(win.NEXT_METERING) {
+			autoExposure.Metering = (autoExposure.Metering + 1) % gfx.NumExposureMeterings
+		}
+		if window.InputManager().IsTriggered(win.PRINT_HISTOGRAM) {
+			printHistogram(autoExposure)
+		}
+
+		// the exposure keys change whichever exposure is in control
+		exposureChange := float32(0)
+		if window.InputManager().IsActive(win.EXPOSURE_UP) {
+			exposureChange += exposureStopsPerSecond * dt
+		}
+		if window.InputManager().IsActive(win.EXPOSURE_DOWN) {
+			exposureChange -= exposureStopsPerSecond * dt
+		}
+
+		if useAutoExposure {
+			autoExposure.Compensation += exposureChange
+			if err := autoExposure.Update(dt); err != nil {
+				return err
+			}
+			toneMapping.Exposure = autoExposure.Exposure
+		} else {
+			toneMapping.Exposure += exposureChange
+		}
+
+		if window.InputManager().IsTriggered(win.TOGGLE_BLOOM) {
+			bloomPass.Enabled = !bloomPass.Enabled
+		}
+		if window.InputManager().IsActive(win.BLOOM_STRONGER) {
+			bloom.Intensity += bloomIntensityPerSecond * dt
+		}
+		if window.InputManager().IsActive(win.BLOOM_WEAKER) {
+			bloom.Intensity = float32(math.Max(0, float64(bloom.Intensity-bloomIntensityPerSecond*dt)))
+		}
+		if window.InputManager().IsActive(win.BLOOM_WIDER) {
+			bloom.Radius += bloomRadiusPerSecond * dt
+		}
+		if window.InputManager().IsActive(win.BLOOM_NARROWER) {
+			bloom.Radius = float32(math.Max(0, float64(bloom.Radius-bloomRadiusPerSecond*dt)))
+		}
+
+		exposureInfo := fmt.Sprintf("exposure %+.2f stops", toneMapping.Exposure)
+		if useAutoExposure {
+			exposureInfo = fmt.Sprintf("auto exposure %+.2f stops (target %+.2f, compensation %+.2f, "+
+				"%v metering luminance %.3f)", autoExposure.Exposure, autoExposure.Target,
+				autoExposure.Compensation, autoExposure.Metering, autoExposure.Luminance)
+		}
+		bloomInfo := "off"
+		if bloomPass.Enabled {
+			bloomInfo = fmt.Sprintf("intensity %.2f radius %.2f", bloom.Intensity, bloom.Radius)
+		}
+		window.SetTitle(fmt.Sprintf("HDR - %v, %s, bloom %s, gamma correction %s", toneMapping.Operator,
+			exposureInfo, bloomInfo, onOff(window.GammaCorrection())))
+
+		// animate the nodes
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+
+		// the pulse is eased so the light spends a while at both extremes
+		pulse := 0.5 - 0.5*float32(math.Cos(float64(time)*0.5))
+		pulsingLight := nodes.pulsing.PointLight
+		pulsingLight.Diffuse = pointLightColors[0].Mul(pulseMin + (pulseMax-pulseMin)*pulse*pulse)
+		pulsingLight.Specular = pulsingLight.Diffuse
+
+		// the flashlight is held by the camera
+		nodes.flashlight.SetPosition(camera.Position())
+		nodes.flashlight.LookAt(camera.Position().Add(camera.Front()), mgl32.Vec3{0, 1, 0})
+
+		// everything until End is drawn to the floating point framebuffer
+		post.Begin()
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		program.Use()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(float32(window.FramebufferWidth()) / float32(window.FramebufferHeight()))
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		setLightUniforms(program, world.Lights(), camTransform)
+
+		// everything except the light gizmos is lit
+		world.Draw(program, func(n *scene.Node) bool {
+			return n.PointLight == nil
+		})
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(lightProgram, func(n *scene.Node) bool {
+			if n.PointLight == nil {
+				return false
+			}
+			light := n.PointLight
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"),
+				light.Diffuse.X(), light.Diffuse.Y(), light.Diffuse.Z())
+			return true
+		})
+
+		// tone mapped to the window
+		post.End()
+
+		// measured for the exposure of a later frame
+		if useAutoExposure {
+			autoExposure.Measure(post.Scene().Color(0))
+		}
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+// sets the lights in view space, a scene may have no sun or flashlight
+func setLightUniforms(program *gfx.Program, lights scene.Lights, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	numPointLights := len(lights.Point)
+	if numPointLights > maxPointLights {
+		numPointLights = maxPointLights
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+	for i := 0; i < numPointLights; i++ {
+		lights.Point[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+	}
+
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+}
+
+// prints a bar for every histogram bin between the darkest and brightest ones used
+func printHistogram(ae *gfx.AutoExposure) {
+	first, last, most := -1, 0, 0
+	for i, count := range ae.Histogram {
+		if count == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+		if count > most {
+			most = count
+		}
+	}
+	if first < 0 {
+		fmt.Println("no luminance measured yet")
+		return
+	}
+
+	const barWidth = 60
+	fmt.Printf("luminance histogram (%v metering, luminance %.4f)\n", ae.Metering, ae.Luminance)
+	for i := first; i <= last; i++ {
+		bar := strings.Repeat("#", (ae.Histogram[i]*barWidth+most-1)/most)
+		fmt.Printf("%10.4f %5d %s\n", math.Exp2(float64(gfx.HistogramBinLog2(i))), ae.Histogram[i], bar)
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
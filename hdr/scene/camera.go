@@ -0,0 +1,18 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Camera is the lens part of a camera, where it is and where it looks come from the node it
+// is attached to. Like OpenGL cameras it looks down the node's -z axis with +y up.
+type Camera struct {
+	Fov  float32 // vertical field of view in degrees
+	Near float32
+	Far  float32
+}
+
+// Projection is the perspective transform for a viewport with the given width / height.
+func (c *Camera) Projection(aspect float32) mgl32.Mat4 {
+	return mgl32.Perspective(mgl32.DegToRad(c.Fov), aspect, c.Near, c.Far)
+}
@@ -0,0 +1,145 @@
+package scene
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/hdr/geom"
+	"github.com/cstegel/opengl-samples-golang/hdr/gfx"
+)
+
+// Scene is a tree of nodes under a single root.
+type Scene struct {
+	Root *Node
+}
+
+// Lights are the lights attached to a scene's nodes, converted to world space
+// so they can be uploaded with their SetUniforms methods.
+type Lights struct {
+	Directional []gfx.DirectionalLight
+	Point       []gfx.PointLight
+	Spot        []gfx.SpotLight
+}
+
+func New() *Scene {
+	return &Scene{Root: NewNode("root")}
+}
+
+// Add attaches n to the root of the scene and returns it.
+func (s *Scene) Add(n *Node) *Node {
+	return s.Root.AddChild(n)
+}
+
+// Find returns the first node with the given name or nil.
+func (s *Scene) Find(name string) *Node {
+	return s.Root.Find(name)
+}
+
+// Walk visits every node that is not hidden (or below a hidden node), parents first.
+func (s *Scene) Walk(visit func(*Node)) {
+	s.Root.Walk(func(n *Node) bool {
+		if n.Hidden {
+			return false
+		}
+		visit(n)
+		return true
+	})
+}
+
+// DrawStats counts what happened to the nodes with meshes in a draw.
+type DrawStats struct {
+	Drawn  int
+	Culled int // outside the view frustum
+}
+
+// Draw draws every visible node with a mesh using prog, which must be in use.
+// The node's world transform is set as the "model" uniform. before is called ahead of each
+// node so it can set per node uniforms; returning false skips the node. before may be nil.
+// Draw returns how many nodes were drawn.
+func (s *Scene) Draw(prog *gfx.Program, before func(*Node) bool) int {
+	return s.DrawCulled(prog, nil, before).Drawn
+}
+
+// DrawCulled is like Draw but skips nodes whose mesh is completely outside frustum.
+// The cheap bounding sphere test runs first and the tighter box test only for nodes that pass.
+// A nil frustum culls nothing.
+func (s *Scene) DrawCulled(prog *gfx.Program, frustum *geom.Frustum, before func(*Node) bool) DrawStats {
+	modelLoc := prog.GetUniformLocation("model")
+	stats := DrawStats{}
+
+	s.Walk(func(n *Node) {
+		if n.Mesh == nil {
+			return
+		}
+		if before != nil && !before(n) {
+			return
+		}
+		if frustum != nil {
+			if !frustum.IntersectsSphere(n.WorldBoundingSphere()) || !frustum.IntersectsAABB(n.WorldBounds()) {
+				stats.Culled++
+				return
+			}
+		}
+
+		model := n.WorldTransform()
+		gl.UniformMatrix4fv(modelLoc, 1, false, &model[0])
+
+		n.Mesh.Bind()
+		n.Mesh.Draw()
+		stats.Drawn++
+	})
+	gl.BindVertexArray(0)
+
+	return stats
+}
+
+// Lights collects the lights of every visible node in world space.
+func (s *Scene) Lights() Lights {
+	var lights Lights
+
+	s.Walk(func(n *Node) {
+		if n.DirectionalLight == nil && n.PointLight == nil && n.SpotLight == nil {
+			return
+		}
+		world := n.WorldTransform()
+
+		if n.DirectionalLight != nil {
+			light := *n.DirectionalLight
+			light.Direction = transformDirection(world, light.Direction)
+			lights.Directional = append(lights.Directional, light)
+		}
+		if n.PointLight != nil {
+			light := *n.PointLight
+			light.Position = transformPoint(world, light.Position)
+			lights.Point = append(lights.Point, light)
+		}
+		if n.SpotLight != nil {
+			light := *n.SpotLight
+			light.Position = transformPoint(world, light.Position)
+			light.Direction = transformDirection(world, light.Direction)
+			lights.Spot = append(lights.Spot, light)
+		}
+	})
+
+	return lights
+}
+
+// Cameras returns the visible nodes that have a camera attached.
+func (s *Scene) Cameras() []*Node {
+	var cameras []*Node
+	s.Walk(func(n *Node) {
+		if n.Camera != nil {
+			cameras = append(cameras, n)
+		}
+	})
+	return cameras
+}
+
+func transformPoint(m mgl32.Mat4, p mgl32.Vec3) mgl32.Vec3 {
+	return m.Mul4x1(p.Vec4(1)).Vec3()
+}
+
+// directions ignore translation, they are renormalized since the node may be scaled
+func transformDirection(m mgl32.Mat4, dir mgl32.Vec3) mgl32.Vec3 {
+	return m.Mat3().Mul3x1(dir).Normalize()
+}
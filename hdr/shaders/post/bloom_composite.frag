@@ -0,0 +1,18 @@
+#version 410 core
+
+// adds the blurred bright parts of the scene back on top of it, before tone mapping
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+uniform sampler2D bloomTexture;
+
+uniform float intensity;
+
+void main()
+{
+	vec3 hdr = texture(screenTexture, TexCoords).rgb;
+	vec3 bloom = texture(bloomTexture, TexCoords).rgb;
+	color = vec4(hdr + bloom * intensity, 1.0);
+}
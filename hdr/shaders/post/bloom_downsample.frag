@@ -0,0 +1,43 @@
+#version 410 core
+
+// halves the size of a bloom level with the 13 tap filter from Jimenez 2014, "Next Generation
+// Post Processing in Call of Duty: Advanced Warfare". It is a mix of overlapping 2x2 boxes
+// (each one a single bilinear sample) that does not shimmer as things move like a plain
+// 2x2 average does.
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+void main()
+{
+	vec2 texel = 1.0 / vec2(textureSize(screenTexture, 0));
+
+	// a - b - c
+	// - j - k -
+	// d - e - f
+	// - l - m -
+	// g - h - i
+	vec3 a = texture(screenTexture, TexCoords + texel * vec2(-2.0, 2.0)).rgb;
+	vec3 b = texture(screenTexture, TexCoords + texel * vec2(0.0, 2.0)).rgb;
+	vec3 c = texture(screenTexture, TexCoords + texel * vec2(2.0, 2.0)).rgb;
+	vec3 d = texture(screenTexture, TexCoords + texel * vec2(-2.0, 0.0)).rgb;
+	vec3 e = texture(screenTexture, TexCoords).rgb;
+	vec3 f = texture(screenTexture, TexCoords + texel * vec2(2.0, 0.0)).rgb;
+	vec3 g = texture(screenTexture, TexCoords + texel * vec2(-2.0, -2.0)).rgb;
+	vec3 h = texture(screenTexture, TexCoords + texel * vec2(0.0, -2.0)).rgb;
+	vec3 i = texture(screenTexture, TexCoords + texel * vec2(2.0, -2.0)).rgb;
+	vec3 j = texture(screenTexture, TexCoords + texel * vec2(-1.0, 1.0)).rgb;
+	vec3 k = texture(screenTexture, TexCoords + texel * vec2(1.0, 1.0)).rgb;
+	vec3 l = texture(screenTexture, TexCoords + texel * vec2(-1.0, -1.0)).rgb;
+	vec3 m = texture(screenTexture, TexCoords + texel * vec2(1.0, -1.0)).rgb;
+
+	// the center box gets half the weight and the 4 corner boxes share the rest
+	vec3 result = e * 0.125;
+	result += (a + c + g + i) * 0.03125;
+	result += (b + d + f + h) * 0.0625;
+	result += (j + k + l + m) * 0.125;
+
+	color = vec4(result, 1.0);
+}
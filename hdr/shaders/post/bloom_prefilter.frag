@@ -0,0 +1,27 @@
+#version 410 core
+
+// keeps only the parts of the scene bright enough to glow, see gfx/bloom.go.
+// The output is half the size of the input so the bilinear sample at each output pixel
+// averages the 2x2 input pixels under it.
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+uniform float threshold;
+uniform float knee;  // width of the fade in below threshold, in the same units
+
+void main()
+{
+	vec3 hdr = texture(screenTexture, TexCoords).rgb;
+	float brightness = max(hdr.r, max(hdr.g, hdr.b));
+
+	// a quadratic curve from threshold - knee to threshold + knee joins up smoothly with the
+	// linear part above it, a hard cut off would make glows pop in and flicker
+	float soft = clamp(brightness - threshold + knee, 0.0, 2.0 * knee);
+	soft = soft * soft / (4.0 * knee + 1.0e-5);
+
+	float contribution = max(soft, brightness - threshold) / max(brightness, 1.0e-5);
+	color = vec4(hdr * contribution, 1.0);
+}
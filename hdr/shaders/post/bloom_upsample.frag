@@ -0,0 +1,32 @@
+#version 410 core
+
+// doubles the size of a bloom level with a 3x3 tent filter, the result is added to the
+// level above with blending (see gfx/bloom.go)
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+// how far apart the taps are in texels of the smaller level, larger spreads the glow further
+uniform float radius;
+
+void main()
+{
+	vec2 offset = radius / vec2(textureSize(screenTexture, 0));
+
+	// 1 2 1
+	// 2 4 2  / 16
+	// 1 2 1
+	vec3 result = texture(screenTexture, TexCoords).rgb * 4.0;
+	result += texture(screenTexture, TexCoords + offset * vec2(0.0, 1.0)).rgb * 2.0;
+	result += texture(screenTexture, TexCoords + offset * vec2(-1.0, 0.0)).rgb * 2.0;
+	result += texture(screenTexture, TexCoords + offset * vec2(1.0, 0.0)).rgb * 2.0;
+	result += texture(screenTexture, TexCoords + offset * vec2(0.0, -1.0)).rgb * 2.0;
+	result += texture(screenTexture, TexCoords + offset * vec2(-1.0, 1.0)).rgb;
+	result += texture(screenTexture, TexCoords + offset * vec2(1.0, 1.0)).rgb;
+	result += texture(screenTexture, TexCoords + offset * vec2(-1.0, -1.0)).rgb;
+	result += texture(screenTexture, TexCoords + offset * vec2(1.0, -1.0)).rgb;
+
+	color = vec4(result / 16.0, 1.0);
+}
@@ -0,0 +1,13 @@
+#version 410 core
+
+// passes the scene through unchanged when no effect is enabled
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+void main()
+{
+	color = texture(screenTexture, TexCoords);
+}
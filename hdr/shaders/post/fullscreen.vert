@@ -0,0 +1,16 @@
+#version 410 core
+
+// draws a single triangle that covers the whole screen with no vertex data, see
+// gfx/postprocess.go. Vertices 0, 1 and 2 are placed at (-1, -1), (3, -1) and (-1, 3) so
+// the part inside the screen is exactly the [-1, 1] square with texture coordinates [0, 1].
+// Unlike a quad made of two triangles there is no diagonal seam to shade twice.
+
+out vec2 TexCoords;
+
+void main()
+{
+	vec2 corner = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+
+	TexCoords = corner;
+	gl_Position = vec4(corner * 2.0 - 1.0, 0.0, 1.0);
+}
@@ -0,0 +1,12 @@
+#version 410 core
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+void main()
+{
+	vec4 texel = texture(screenTexture, TexCoords);
+	color = vec4(1.0 - texel.rgb, texel.a);
+}
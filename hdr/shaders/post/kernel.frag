@@ -0,0 +1,27 @@
+#version 410 core
+
+// 3x3 convolution, see the kernels in gfx/effects.go
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+// row by row from the top left of the screen
+uniform float kernel[9];
+
+void main()
+{
+	vec2 texel = 1.0 / vec2(textureSize(screenTexture, 0));
+
+	vec3 sum = vec3(0.0);
+	for (int row = 0; row < 3; row++) {
+		for (int col = 0; col < 3; col++) {
+			// texture coordinates go up the screen while the kernel rows go down
+			vec2 offset = vec2(col - 1, 1 - row) * texel;
+			sum += kernel[row * 3 + col] * texture(screenTexture, TexCoords + offset).rgb;
+		}
+	}
+
+	color = vec4(sum, texture(screenTexture, TexCoords).a);
+}
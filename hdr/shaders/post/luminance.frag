@@ -0,0 +1,19 @@
+#version 410 core
+
+// writes the log2 luminance of the HDR scene for gfx/exposure.go to measure.
+// Averaging logarithms makes a geometric mean so a few very bright pixels do not outweigh
+// the rest of the scene like they would with a plain average.
+
+in vec2 TexCoords;
+out float logLuminance;
+
+uniform sampler2D screenTexture;
+
+void main()
+{
+	vec3 hdr = texture(screenTexture, TexCoords).rgb;
+	float luminance = dot(hdr, vec3(0.2126, 0.7152, 0.0722));
+
+	// black has no logarithm, treat it as very dark instead
+	logLuminance = log2(max(luminance, 1.0e-4));
+}
@@ -0,0 +1,85 @@
+#version 410 core
+
+// turns the HDR scene into colors the window can show, see gfx/tonemap.go
+
+in vec2 TexCoords;
+out vec4 color;
+
+// must match ToneMapOperator in gfx/tonemap.go
+#define TONE_MAP_CLAMP      0
+#define TONE_MAP_REINHARD   1
+#define TONE_MAP_ACES       2
+#define TONE_MAP_UNCHARTED2 3
+
+uniform sampler2D screenTexture;
+
+uniform int operator;
+uniform float exposure;    // in stops
+uniform float whitePoint;  // scene brightness that maps to white
+uniform bool encodeSRGB;
+
+// Reinhard extended with a white point so it can reach 1
+vec3 reinhard(vec3 x)
+{
+	return x * (1.0 + x / (whitePoint * whitePoint)) / (1.0 + x);
+}
+
+// Narkowicz 2015, "ACES Filmic Tone Mapping Curve"
+vec3 aces(vec3 x)
+{
+	const float a = 2.51;
+	const float b = 0.03;
+	const float c = 2.43;
+	const float d = 0.59;
+	const float e = 0.14;
+	return clamp((x * (a * x + b)) / (x * (c * x + d) + e), 0.0, 1.0);
+}
+
+// Hable 2010, "Filmic Tonemapping Operators"
+vec3 uncharted2Curve(vec3 x)
+{
+	const float A = 0.15;  // shoulder strength
+	const float B = 0.50;  // linear strength
+	const float C = 0.10;  // linear angle
+	const float D = 0.20;  // toe strength
+	const float E = 0.02;  // toe numerator
+	const float F = 0.30;  // toe denominator
+	return ((x * (A * x + C * B) + D * E) / (x * (A * x + B) + D * F)) - E / F;
+}
+
+vec3 uncharted2(vec3 x)
+{
+	// the curve is made for scenes about twice as bright, then scaled so whitePoint is 1
+	const float exposureBias = 2.0;
+	return uncharted2Curve(x * exposureBias) / uncharted2Curve(vec3(whitePoint));
+}
+
+// the exact sRGB transfer function, a pow(1/2.2) is close but too dark in the shadows
+vec3 linearToSRGB(vec3 c)
+{
+	vec3 low = c * 12.92;
+	vec3 high = 1.055 * pow(c, vec3(1.0 / 2.4)) - 0.055;
+	return mix(low, high, step(vec3(0.0031308), c));
+}
+
+void main()
+{
+	vec3 hdr = texture(screenTexture, TexCoords).rgb * exp2(exposure);
+
+	vec3 mapped;
+	if (operator == TONE_MAP_REINHARD) {
+		mapped = reinhard(hdr);
+	} else if (operator == TONE_MAP_ACES) {
+		mapped = aces(hdr);
+	} else if (operator == TONE_MAP_UNCHARTED2) {
+		mapped = uncharted2(hdr);
+	} else {
+		mapped = hdr;
+	}
+	mapped = clamp(mapped, 0.0, 1.0);
+
+	if (encodeSRGB) {
+		mapped = linearToSRGB(mapped);
+	}
+	color = vec4(mapped, 1.0);
+}
@@ -0,0 +1,24 @@
+#version 410 core
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+// distance from the center where darkening starts and how much further until it is black,
+// the screen's half height is 0.5
+uniform float radius;
+uniform float softness;
+
+void main()
+{
+	vec4 texel = texture(screenTexture, TexCoords);
+
+	// measure in the screen's proportions so the darkened area is round and not stretched
+	vec2 size = vec2(textureSize(screenTexture, 0));
+	vec2 fromCenter = (TexCoords - 0.5) * vec2(size.x / size.y, 1.0);
+
+	float dist = length(fromCenter);
+	float light = 1.0 - smoothstep(radius, radius + softness, dist);
+	color = vec4(texel.rgb * light, texel.a);
+}
@@ -0,0 +1,147 @@
+package gfx
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// InstanceBuffer holds per instance attributes (ex: a model matrix and color per cube) for
+// a VertexArray so that every instance can be drawn with a single DrawInstanced call.
+//
+// The instance data is usually a slice of Go structs matching the layout, ex:
+//
+//	type instance struct {
+//		model mgl32.Mat4 // gfx.Mat4Attribs(3)
+//		color mgl32.Vec3 // gfx.FloatAttrib(7, 3)
+//	}
+//
+// Go lays out structs of 4 byte fields the same way VertexLayout does.
+type InstanceBuffer struct {
+	buf    *Buffer
+	layout *VertexLayout
+	count  int
+
+	// streaming instance buffers write every Update into the next section of ring and point
+	// va's attributes at it, see NewStreamingInstanceBuffer
+	ring    *RingBuffer
+	va      *VertexArray
+	pending bool // Update wrote to ring and EndFrame has not fenced it yet
+}
+
+var errInstanceDivisor = errors.New("instance buffer layouts can only have per instance attributes")
+
+var errTooManyInstances = errors.New("more instances than the streaming instance buffer was made for")
+
+// NewInstanceBuffer attaches a new buffer of per instance attributes to va. Every attribute
+// in layout must be per instance (see PerInstance) and use locations not used by the mesh.
+func NewInstanceBuffer(va *VertexArray, layout *VertexLayout, usage uint32) (*InstanceBuffer, error) {
+	for _, attrib := range layout.Attribs() {
+		if attrib.Divisor == 0 {
+			return nil, errInstanceDivisor
+		}
+	}
+
+	ib := InstanceBuffer{
+		buf:    NewBuffer(gl.ARRAY_BUFFER, usage),
+		layout: layout,
+	}
+
+	// the attribute pointers are stored in the VAO and refer to whichever buffer was bound
+	va.Bind()
+	ib.buf.Bind()
+	layout.Enable()
+	va.UnBind()
+	ib.buf.UnBind()
+
+	return &ib, nil
+}
+
+// NewStreamingInstanceBuffer is an instance buffer for data rewritten every frame, ex: moving
+// instances. Updates go into a RingBuffer with room for maxInstances per frame in flight, so
+// writing the next frame's instances never waits on draws still reading the last ones.
+// Every Update has to be followed by EndFrame once the draws using it have been issued.
+func NewStreamingInstanceBuffer(va *VertexArray, layout *VertexLayout, maxInstances int) (*InstanceBuffer, error) {
+	for _, attrib := range layout.Attribs() {
+		if attrib.Divisor == 0 {
+			return nil, errInstanceDivisor
+		}
+	}
+
+	ib := InstanceBuffer{
+		layout: layout,
+		ring:   NewRingBuffer(gl.ARRAY_BUFFER, maxInstances*layout.Stride(), 3),
+		va:     va,
+	}
+	ib.buf = ib.ring.Buffer()
+
+	return &ib, nil
+}
+
+// Update replaces the instance data with count instances read from data.
+// data must hold count * layout.Stride() bytes.
+func (ib *InstanceBuffer) Update(count int, data unsafe.Pointer) error {
+	if ib.ring == nil {
+		// orphans the old data when the size stays the same so instances can be updated
+		// every frame without waiting on draws that still use last frame's data
+		ib.buf.Stream(count*ib.layout.Stride(), data)
+		ib.buf.UnBind()
+		ib.count = count
+		return nil
+	}
+
+	// a second Update before EndFrame moves on to the next section, fencing the draws of
+	// the first one
+	if ib.pending {
+		ib.EndFrame()
+	}
+	if err := ib.ring.BeginFrame(); err != nil {
+		return err
+	}
+	offset, err := ib.ring.Write(data, count*ib.layout.Stride(), 4)
+	if err == errRingBufferFull {
+		return errTooManyInstances
+	} else if err != nil {
+		return err
+	}
+	ib.pending = true
+
+	// the attribute pointers are stored in the VAO so they have to follow the data around
+	ib.va.Bind()
+	ib.buf.Bind()
+	ib.layout.EnableAt(offset)
+	ib.va.UnBind()
+	ib.buf.UnBind()
+
+	ib.count = count
+	return nil
+}
+
+// EndFrame fences the draws issued since the last Update of a streaming instance buffer so
+// its section of the ring is not written again until the GPU is done with them. It does
+// nothing for other instance buffers or when there was no Update.
+func (ib *InstanceBuffer) EndFrame() {
+	if ib.ring == nil || !ib.pending {
+		return
+	}
+	ib.ring.EndFrame()
+	ib.pending = false
+}
+
+// Count is the number of instances from the last Update.
+func (ib *InstanceBuffer) Count() int {
+	return ib.count
+}
+
+func (ib *InstanceBuffer) Layout() *VertexLayout {
+	return ib.layout
+}
+
+func (ib *InstanceBuffer) Delete() {
+	if ib.ring != nil {
+		ib.ring.Delete()
+		return
+	}
+	ib.buf.Delete()
+}
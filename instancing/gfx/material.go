@@ -0,0 +1,234 @@
+package gfx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Material describes how a surface reacts to light under the phong model.
+// It matches the Material struct in shaders/phong.frag.
+//
+// In JSON files colors are [r, g, b] arrays and field names are lower camel case:
+//
+//	{
+//		"name": "shiny crate",
+//		"preset": "chrome",
+//		"diffuseMap": "../images/container2.png",
+//		"shininess": 64
+//	}
+//
+// "preset" starts from one of the built in presets (see MaterialPresetNames) and any other
+// field overrides it. Texture paths are relative to the JSON file. A diffuse map replaces the
+// ambient and diffuse colors and a specular map replaces the specular color.
+type Material struct {
+	Name      string     `json:"name"`
+	Preset    string     `json:"preset,omitempty"`
+	Ambient   mgl32.Vec3 `json:"ambient"`
+	Diffuse   mgl32.Vec3 `json:"diffuse"`
+	Specular  mgl32.Vec3 `json:"specular"`
+	Shininess float32    `json:"shininess"`
+
+	DiffuseMap  string `json:"diffuseMap,omitempty"`
+	SpecularMap string `json:"specularMap,omitempty"`
+
+	// loaded on the first Bind since that needs a GL context
+	diffuseTex  *Texture
+	specularTex *Texture
+}
+
+// texture units used for the material's maps
+const (
+	diffuseMapUnit  = gl.TEXTURE0
+	specularMapUnit = gl.TEXTURE1
+)
+
+// the classic OpenGL material table (http://devernay.free.fr/cours/opengl/materials.html)
+// shininess there is a fraction of 128. These are meant to be lit with white light.
+var materialPresets = map[string]Material{
+	"emerald":        {Ambient: mgl32.Vec3{0.0215, 0.1745, 0.0215}, Diffuse: mgl32.Vec3{0.07568, 0.61424, 0.07568}, Specular: mgl32.Vec3{0.633, 0.727811, 0.633}, Shininess: 0.6 * 128},
+	"jade":           {Ambient: mgl32.Vec3{0.135, 0.2225, 0.1575}, Diffuse: mgl32.Vec3{0.54, 0.89, 0.63}, Specular: mgl32.Vec3{0.316228, 0.316228, 0.316228}, Shininess: 0.1 * 128},
+	"obsidian":       {Ambient: mgl32.Vec3{0.05375, 0.05, 0.06625}, Diffuse: mgl32.Vec3{0.18275, 0.17, 0.22525}, Specular: mgl32.Vec3{0.332741, 0.328634, 0.346435}, Shininess: 0.3 * 128},
+	"pearl":          {Ambient: mgl32.Vec3{0.25, 0.20725, 0.20725}, Diffuse: mgl32.Vec3{1.0, 0.829, 0.829}, Specular: mgl32.Vec3{0.296648, 0.296648, 0.296648}, Shininess: 0.088 * 128},
+	"ruby":           {Ambient: mgl32.Vec3{0.1745, 0.01175, 0.01175}, Diffuse: mgl32.Vec3{0.61424, 0.04136, 0.04136}, Specular: mgl32.Vec3{0.727811, 0.626959, 0.626959}, Shininess: 0.6 * 128},
+	"turquoise":      {Ambient: mgl32.Vec3{0.1, 0.18725, 0.1745}, Diffuse: mgl32.Vec3{0.396, 0.74151, 0.69102}, Specular: mgl32.Vec3{0.297254, 0.30829, 0.306678}, Shininess: 0.1 * 128},
+	"brass":          {Ambient: mgl32.Vec3{0.329412, 0.223529, 0.027451}, Diffuse: mgl32.Vec3{0.780392, 0.568627, 0.113725}, Specular: mgl32.Vec3{0.992157, 0.941176, 0.807843}, Shininess: 0.21794872 * 128},
+	"bronze":         {Ambient: mgl32.Vec3{0.2125, 0.1275, 0.054}, Diffuse: mgl32.Vec3{0.714, 0.4284, 0.18144}, Specular: mgl32.Vec3{0.393548, 0.271906, 0.166721}, Shininess: 0.2 * 128},
+	"chrome":         {Ambient: mgl32.Vec3{0.25, 0.25, 0.25}, Diffuse: mgl32.Vec3{0.4, 0.4, 0.4}, Specular: mgl32.Vec3{0.774597, 0.774597, 0.774597}, Shininess: 0.6 * 128},
+	"copper":         {Ambient: mgl32.Vec3{0.19125, 0.0735, 0.0225}, Diffuse: mgl32.Vec3{0.7038, 0.27048, 0.0828}, Specular: mgl32.Vec3{0.256777, 0.137622, 0.086014}, Shininess: 0.1 * 128},
+	"gold":           {Ambient: mgl32.Vec3{0.24725, 0.1995, 0.0745}, Diffuse: mgl32.Vec3{0.75164, 0.60648, 0.22648}, Specular: mgl32.Vec3{0.628281, 0.555802, 0.366065}, Shininess: 0.4 * 128},
+	"silver":         {Ambient: mgl32.Vec3{0.19225, 0.19225, 0.19225}, Diffuse: mgl32.Vec3{0.50754, 0.50754, 0.50754}, Specular: mgl32.Vec3{0.508273, 0.508273, 0.508273}, Shininess: 0.4 * 128},
+	"black plastic":  {Ambient: mgl32.Vec3{0.0, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.01, 0.01, 0.01}, Specular: mgl32.Vec3{0.50, 0.50, 0.50}, Shininess: 0.25 * 128},
+	"cyan plastic":   {Ambient: mgl32.Vec3{0.0, 0.1, 0.06}, Diffuse: mgl32.Vec3{0.0, 0.50980392, 0.50980392}, Specular: mgl32.Vec3{0.50196078, 0.50196078, 0.50196078}, Shininess: 0.25 * 128},
+	"green plastic":  {Ambient: mgl32.Vec3{0.0, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.1, 0.35, 0.1}, Specular: mgl32.Vec3{0.45, 0.55, 0.45}, Shininess: 0.25 * 128},
+	"red plastic":    {Ambient: mgl32.Vec3{0.0, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.5, 0.0, 0.0}, Specular: mgl32.Vec3{0.7, 0.6, 0.6}, Shininess: 0.25 * 128},
+	"white plastic":  {Ambient: mgl32.Vec3{0.0, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.55, 0.55, 0.55}, Specular: mgl32.Vec3{0.70, 0.70, 0.70}, Shininess: 0.25 * 128},
+	"yellow plastic": {Ambient: mgl32.Vec3{0.0, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.5, 0.5, 0.0}, Specular: mgl32.Vec3{0.60, 0.60, 0.50}, Shininess: 0.25 * 128},
+	"black rubber":   {Ambient: mgl32.Vec3{0.02, 0.02, 0.02}, Diffuse: mgl32.Vec3{0.01, 0.01, 0.01}, Specular: mgl32.Vec3{0.4, 0.4, 0.4}, Shininess: 0.078125 * 128},
+	"cyan rubber":    {Ambient: mgl32.Vec3{0.0, 0.05, 0.05}, Diffuse: mgl32.Vec3{0.4, 0.5, 0.5}, Specular: mgl32.Vec3{0.04, 0.7, 0.7}, Shininess: 0.078125 * 128},
+	"green rubber":   {Ambient: mgl32.Vec3{0.0, 0.05, 0.0}, Diffuse: mgl32.Vec3{0.4, 0.5, 0.4}, Specular: mgl32.Vec3{0.04, 0.7, 0.04}, Shininess: 0.078125 * 128},
+	"red rubber":     {Ambient: mgl32.Vec3{0.05, 0.0, 0.0}, Diffuse: mgl32.Vec3{0.5, 0.4, 0.4}, Specular: mgl32.Vec3{0.7, 0.04, 0.04}, Shininess: 0.078125 * 128},
+	"white rubber":   {Ambient: mgl32.Vec3{0.05, 0.05, 0.05}, Diffuse: mgl32.Vec3{0.5, 0.5, 0.5}, Specular: mgl32.Vec3{0.7, 0.7, 0.7}, Shininess: 0.078125 * 128},
+	"yellow rubber":  {Ambient: mgl32.Vec3{0.05, 0.05, 0.0}, Diffuse: mgl32.Vec3{0.5, 0.5, 0.4}, Specular: mgl32.Vec3{0.7, 0.7, 0.04}, Shininess: 0.078125 * 128},
+}
+
+// MaterialPreset returns a copy of a built in preset, ex: MaterialPreset("gold").
+func MaterialPreset(name string) (*Material, bool) {
+	preset, ok := materialPresets[name]
+	if !ok {
+		return nil, false
+	}
+	preset.Name = name
+	preset.Preset = name
+	return &preset, true
+}
+
+// MaterialPresetNames lists the built in presets in alphabetical order.
+func MaterialPresetNames() []string {
+	names := make([]string, 0, len(materialPresets))
+	for name := range materialPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadMaterial reads a single material from a JSON file.
+func LoadMaterial(file string) (*Material, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	mat, err := ParseMaterial(data, filepath.Dir(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return mat, nil
+}
+
+// LoadMaterialLibrary reads a JSON array of named materials and returns them by name.
+func LoadMaterialLibrary(file string) (map[string]*Material, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	library := make(map[string]*Material, len(raw))
+	for i, entry := range raw {
+		mat, err := ParseMaterial(entry, filepath.Dir(file))
+		if err != nil {
+			return nil, fmt.Errorf("%s: material %d: %v", file, i, err)
+		}
+		if mat.Name == "" {
+			return nil, fmt.Errorf("%s: material %d has no name", file, i)
+		}
+		if _, exists := library[mat.Name]; exists {
+			return nil, fmt.Errorf("%s: duplicate material %q", file, mat.Name)
+		}
+		library[mat.Name] = mat
+	}
+
+	return library, nil
+}
+
+// ParseMaterial decodes one JSON material, applying its preset (if any) first so that the
+// other fields override it. Texture paths are taken relative to dir.
+func ParseMaterial(data []byte, dir string) (*Material, error) {
+	var header struct {
+		Preset string `json:"preset"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+
+	mat := &Material{}
+	if header.Preset != "" {
+		preset, ok := MaterialPreset(header.Preset)
+		if !ok {
+			return nil, fmt.Errorf("unknown material preset %q", header.Preset)
+		}
+		mat = preset
+		mat.Name = ""
+	}
+
+	if err := json.Unmarshal(data, mat); err != nil {
+		return nil, err
+	}
+
+	if mat.DiffuseMap != "" {
+		mat.DiffuseMap = filepath.Join(dir, mat.DiffuseMap)
+	}
+	if mat.SpecularMap != "" {
+		mat.SpecularMap = filepath.Join(dir, mat.SpecularMap)
+	}
+
+	return mat, nil
+}
+
+// Bind sets the material's uniforms on prog, ex: mat.Bind(program, "material").
+// The program must be in use. Maps are bound to texture units 0 (diffuse) and 1 (specular)
+// and loaded from disk the first time they are needed.
+func (mat *Material) Bind(prog *Program, name string) error {
+	if err := mat.loadTextures(); err != nil {
+		return err
+	}
+
+	gl.Uniform3f(prog.GetUniformLocation(name+".ambient"), mat.Ambient.X(), mat.Ambient.Y(), mat.Ambient.Z())
+	gl.Uniform3f(prog.GetUniformLocation(name+".diffuse"), mat.Diffuse.X(), mat.Diffuse.Y(), mat.Diffuse.Z())
+	gl.Uniform3f(prog.GetUniformLocation(name+".specular"), mat.Specular.X(), mat.Specular.Y(), mat.Specular.Z())
+	gl.Uniform1f(prog.GetUniformLocation(name+".shininess"), mat.Shininess)
+
+	bindMap(prog, name+".diffuseMap", name+".hasDiffuseMap", mat.diffuseTex, diffuseMapUnit)
+	bindMap(prog, name+".specularMap", name+".hasSpecularMap", mat.specularTex, specularMapUnit)
+
+	return nil
+}
+
+func bindMap(prog *Program, samplerName, flagName string, tex *Texture, texUnit uint32) {
+	if tex == nil {
+		gl.Uniform1i(prog.GetUniformLocation(flagName), 0)
+		return
+	}
+	tex.Bind(texUnit)
+	tex.SetUniform(prog.GetUniformLocation(samplerName))
+	gl.Uniform1i(prog.GetUniformLocation(flagName), 1)
+}
+
+// UnBind unbinds any maps bound by Bind.
+func (mat *Material) UnBind() {
+	if mat.diffuseTex != nil {
+		mat.diffuseTex.UnBind()
+	}
+	if mat.specularTex != nil {
+		mat.specularTex.UnBind()
+	}
+}
+
+func (mat *Material) loadTextures() error {
+	var err error
+	if mat.DiffuseMap != "" && mat.diffuseTex == nil {
+		mat.diffuseTex, err = NewTextureFromFile(mat.DiffuseMap, gl.REPEAT, gl.REPEAT)
+		if err != nil {
+			return fmt.Errorf("material %q: %v", mat.Name, err)
+		}
+	}
+	// the specular map is an amount of light rather than a color so it is not sRGB encoded
+	if mat.SpecularMap != "" && mat.specularTex == nil {
+		mat.specularTex, err = NewLinearTextureFromFile(mat.SpecularMap, gl.REPEAT, gl.REPEAT)
+		if err != nil {
+			return fmt.Errorf("material %q: %v", mat.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package gfx
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// RingBuffer hands out per-frame chunks of one large stream buffer.
+// The buffer is split into sections (one per frame in flight). Each frame writes into its own
+// section through unsynchronized mapped ranges and fences it when done, so the CPU only ever
+// waits if it gets more than len(sections) frames ahead of the GPU.
+//
+// Typical frame:
+//
+//	ring.BeginFrame()
+//	offset, _ := ring.Write(gl.Ptr(particles), len(particles)*4, 4)
+//	... draw using ring.Buffer() starting at offset ...
+//	ring.EndFrame()
+type RingBuffer struct {
+	buf         *Buffer
+	sectionSize int
+	fences      []uintptr // one per section, 0 if the section is not in use by the GPU
+	section     int       // section being written this frame
+	head        int       // next free byte in the current section
+}
+
+// how long to block on a single fence wait before checking again (nanoseconds)
+const ringFenceTimeout = 1000000000
+
+var errRingBufferFull = errors.New("ring buffer section is full")
+
+var errFenceWaitFailed = errors.New("failed waiting on ring buffer fence")
+
+// NewRingBuffer allocates a stream buffer of sections*sectionSize bytes.
+// Three sections (triple buffering) is usually enough to never stall.
+func NewRingBuffer(target uint32, sectionSize, sections int) *RingBuffer {
+	buf := NewBuffer(target, gl.STREAM_DRAW)
+	buf.Data(sectionSize*sections, nil)
+	buf.UnBind()
+
+	return &RingBuffer{
+		buf:         buf,
+		sectionSize: sectionSize,
+		fences:      make([]uintptr, sections),
+	}
+}
+
+// Buffer returns the underlying buffer so it can be bound for drawing.
+func (rb *RingBuffer) Buffer() *Buffer {
+	return rb.buf
+}
+
+// BeginFrame waits for the GPU to finish with the current section (if needed) and
+// resets it so that it can be written again.
+func (rb *RingBuffer) BeginFrame() error {
+	fence := rb.fences[rb.section]
+	if fence != 0 {
+		for {
+			status := gl.ClientWaitSync(fence, gl.SYNC_FLUSH_COMMANDS_BIT, ringFenceTimeout)
+			if status == gl.ALREADY_SIGNALED || status == gl.CONDITION_SATISFIED {
+				break
+			}
+			if status == gl.WAIT_FAILED {
+				return errFenceWaitFailed
+			}
+		}
+		gl.DeleteSync(fence)
+		rb.fences[rb.section] = 0
+	}
+
+	rb.head = 0
+	return nil
+}
+
+// Map reserves size bytes in the current section aligned to align bytes and maps them for writing.
+// It returns the offset of the reservation from the start of the whole buffer.
+// Unmap must be called before drawing from the buffer.
+func (rb *RingBuffer) Map(size, align int) (int, []byte, error) {
+	start := rb.head
+	if align > 1 {
+		start = (start + align - 1) / align * align
+	}
+	if start+size > rb.sectionSize {
+		return 0, nil, errRingBufferFull
+	}
+
+	offset := rb.section*rb.sectionSize + start
+
+	// unsynchronized is safe here since the fence in BeginFrame guarantees the GPU is not reading this section
+	access := uint32(gl.MAP_WRITE_BIT | gl.MAP_UNSYNCHRONIZED_BIT | gl.MAP_INVALIDATE_RANGE_BIT)
+	mem, err := rb.buf.MapRange(offset, size, access)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rb.head = start + size
+	return offset, mem, nil
+}
+
+func (rb *RingBuffer) Unmap() error {
+	return rb.buf.Unmap()
+}
+
+// Write copies size bytes from data into the current section and returns their offset in the buffer.
+func (rb *RingBuffer) Write(data unsafe.Pointer, size, align int) (int, error) {
+	offset, mem, err := rb.Map(size, align)
+	if err != nil {
+		return 0, err
+	}
+	copy(mem, unsafe.Slice((*byte)(data), size))
+
+	return offset, rb.Unmap()
+}
+
+// EndFrame fences all commands issued so far against the current section and moves on to the next one.
+func (rb *RingBuffer) EndFrame() {
+	rb.fences[rb.section] = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	rb.section = (rb.section + 1) % len(rb.fences)
+}
+
+func (rb *RingBuffer) Delete() {
+	for i, fence := range rb.fences {
+		if fence != 0 {
+			gl.DeleteSync(fence)
+			rb.fences[i] = 0
+		}
+	}
+	rb.buf.Delete()
+}
@@ -0,0 +1,300 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-OpenGL/Instancing
+
+Draws 100,000 cubes. With instancing every cube's model matrix, color and material are
+stored in a per instance vertex buffer and all of them are drawn with one draw call.
+Press I to switch to setting uniforms and drawing each cube on its own and compare the
+frame times shown in the title.
+
+Press R to spin the cubes. Every frame then rewrites all of the instance data, which goes
+through a gfx.RingBuffer so the upload never waits for the GPU to finish drawing the last
+frame's instances.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/instancing/cam"
+	"github.com/cstegel/opengl-samples-golang/instancing/gfx"
+	"github.com/cstegel/opengl-samples-golang/instancing/win"
+)
+
+// the cubes fill a gridSize x gridLayers x gridSize block
+const (
+	gridSize    = 100
+	gridLayers  = 10
+	gridSpacing = 2.0
+	numCubes    = gridSize * gridSize * gridLayers
+)
+
+// must match NUM_MATERIALS in shaders/cube.frag
+const numMaterials = 4
+
+var materialPresets = [numMaterials]string{"gold", "chrome", "jade", "ruby"}
+
+// cubeInstance is the per instance data, laid out the same as instanceLayout
+type cubeInstance struct {
+	model    mgl32.Mat4
+	color    mgl32.Vec3
+	material uint32
+}
+
+// attribute locations 0-2 are used by the mesh (see gfx.Mesh)
+var instanceLayout = gfx.NewVertexLayout(gfx.PerInstance(append(gfx.Mat4Attribs(3),
+	gfx.FloatAttrib(7, 3),
+	gfx.UintAttrib(8, 1),
+)...)...)
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Instancing")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	// don't wait for vsync so the frame times show the actual cost of drawing
+	glfw.SwapInterval(0)
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+ * Places the cubes on a grid with a random-looking but repeatable rotation, color and material.
+ */
+func makeCubes() []cubeInstance {
+	cubes := make([]cubeInstance, 0, numCubes)
+	half := float32(gridSize-1) * gridSpacing / 2
+
+	for y := 0; y < gridLayers; y++ {
+		for z := 0; z < gridSize; z++ {
+			for x := 0; x < gridSize; x++ {
+				i := len(cubes)
+				t := float32(i) / float32(numCubes)
+
+				pos := mgl32.Vec3{
+					float32(x)*gridSpacing - half,
+					float32(y)*gridSpacing - float32(gridLayers)*gridSpacing,
+					-float32(z) * gridSpacing,
+				}
+				axis := mgl32.Vec3{float32(x%7) - 3, float32(y%5) + 1, float32(z%3) - 1}.Normalize()
+				angle := float32(i%360) * math.Pi / 180
+
+				model := mgl32.Translate3D(pos.X(), pos.Y(), pos.Z()).
+					Mul4(mgl32.HomogRotate3D(angle, axis)).
+					Mul4(mgl32.Scale3D(0.8, 0.8, 0.8))
+
+				// light pastel colors so the material still shows through
+				color := mgl32.Vec3{
+					0.6 + 0.4*float32(math.Sin(float64(t*47))),
+					0.6 + 0.4*float32(math.Sin(float64(t*31+2))),
+					0.6 + 0.4*float32(math.Sin(float64(t*17+4))),
+				}
+
+				cubes = append(cubes, cubeInstance{
+					model:    model,
+					color:    color,
+					material: uint32((x + z + y) % numMaterials),
+				})
+			}
+		}
+	}
+
+	return cubes
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/cube.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/cube.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	cubes := makeCubes()
+
+	// spinning starts from where makeCubes placed the cubes
+	baseModels := make([]mgl32.Mat4, len(cubes))
+	for i := range cubes {
+		baseModels[i] = cubes[i].model
+	}
+
+	instances, err := gfx.NewStreamingInstanceBuffer(cube, instanceLayout, len(cubes))
+	if err != nil {
+		return err
+	}
+	defer instances.Delete()
+	if err := instances.Update(len(cubes), gl.Ptr(&cubes[0])); err != nil {
+		return err
+	}
+
+	sun := gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.3, -1.0, -0.5},
+		Ambient:   mgl32.Vec3{0.3, 0.3, 0.3},
+		Diffuse:   mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:  mgl32.Vec3{1.0, 1.0, 1.0},
+	}
+
+	var materials [numMaterials]*gfx.Material
+	for i, name := range materialPresets {
+		materials[i], _ = gfx.MaterialPreset(name)
+	}
+
+	// looking up uniforms 100,000 times a frame would hide the cost of the draw calls
+	modelLoc := program.GetUniformLocation("model")
+	colorLoc := program.GetUniformLocation("color")
+	materialLoc := program.GetUniformLocation("material")
+
+	instanced := true
+	spinning := false
+	spinAngle := float32(0)
+
+	// frame time is averaged and shown in the title once per second
+	frames := 0
+	frameTime := 0.0
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 5, 10}, mgl32.Vec3{0, 1, 0}, -90, -20, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		if window.InputManager().IsTriggered(win.TOGGLE_INSTANCING) {
+			instanced = !instanced
+		}
+		if window.InputManager().IsTriggered(win.TOGGLE_SPIN) {
+			spinning = !spinning
+		}
+
+		if spinning {
+			spinAngle += float32(window.SinceLastFrame()) * math.Pi / 2
+			spin := mgl32.HomogRotate3DY(spinAngle)
+			for i := range cubes {
+				cubes[i].model = baseModels[i].Mul4(spin)
+			}
+			if err := instances.Update(len(cubes), gl.Ptr(&cubes[0])); err != nil {
+				return err
+			}
+		}
+
+		frames++
+		frameTime += window.SinceLastFrame()
+		if frameTime >= 1.0 {
+			mode := "instanced, 1 draw call"
+			if !instanced {
+				mode = fmt.Sprintf("not instanced, %d draw calls", len(cubes))
+			}
+			window.SetTitle(fmt.Sprintf("Instancing - %d cubes, %s - %.2f ms/frame",
+				len(cubes), mode, 1000*frameTime/float64(frames)))
+			frames = 0
+			frameTime = 0
+		}
+
+		// background color
+		gl.ClearColor(0.1, 0.1, 0.12, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		// creates perspective
+		fov := float32(60.0)
+		projectTransform := mgl32.Perspective(mgl32.DegToRad(fov),
+			float32(window.Width())/float32(window.Height()),
+			0.1,
+			500.0)
+
+		camTransform := camera.GetTransform()
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		sun.SetUniforms(program, "dirLight", camTransform)
+		for i, mat := range materials {
+			if err := mat.Bind(program, fmt.Sprintf("materials[%d]", i)); err != nil {
+				return err
+			}
+		}
+
+		gl.Uniform1i(program.GetUniformLocation("instanced"), boolToInt(instanced))
+
+		cube.Bind()
+		if instanced {
+			cube.DrawInstanced(instances.Count())
+		} else {
+			// the same data as the instance buffer, one cube at a time
+			for i := range cubes {
+				c := &cubes[i]
+				gl.UniformMatrix4fv(modelLoc, 1, false, &c.model[0])
+				gl.Uniform3f(colorLoc, c.color.X(), c.color.Y(), c.color.Z())
+				gl.Uniform1ui(materialLoc, c.material)
+				cube.Draw()
+			}
+		}
+		cube.UnBind()
+
+		// the ring section written by Update can be reused once these draws are done
+		instances.EndFrame()
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
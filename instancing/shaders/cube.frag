@@ -0,0 +1,51 @@
+#version 410 core
+
+// must match numMaterials in main.go
+#define NUM_MATERIALS 4
+
+// matches gfx.Material, only the colors are used here
+struct Material {
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+	float shininess;
+};
+
+// matches gfx.DirectionalLight, the direction is in view space
+struct DirLight {
+	vec3 direction;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+in vec3 Normal;
+in vec3 FragPos;
+in vec3 Color;
+flat in uint MaterialIndex;
+out vec4 color;
+
+uniform Material materials[NUM_MATERIALS];
+uniform DirLight dirLight;
+
+void main()
+{
+	Material material = materials[MaterialIndex];
+
+	vec3 norm = normalize(Normal);
+	vec3 dirToLight = normalize(-dirLight.direction);
+	vec3 dirToView = normalize(-FragPos);
+
+	// the instance color tints the material
+	vec3 ambient = dirLight.ambient * material.ambient * Color;
+
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = dirLight.diffuse * lightNormalDiff * material.diffuse * Color;
+
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = dirLight.specular * spec * material.specular;
+
+	color = vec4(ambient + diffuse + specular, 1.0f);
+}
@@ -0,0 +1,38 @@
+#version 410 core
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 normal;
+
+// per instance attributes (see instanceLayout in main.go), a mat4 takes up locations 3-6
+layout (location = 3) in mat4 instanceModel;
+layout (location = 7) in vec3 instanceColor;
+layout (location = 8) in uint instanceMaterial;
+
+uniform mat4 view;
+uniform mat4 project;
+
+// when false the cube is drawn on its own with these uniforms instead of the instance attributes
+uniform bool instanced;
+uniform mat4 model;
+uniform vec3 color;
+uniform uint material;
+
+out vec3 Normal;
+out vec3 FragPos;
+out vec3 Color;
+flat out uint MaterialIndex;
+
+void main()
+{
+    mat4 m = instanced ? instanceModel : model;
+    Color = instanced ? instanceColor : color;
+    MaterialIndex = instanced ? instanceMaterial : material;
+
+    gl_Position = project * view * m * vec4(position, 1.0);
+
+    // lighting is done in view space so that the viewer is at (0,0,0)
+    FragPos = vec3(view * m * vec4(position, 1.0));
+
+    // the cubes are only rotated and uniformly scaled so the normal matrix is not needed
+    Normal = mat3(view) * mat3(m) * normal;
+}
@@ -0,0 +1,333 @@
+package main
+
+/*
+http://www.learnopengl.com/#!Lighting/Light-casters
+
+Shows the three common types of light casters mixed in one scene:
+a directional light (sun), point lights that fade with distance
+and a spot light acting as a flashlight held by the camera
+*/
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/light-casters/gfx"
+	"github.com/cstegel/opengl-samples-golang/light-casters/win"
+	"github.com/cstegel/opengl-samples-golang/light-casters/cam"
+)
+
+// vertices to draw 6 faces of a cube
+var cubeVertices = []float32{
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+}
+
+var cubePositions = [][]float32 {
+	{ 0.0,  0.0,  -3.0},
+	{ 2.0,  5.0, -15.0},
+	{-1.5, -2.2, -2.5 },
+	{-3.8, -2.0, -12.3},
+	{ 2.4, -0.4, -3.5 },
+	{-1.7,  3.0, -7.5 },
+	{ 1.3, -2.0, -2.5 },
+	{ 1.5,  2.0, -2.5 },
+	{ 1.5,  0.2, -1.5 },
+	{-1.3,  1.0, -1.5 },
+}
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 4
+
+// colored point lights spread around the cubes
+var pointLights = []gfx.PointLight{
+	{
+		Position:    mgl32.Vec3{0.7, 0.2, 2.0},
+		Ambient:     mgl32.Vec3{0.05, 0.05, 0.05},
+		Diffuse:     mgl32.Vec3{0.8, 0.8, 0.8},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Position:    mgl32.Vec3{2.3, -3.3, -4.0},
+		Ambient:     mgl32.Vec3{0.05, 0.0, 0.0},
+		Diffuse:     mgl32.Vec3{0.8, 0.1, 0.1},
+		Specular:    mgl32.Vec3{1.0, 0.2, 0.2},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Position:    mgl32.Vec3{-4.0, 2.0, -12.0},
+		Ambient:     mgl32.Vec3{0.0, 0.05, 0.0},
+		Diffuse:     mgl32.Vec3{0.1, 0.8, 0.1},
+		Specular:    mgl32.Vec3{0.2, 1.0, 0.2},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Position:    mgl32.Vec3{-1.0, -0.5, -5.0},
+		Ambient:     mgl32.Vec3{0.0, 0.0, 0.05},
+		Diffuse:     mgl32.Vec3{0.1, 0.1, 0.8},
+		Specular:    mgl32.Vec3{0.2, 0.2, 1.0},
+		Attenuation: gfx.AttenuationForDistance(20),
+	},
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Light casters")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+ * Creates the Vertex Array Object for a triangle.
+ * indices is leftover from earlier samples and not used here.
+ */
+func createVAO(vertices []float32, indices []uint32) uint32 {
+
+	var VAO uint32
+	gl.GenVertexArrays(1, &VAO)
+
+	// Bind the Vertex Array Object first, then bind and set vertex buffer(s) and attribute pointers()
+	gl.BindVertexArray(VAO)
+
+	// copy vertices data into VBO (it is left bound for the attribute pointers below)
+	gfx.NewBufferWithData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, len(vertices)*4, gl.Ptr(vertices))
+
+	// position, normal, texture position
+	layout := gfx.NewVertexLayout(gfx.FloatAttrib(0, 3), gfx.FloatAttrib(1, 3), gfx.FloatAttrib(2, 2))
+	layout.Enable()
+
+	// unbind the VAO (safe practice so we don't accidentally (mis)configure it later)
+	gl.BindVertexArray(0)
+
+	return VAO
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+
+	VAO := createVAO(cubeVertices, nil)
+	lightVAO := createVAO(cubeVertices, nil)
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// dim bluish sun light coming from above
+	sun := gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.05, 0.05, 0.08},
+		Diffuse:   mgl32.Vec3{0.2, 0.2, 0.3},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	// the flashlight moves with the camera, its position/direction are updated every frame
+	flashlight := gfx.SpotLight{
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)  // depth buffer needed for DEPTH_TEST
+
+
+		// cube rotation matrices
+		rotateX   := (mgl32.Rotate3DX(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+		rotateY   := (mgl32.Rotate3DY(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+		rotateZ   := (mgl32.Rotate3DZ(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+
+		// creates perspective
+		fov := float32(60.0)
+		projectTransform := mgl32.Perspective(mgl32.DegToRad(fov),
+		                                      float32(window.Width())/float32(window.Height()),
+		                                      0.1,
+		                                      100.0)
+
+		camTransform := camera.GetTransform()
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false,
+		                    &projectTransform[0])
+
+		gl.BindVertexArray(VAO)
+
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		// the lights convert themselves to view space using the camera transform
+		sun.SetUniforms(program, "dirLight", camTransform)
+
+		numPointLights := len(pointLights)
+		if numPointLights > maxPointLights {
+			numPointLights = maxPointLights
+		}
+		for i := 0; i < numPointLights; i++ {
+			pointLights[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+		}
+		gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+
+		flashlight.Position = camera.Position()
+		flashlight.Direction = camera.Front()
+		flashlight.SetUniforms(program, "spotLight", camTransform)
+
+		for _, pos := range cubePositions {
+
+			worldTranslate := mgl32.Translate3D(pos[0], pos[1], pos[2])
+			worldTransform := worldTranslate.Mul4(
+				rotateX.Mul3(rotateY).Mul3(rotateZ).Mat4(),
+			)
+
+			gl.UniformMatrix4fv(program.GetUniformLocation("model"), 1, false,
+			                    &worldTransform[0])
+
+			gl.DrawArrays(gl.TRIANGLES, 0, 36)
+		}
+		gl.BindVertexArray(0)
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// Draw the point lights after the other boxes using their separate shader program
+		// this means that we must re-bind any uniforms
+		lightProgram.Use()
+		gl.BindVertexArray(lightVAO)
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		for _, light := range pointLights[:numPointLights] {
+			lightTransform := mgl32.Translate3D(light.Position.X(), light.Position.Y(), light.Position.Z()).Mul4(
+			                                    mgl32.Scale3D(0.2, 0.2, 0.2))
+			gl.UniformMatrix4fv(lightProgram.GetUniformLocation("model"), 1, false, &lightTransform[0])
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"),
+			             light.Diffuse.X(), light.Diffuse.Y(), light.Diffuse.Z())
+			gl.DrawArrays(gl.TRIANGLES, 0, 36)
+		}
+
+		gl.BindVertexArray(0)
+
+		// end of draw loop
+	}
+
+	return nil
+}
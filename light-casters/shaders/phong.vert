@@ -0,0 +1,29 @@
+#version 410 core
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 normal;
+layout (location = 2) in vec2 texCoord;
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 project;
+
+out vec3 Normal;
+out vec3 FragPos;
+out vec2 TexCoords;
+
+void main()
+{
+    gl_Position = project * view * model * vec4(position, 1.0);
+
+    // we transform positions and vectors to view space before performing lighting
+    // calculations in the fragment shader so that we know that the viewer position is (0,0,0)
+    // the lights are uploaded in view space already (see gfx/light.go)
+    FragPos = vec3(view * model * vec4(position, 1.0));
+
+    TexCoords = texCoord;
+
+    // transform the normals to the view space (see basic-light for why this is different)
+    mat3 normMatrix = mat3(transpose(inverse(view))) * mat3(transpose(inverse(model)));
+    Normal = normMatrix * normal;
+}
@@ -0,0 +1,257 @@
+package gfx
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+func TestFloat32ToHalf(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float32
+		want uint16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3c00},
+		{"minus two", -2, 0xc000},
+		{"smallest normal", 1.0 / (1 << 14), 0x0400},
+		{"largest subnormal", 1023.0 / (1 << 24), 0x03ff},
+		{"smallest subnormal", 1.0 / (1 << 24), 0x0001},
+		{"half of smallest subnormal rounds to even zero", 1.0 / (1 << 25), 0x0000},
+		{"below half of smallest subnormal", 1.0 / (1 << 26), 0x0000},
+		{"subnormal tie rounds to even", 3.0 / (1 << 25), 0x0002},
+		{"negative subnormal", -1.0 / (1 << 24), 0x8001},
+		{"tie rounds down to even", 1 + 1.0/(1<<11), 0x3c00},
+		{"tie rounds up to even", 1 + 3.0/(1<<11), 0x3c02},
+		{"above tie rounds up", 1 + 1.0/(1<<11) + 1.0/(1<<20), 0x3c01},
+		{"largest half", 65504, 0x7bff},
+		{"below overflow rounds down", 65519, 0x7bff},
+		{"rounding overflows to inf", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"negative overflow", -1e6, 0xfc00},
+		{"inf", float32(math.Inf(1)), 0x7c00},
+		{"negative inf", float32(math.Inf(-1)), 0xfc00},
+	}
+
+	for _, test := range tests {
+		if got := Float32ToHalf(test.f); got != test.want {
+			t.Errorf("%s: Float32ToHalf(%v) = 0x%04x, want 0x%04x", test.name, test.f, got, test.want)
+		}
+	}
+
+	for _, f := range []float32{float32(math.NaN()), -float32(math.NaN())} {
+		if h := Float32ToHalf(f); h&0x7c00 != 0x7c00 || h&0x3ff == 0 {
+			t.Errorf("Float32ToHalf(NaN) = 0x%04x, want a NaN", h)
+		}
+	}
+}
+
+func TestHalfToFloat32(t *testing.T) {
+	tests := []struct {
+		name string
+		h    uint16
+		want float32
+	}{
+		{"zero", 0x0000, 0},
+		{"one", 0x3c00, 1},
+		{"minus two", 0xc000, -2},
+		{"smallest normal", 0x0400, 1.0 / (1 << 14)},
+		{"largest subnormal", 0x03ff, 1023.0 / (1 << 24)},
+		{"smallest subnormal", 0x0001, 1.0 / (1 << 24)},
+		{"negative subnormal", 0x8001, -1.0 / (1 << 24)},
+		{"largest half", 0x7bff, 65504},
+		{"inf", 0x7c00, float32(math.Inf(1))},
+		{"negative inf", 0xfc00, float32(math.Inf(-1))},
+	}
+
+	for _, test := range tests {
+		if got := HalfToFloat32(test.h); got != test.want {
+			t.Errorf("%s: HalfToFloat32(0x%04x) = %v, want %v", test.name, test.h, got, test.want)
+		}
+	}
+
+	if f := HalfToFloat32(0x8000); f != 0 || !math.Signbit(float64(f)) {
+		t.Errorf("HalfToFloat32(0x8000) = %v, want -0", f)
+	}
+	if f := HalfToFloat32(0x7e00); !math.IsNaN(float64(f)) {
+		t.Errorf("HalfToFloat32(0x7e00) = %v, want NaN", f)
+	}
+}
+
+func TestHalfRoundTrip(t *testing.T) {
+	for h := 0; h <= 0xffff; h++ {
+		// NaNs only have to stay NaNs, not keep their payload
+		if h&0x7c00 == 0x7c00 && h&0x3ff != 0 {
+			continue
+		}
+		if got := Float32ToHalf(HalfToFloat32(uint16(h))); got != uint16(h) {
+			t.Fatalf("Float32ToHalf(HalfToFloat32(0x%04x)) = 0x%04x", h, got)
+		}
+	}
+}
+
+func TestPackUnorm8(t *testing.T) {
+	tests := []struct {
+		f    float32
+		want uint8
+	}{
+		{0, 0},
+		{1, 255},
+		{0.5, 128},
+		{1.0 / 255, 1},
+		{-1, 0},
+		{2, 255},
+	}
+
+	for _, test := range tests {
+		if got := PackUnorm8(test.f); got != test.want {
+			t.Errorf("PackUnorm8(%v) = %d, want %d", test.f, got, test.want)
+		}
+	}
+
+	for b := 0; b <= 255; b++ {
+		if got := PackUnorm8(UnpackUnorm8(uint8(b))); got != uint8(b) {
+			t.Errorf("PackUnorm8(UnpackUnorm8(%d)) = %d", b, got)
+		}
+	}
+}
+
+func TestPackInt2101010Rev(t *testing.T) {
+	tests := []struct {
+		name       string
+		x, y, z, w float32
+		want       uint32
+	}{
+		{"zero", 0, 0, 0, 0, 0},
+		{"x", 1, 0, 0, 0, 511},
+		{"minus x", -1, 0, 0, 0, 0x201},
+		{"y", 0, 1, 0, 0, 511 << 10},
+		{"minus z", 0, 0, -1, 0, 0x201 << 20},
+		{"w", 0, 0, 0, 1, 1 << 30},
+		{"minus w", 0, 0, 0, -1, 3 << 30},
+		{"clamped", 2, -2, 0, 5, 511 | 0x201<<10 | 1<<30},
+		{"rounded", 0.5, 0, 0, 0, 256}, // 255.5 rounds up
+	}
+
+	for _, test := range tests {
+		if got := PackInt2101010Rev(test.x, test.y, test.z, test.w); got != test.want {
+			t.Errorf("%s: PackInt2101010Rev(%v, %v, %v, %v) = 0x%08x, want 0x%08x",
+				test.name, test.x, test.y, test.z, test.w, got, test.want)
+		}
+	}
+
+	// -1 must come back as -1 in every field, including the 2 bit w whose most negative
+	// value is -2
+	x, y, z, w := UnpackInt2101010Rev(PackInt2101010Rev(-1, -1, -1, -1))
+	if x != -1 || y != -1 || z != -1 || w != -1 {
+		t.Errorf("unpacking -1 everywhere gave %v, %v, %v, %v", x, y, z, w)
+	}
+	if _, _, _, w := UnpackInt2101010Rev(2 << 30); w != -1 {
+		t.Errorf("unpacking the most negative w gave %v, want -1", w)
+	}
+
+	x, y, z, w = UnpackInt2101010Rev(PackInt2101010Rev(0.25, -0.5, 0.75, 1))
+	for _, c := range []struct{ got, want float32 }{{x, 0.25}, {y, -0.5}, {z, 0.75}, {w, 1}} {
+		if math.Abs(float64(c.got-c.want)) > 1.0/511 {
+			t.Errorf("round trip gave %v, want %v", c.got, c.want)
+		}
+	}
+}
+
+func TestVertexLayoutPack(t *testing.T) {
+	layout := NewVertexLayout(FloatAttrib(0, 3), PackedNormalAttrib(1), HalfAttrib(2, 2))
+	if layout.Stride() != 20 {
+		t.Fatalf("stride is %d, want 20", layout.Stride())
+	}
+
+	// 36 vertices is a multiple of both 3 and 4, like a cube drawn without indices
+	const numVertices = 36
+	axes := [][3]float32{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}
+	var positions, normals, uvs []float32
+	for v := 0; v < numVertices; v++ {
+		n := axes[v/6]
+		positions = append(positions, float32(v), -float32(v), 0.5)
+		normals = append(normals, n[0], n[1], n[2])
+		uvs = append(uvs, float32(v%2), float32(v%3)/2)
+	}
+
+	data, err := layout.Pack(positions, normals, uvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != numVertices*layout.Stride() {
+		t.Fatalf("packed %d bytes, want %d", len(data), numVertices*layout.Stride())
+	}
+
+	for v := 0; v < numVertices; v++ {
+		vertex := data[v*layout.Stride():]
+
+		for i := 0; i < 3; i++ {
+			got := math.Float32frombits(binary.LittleEndian.Uint32(vertex[layout.Offset(0)+4*i:]))
+			if got != positions[3*v+i] {
+				t.Errorf("vertex %d: position[%d] = %v, want %v", v, i, got, positions[3*v+i])
+			}
+		}
+
+		x, y, z, w := UnpackInt2101010Rev(binary.LittleEndian.Uint32(vertex[layout.Offset(1):]))
+		if got := [3]float32{x, y, z}; got != axes[v/6] || w != 0 {
+			t.Errorf("vertex %d: normal = %v, %v, want %v, 0", v, got, w, axes[v/6])
+		}
+
+		for i := 0; i < 2; i++ {
+			got := HalfToFloat32(binary.LittleEndian.Uint16(vertex[layout.Offset(2)+2*i:]))
+			if got != uvs[2*v+i] {
+				t.Errorf("vertex %d: uv[%d] = %v, want %v", v, i, got, uvs[2*v+i])
+			}
+		}
+	}
+}
+
+func TestVertexLayoutPackErrors(t *testing.T) {
+	layout := NewVertexLayout(FloatAttrib(0, 3), PackedNormalAttrib(1))
+	positions := make([]float32, 3*36)
+
+	tests := []struct {
+		name    string
+		streams [][]float32
+	}{
+		{"missing stream", [][]float32{positions}},
+		{"normals with w", [][]float32{positions, make([]float32, 4*36)}},
+		{"fewer normals", [][]float32{positions, make([]float32, 3*35)}},
+		{"partial vertex", [][]float32{positions[:3*36-1], make([]float32, 3*36)}},
+		{"more normals", [][]float32{positions, make([]float32, 3*37)}},
+		{"no positions", [][]float32{nil, make([]float32, 3*36)}},
+	}
+
+	for _, test := range tests {
+		if _, err := layout.Pack(test.streams...); err == nil {
+			t.Errorf("%s: Pack succeeded", test.name)
+		}
+	}
+}
+
+func TestNewVertexLayoutInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		attrib VertexAttrib
+	}{
+		{"no components", FloatAttrib(0, 0)},
+		{"too many components", FloatAttrib(0, 5)},
+		{"more inputs than components", VertexAttrib{Index: 0, Size: 2, Type: gl.FLOAT, Inputs: 3}},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: NewVertexLayout did not panic", test.name)
+				}
+			}()
+			NewVertexLayout(FloatAttrib(1, 3), test.attrib)
+		}()
+	}
+}
@@ -36,7 +36,29 @@ func NewTextureFromFile(file string, wrapR, wrapS int32) (*Texture, error) {
 	return NewTexture(img, wrapR, wrapS)
 }
 
+// NewLinearTextureFromFile is like NewTextureFromFile but for images holding data
+// rather than colors, see NewLinearTexture.
+func NewLinearTextureFromFile(file string, wrapR, wrapS int32) (*Texture, error) {
+	img, err := loadImageFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewLinearTexture(img, wrapR, wrapS)
+}
+
+// NewTexture creates a texture for color images which are stored in sRGB space
+// so they are converted to linear values when sampled.
 func NewTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
+	return newTexture(img, gl.SRGB_ALPHA, wrapR, wrapS)
+}
+
+// NewLinearTexture creates a texture whose texels are sampled as is (no sRGB conversion).
+// Use it for non-color data such as normal maps.
+func NewLinearTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
+	return newTexture(img, gl.RGBA8, wrapR, wrapS)
+}
+
+func newTexture(img image.Image, internalFmt int32, wrapR, wrapS int32) (*Texture, error) {
 	rgba := image.NewRGBA(img.Bounds())
 	draw.Draw(rgba, rgba.Bounds(), img, image.Pt(0, 0), draw.Src)
 	if rgba.Stride != rgba.Rect.Size().X*4 {  // TODO-cs: why?
@@ -47,7 +69,6 @@ func NewTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
 	gl.GenTextures(1, &handle)
 
 	target      := uint32(gl.TEXTURE_2D)
-	internalFmt := int32(gl.SRGB_ALPHA)
 	format      := uint32(gl.RGBA)
 	width       := int32(rgba.Rect.Size().X)
 	height      := int32(rgba.Rect.Size().Y)
@@ -84,6 +105,10 @@ func (tex *Texture) Bind(texUnit uint32) {
 }
 
 func (tex *Texture) UnBind() {
+	// unbind from the unit this texture was bound to, not whichever unit happens to be active
+	if tex.texUnit != 0 {
+		gl.ActiveTexture(tex.texUnit)
+	}
 	tex.texUnit = 0
 	gl.BindTexture(tex.target, 0)
 }
@@ -1,15 +1,15 @@
 package main
 
 /*
-http://www.learnopengl.com/#!Lighting/Materials
+http://www.learnopengl.com/#!Lighting/Lighting-maps
 
-Shows basic materials with phong lighting
+Shows phong lighting where the material's diffuse, specular and emission
+colors come from textures instead of being constant for the whole object
 */
 
 import (
 	"log"
 	"runtime"
-	"math"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.1/glfw"
@@ -22,48 +22,48 @@ import (
 
 // vertices to draw 6 faces of a cube
 var cubeVertices = []float32{
-	// position        // normal vector
-	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-
-	-0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	-0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	-0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-
-	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,
-	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,
-	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,
-
-	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,
-	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,
-	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,
-
-	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-
-	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
 }
 
 var cubePositions = [][]float32 {
@@ -113,42 +113,39 @@ func main() {
  * Creates the Vertex Array Object for a triangle.
  * indices is leftover from earlier samples and not used here.
  */
-func createVAO(vertices []float32, indices []uint32) uint32 {
+func createVAO(vertices []float32, indices []uint32) (uint32, error) {
+
+	// split the interleaved position, normal vector and texture position into a stream each
+	var positions, normals, texCoords []float32
+	for v := 0; v+8 <= len(vertices); v += 8 {
+		positions = append(positions, vertices[v:v+3]...)
+		normals = append(normals, vertices[v+3:v+6]...)
+		texCoords = append(texCoords, vertices[v+6:v+8]...)
+	}
+
+	// the normal vector fits in a single packed int and the texture position in half floats,
+	// that is 20 bytes per vertex instead of 32
+	layout := gfx.NewVertexLayout(gfx.FloatAttrib(0, 3), gfx.PackedNormalAttrib(1), gfx.HalfAttrib(2, 2))
+	data, err := layout.Pack(positions, normals, texCoords)
+	if err != nil {
+		return 0, err
+	}
 
 	var VAO uint32
 	gl.GenVertexArrays(1, &VAO)
 
-	var VBO uint32
-	gl.GenBuffers(1, &VBO)
-
-	var EBO uint32;
-	gl.GenBuffers(1, &EBO)
-
 	// Bind the Vertex Array Object first, then bind and set vertex buffer(s) and attribute pointers()
 	gl.BindVertexArray(VAO)
 
-	// copy vertices data into VBO (it needs to be bound first)
-	gl.BindBuffer(gl.ARRAY_BUFFER, VBO)
-	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
-
-	// size of one whole vertex (sum of attrib sizes)
-	var stride int32 = 3*4 + 3*4
-	var offset int = 0
+	// copy vertices data into VBO (it is left bound for the attribute pointers below)
+	gfx.NewBufferWithData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, len(data), gl.Ptr(data))
 
-	// position
-	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, stride, gl.PtrOffset(offset))
-	gl.EnableVertexAttribArray(0)
-	offset += 3*4
-
-	// normal
-	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, stride, gl.PtrOffset(offset))
-	gl.EnableVertexAttribArray(1)
-	offset += 3*4
+	layout.Enable()
 
 	// unbind the VAO (safe practice so we don't accidentally (mis)configure it later)
 	gl.BindVertexArray(0)
 
-	return VAO
+	return VAO, nil
 }
 
 func programLoop(window *win.Window) error {
@@ -181,8 +178,33 @@ func programLoop(window *win.Window) error {
 		return err
 	}
 
-	VAO := createVAO(cubeVertices, nil)
-	lightVAO := createVAO(cubeVertices, nil)
+	VAO, err := createVAO(cubeVertices, nil)
+	if err != nil {
+		return err
+	}
+	lightVAO, err := createVAO(cubeVertices, nil)
+	if err != nil {
+		return err
+	}
+
+	// the material colors now come from textures sampled with the cube's texture positions
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// white where the steel frame should be shiny, black for the wood. It is an amount of
+	// specular light rather than a color so it is sampled as is instead of converted from sRGB.
+	specularMap, err := gfx.NewLinearTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// emitted light is added regardless of the lights in the scene
+	emissionMap, err := gfx.NewTextureFromFile("../images/container2_emission.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
 
 	// ensure that triangles that are "behind" others do not draw over top of them
 	gl.Enable(gl.DEPTH_TEST)
@@ -228,35 +250,19 @@ func programLoop(window *win.Window) error {
 
 		// draw each cube after all coordinate system transforms are bound
 
-		// obj is colored, light is white
-		gl.Uniform3f(program.GetUniformLocation("material.ambient"), 1.0, 0.5, 0.31)
-		gl.Uniform3f(program.GetUniformLocation("material.diffuse"), 1.0, 0.5, 0.31)
-		gl.Uniform3f(program.GetUniformLocation("material.specular"), 0.5, 0.5, 0.5)
-		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
-
-		lightColor := mgl32.Vec3{
-			float32(math.Sin(glfw.GetTime() * 1)),
-			float32(math.Sin(glfw.GetTime() * 0.35)),
-			float32(math.Sin(glfw.GetTime() * 0.65)),
-		}
-
-		diffuseColor := mgl32.Vec3{
-			0.5 * lightColor[0],
-			0.5 * lightColor[1],
-			0.5 * lightColor[2],
-		}
-		ambientColor := mgl32.Vec3{
-			0.2 * lightColor[0],
-			0.2 * lightColor[1],
-			0.2 * lightColor[2],
-		}
-
-		gl.Uniform3f(program.GetUniformLocation("light.ambient"),
-		             ambientColor[0], ambientColor[1], ambientColor[2])
-		gl.Uniform3f(program.GetUniformLocation("light.diffuse"),
-		             diffuseColor[0], diffuseColor[1], diffuseColor[2])
+		// each map is bound to its own texture unit and the sampler uniform is set to that unit
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		emissionMap.Bind(gl.TEXTURE2)
+		emissionMap.SetUniform(program.GetUniformLocation("material.emission"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 64.0)
+
+		gl.Uniform3f(program.GetUniformLocation("light.ambient"), 0.2, 0.2, 0.2)
+		gl.Uniform3f(program.GetUniformLocation("light.diffuse"), 0.5, 0.5, 0.5)
 		gl.Uniform3f(program.GetUniformLocation("light.specular"), 1.0, 1.0, 1.0)
-		gl.Uniform3f(program.GetUniformLocation("light.position"), lightPos.X(), lightPos.Y(), lightPos.Z())
+		gl.Uniform3f(program.GetUniformLocation("lightPos"), lightPos.X(), lightPos.Y(), lightPos.Z())
 
 		for _, pos := range cubePositions {
 
@@ -273,6 +279,10 @@ func programLoop(window *win.Window) error {
 		}
 		gl.BindVertexArray(0)
 
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+		emissionMap.UnBind()
+
 		// Draw the light obj after the other boxes using its separate shader program
 		// this means that we must re-bind any uniforms
 		lightProgram.Use()
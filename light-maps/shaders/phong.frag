@@ -1,15 +1,14 @@
 #version 410 core
 
+// all of the material's colors are looked up per fragment from texture maps
 struct Material {
-	vec3 ambient;
-	vec3 diffuse;
-	vec3 specular;
+	sampler2D diffuse;
+	sampler2D specular;
+	sampler2D emission;
 	float shininess;
 };
 
 struct Light {
-	vec3 position;
-
 	vec3 ambient;
 	vec3 diffuse;
 	vec3 specular;
@@ -18,6 +17,7 @@ struct Light {
 in vec3 Normal;
 in vec3 FragPos;
 in vec3 LightPos;
+in vec2 TexCoords;
 out vec4 color;
 
 uniform Material material;
@@ -25,22 +25,27 @@ uniform Light light;
 
 void main()
 {
-	// ambient
-	vec3 ambient = light.ambient * material.ambient;
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+
+	// ambient is the diffuse color in the dark
+	vec3 ambient = light.ambient * diffuseColor;
 
 	// diffuse
 	vec3 norm = normalize(Normal);
 	vec3 dirToLight = normalize(LightPos - FragPos);
 	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
-	vec3 diffuse = light.diffuse * (material.diffuse * lightNormalDiff);
+	vec3 diffuse = light.diffuse * lightNormalDiff * diffuseColor;
 
 	// specular
 	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
 	vec3 dirToView = normalize(viewPos - FragPos);
 	vec3 reflectDir = reflect(-dirToLight, norm);
 	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
-	vec3 specular = light.specular * (spec * material.specular);
+	vec3 specular = light.specular * spec * vec3(texture(material.specular, TexCoords));
+
+	// emission is not affected by any light
+	vec3 emission = vec3(texture(material.emission, TexCoords));
 
-	vec3 result = diffuse + specular + ambient;
+	vec3 result = diffuse + specular + ambient + emission;
 	color = vec4(result, 1.0f);
 }
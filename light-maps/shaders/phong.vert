@@ -2,6 +2,7 @@
 
 layout (location = 0) in vec3 position;
 layout (location = 1) in vec3 normal;
+layout (location = 2) in vec2 texCoord;
 
 uniform mat4 model;
 uniform mat4 view;
@@ -12,6 +13,7 @@ uniform vec3 lightPos;  // only need one light for a basic example
 out vec3 Normal;
 out vec3 FragPos;
 out vec3 LightPos;
+out vec2 TexCoords;
 
 void main()
 {
@@ -25,6 +27,9 @@ void main()
     // LightPos = vec3(view * vec4(lightPos, 1.0));
     LightPos = vec3(view * vec4(lightPos, 1.0));
 
+    // texture maps are sampled per fragment so just pass the texture position along
+    TexCoords = texCoord;
+
     // transform the normals to the view space
     // this is different from just multiplying by the model then view matrix since
     // normals can't translate and are changed by non-uniform scaling
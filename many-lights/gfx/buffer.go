@@ -0,0 +1,151 @@
+package gfx
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Buffer is a GPU buffer object (VBO, EBO, UBO, ...) that remembers its target,
+// usage hint and allocated size so that it can be updated after creation.
+type Buffer struct {
+	handle uint32
+	target uint32 // same target as gl.BindBuffer(<this param>, ...)
+	usage  uint32 // gl.STATIC_DRAW, gl.DYNAMIC_DRAW or gl.STREAM_DRAW
+	size   int    // size in bytes of the current data store
+	mapped bool
+}
+
+var errBufferOutOfRange = errors.New("buffer range is outside of the allocated data store")
+
+var errBufferMapped = errors.New("buffer is already mapped")
+
+var errBufferNotMapped = errors.New("buffer is not mapped")
+
+var errBufferMapFailed = errors.New("failed to map buffer range")
+
+var errBufferCorrupted = errors.New("buffer contents became corrupt while mapped")
+
+// NewBuffer creates an empty buffer object. Nothing is allocated on the GPU until Data is called.
+func NewBuffer(target, usage uint32) *Buffer {
+	var handle uint32
+	gl.GenBuffers(1, &handle)
+
+	return &Buffer{
+		handle: handle,
+		target: target,
+		usage:  usage,
+	}
+}
+
+// NewBufferWithData creates a buffer and immediately fills it with size bytes from data.
+func NewBufferWithData(target, usage uint32, size int, data unsafe.Pointer) *Buffer {
+	buf := NewBuffer(target, usage)
+	buf.Data(size, data)
+	return buf
+}
+
+func (buf *Buffer) Bind() {
+	gl.BindBuffer(buf.target, buf.handle)
+}
+
+func (buf *Buffer) UnBind() {
+	gl.BindBuffer(buf.target, 0)
+}
+
+// BindBase binds the buffer to an indexed binding point such as a uniform block binding.
+func (buf *Buffer) BindBase(index uint32) {
+	gl.BindBufferBase(buf.target, index, buf.handle)
+}
+
+func (buf *Buffer) Handle() uint32 {
+	return buf.handle
+}
+
+func (buf *Buffer) Size() int {
+	return buf.size
+}
+
+func (buf *Buffer) Delete() {
+	gl.DeleteBuffers(1, &buf.handle)
+}
+
+// Data (re)allocates the data store of the buffer and copies size bytes from data into it.
+// data may be nil to allocate uninitialized storage.
+// The buffer is left bound to its target.
+func (buf *Buffer) Data(size int, data unsafe.Pointer) {
+	buf.Bind()
+	gl.BufferData(buf.target, size, data, buf.usage)
+	buf.size = size
+}
+
+// SubData replaces size bytes of the data store starting at offset without reallocating it.
+// The buffer is left bound to its target.
+func (buf *Buffer) SubData(offset, size int, data unsafe.Pointer) error {
+	if offset < 0 || offset+size > buf.size {
+		return errBufferOutOfRange
+	}
+	buf.Bind()
+	gl.BufferSubData(buf.target, offset, size, data)
+	return nil
+}
+
+// Orphan detaches the current data store and allocates a fresh one of the same size.
+// The driver keeps the old storage alive for draws still in flight so the next upload
+// does not have to wait for the GPU to finish with it.
+func (buf *Buffer) Orphan() {
+	buf.Data(buf.size, nil)
+}
+
+// Stream orphans the buffer when the new data has the same size (or reallocates otherwise)
+// and then uploads data. This is the usual pattern for data rewritten every frame.
+func (buf *Buffer) Stream(size int, data unsafe.Pointer) {
+	if size != buf.size {
+		buf.Data(size, data)
+		return
+	}
+	buf.Orphan()
+	gl.BufferSubData(buf.target, 0, size, data)
+}
+
+// MapRange maps length bytes of the buffer starting at offset into client memory.
+// access is a combination of gl.MAP_* bits, ex: gl.MAP_WRITE_BIT|gl.MAP_UNSYNCHRONIZED_BIT.
+// The returned slice is only valid until Unmap is called.
+func (buf *Buffer) MapRange(offset, length int, access uint32) ([]byte, error) {
+	if buf.mapped {
+		return nil, errBufferMapped
+	}
+	if offset < 0 || length <= 0 || offset+length > buf.size {
+		return nil, errBufferOutOfRange
+	}
+
+	buf.Bind()
+	ptr := gl.MapBufferRange(buf.target, offset, length, access)
+	if ptr == nil {
+		return nil, errBufferMapFailed
+	}
+	buf.mapped = true
+
+	return unsafe.Slice((*byte)(ptr), length), nil
+}
+
+// FlushRange tells GL that a sub-range of a range mapped with gl.MAP_FLUSH_EXPLICIT_BIT
+// has been written. offset is relative to the start of the mapped range.
+func (buf *Buffer) FlushRange(offset, length int) {
+	buf.Bind()
+	gl.FlushMappedBufferRange(buf.target, offset, length)
+}
+
+// Unmap releases a range mapped with MapRange.
+func (buf *Buffer) Unmap() error {
+	if !buf.mapped {
+		return errBufferNotMapped
+	}
+	buf.Bind()
+	buf.mapped = false
+	if !gl.UnmapBuffer(buf.target) {
+		return errBufferCorrupted
+	}
+	return nil
+}
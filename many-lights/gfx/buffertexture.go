@@ -0,0 +1,26 @@
+package gfx
+
+import (
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// NewBufferTexture exposes buf to shaders as a 1D array of texels (a samplerBuffer in GLSL)
+// which can hold far more data than uniforms. format is the sized internal format
+// of each texel, ex: gl.RGBA32F or gl.R32UI. Shaders read it with texelFetch().
+// The buffer can be updated later without touching the texture.
+func NewBufferTexture(buf *Buffer, format uint32) *Texture {
+	var handle uint32
+	gl.GenTextures(1, &handle)
+
+	texture := Texture{
+		handle: handle,
+		target: gl.TEXTURE_BUFFER,
+	}
+
+	texture.Bind(gl.TEXTURE0)
+	defer texture.UnBind()
+
+	gl.TexBuffer(gl.TEXTURE_BUFFER, format, buf.handle)
+
+	return &texture
+}
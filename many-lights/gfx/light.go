@@ -0,0 +1,169 @@
+package gfx
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Light types matching the DirLight, PointLight and SpotLight structs in the phong shaders.
+// Positions and directions are in world space. The shaders light in view space (the viewer is
+// at the origin) so SetUniforms takes the view matrix and converts them before uploading.
+
+// DirectionalLight is infinitely far away (ex: the sun) so only its direction matters.
+type DirectionalLight struct {
+	Direction mgl32.Vec3 // direction the light travels in
+
+	Ambient  mgl32.Vec3
+	Diffuse  mgl32.Vec3
+	Specular mgl32.Vec3
+}
+
+// Attenuation is how quickly a light fades with distance d:
+// intensity = 1 / (Constant + Linear*d + Quadratic*d^2)
+type Attenuation struct {
+	Constant  float32
+	Linear    float32
+	Quadratic float32
+}
+
+// PointLight shines equally in all directions and fades with distance.
+type PointLight struct {
+	Position mgl32.Vec3
+
+	Ambient  mgl32.Vec3
+	Diffuse  mgl32.Vec3
+	Specular mgl32.Vec3
+
+	Attenuation
+}
+
+// SpotLight is a point light restricted to a cone. Fragments inside InnerCutoff are fully lit,
+// outside OuterCutoff are unlit, and the light fades smoothly in between.
+type SpotLight struct {
+	Position  mgl32.Vec3
+	Direction mgl32.Vec3 // direction the cone points in
+
+	InnerCutoff float32 // angle from Direction in degrees
+	OuterCutoff float32 // angle from Direction in degrees, larger than InnerCutoff
+
+	Ambient  mgl32.Vec3
+	Diffuse  mgl32.Vec3
+	Specular mgl32.Vec3
+
+	Attenuation
+}
+
+// values that cover a given distance reasonably, from the Ogre3D wiki
+var attenuationTable = []struct {
+	distance float32
+	Attenuation
+}{
+	{7, Attenuation{1.0, 0.7, 1.8}},
+	{13, Attenuation{1.0, 0.35, 0.44}},
+	{20, Attenuation{1.0, 0.22, 0.20}},
+	{32, Attenuation{1.0, 0.14, 0.07}},
+	{50, Attenuation{1.0, 0.09, 0.032}},
+	{65, Attenuation{1.0, 0.07, 0.017}},
+	{100, Attenuation{1.0, 0.045, 0.0075}},
+	{160, Attenuation{1.0, 0.027, 0.0028}},
+	{200, Attenuation{1.0, 0.022, 0.0019}},
+	{325, Attenuation{1.0, 0.014, 0.0007}},
+	{600, Attenuation{1.0, 0.007, 0.0002}},
+	{3250, Attenuation{1.0, 0.0014, 0.000007}},
+}
+
+// AttenuationForDistance picks attenuation terms for a light that should reach about distance units.
+func AttenuationForDistance(distance float32) Attenuation {
+	for _, entry := range attenuationTable {
+		if distance <= entry.distance {
+			return entry.Attenuation
+		}
+	}
+	return attenuationTable[len(attenuationTable)-1].Attenuation
+}
+
+// At returns the fraction of the light's intensity that remains at distance d.
+func (a Attenuation) At(d float32) float32 {
+	return 1 / (a.Constant + a.Linear*d + a.Quadratic*d*d)
+}
+
+// Range is the distance at which a light of the given intensity (its brightest color channel)
+// has faded to threshold. Past this the light can be ignored, ex: for light culling.
+func (a Attenuation) Range(intensity, threshold float32) float32 {
+	// solve intensity / (c + l*d + q*d^2) = threshold for d
+	c := a.Constant - intensity/threshold
+	if a.Quadratic == 0 {
+		if a.Linear == 0 {
+			return float32(math.Inf(1))
+		}
+		return -c / a.Linear
+	}
+	disc := a.Linear*a.Linear - 4*a.Quadratic*c
+	return (-a.Linear + float32(math.Sqrt(float64(disc)))) / (2 * a.Quadratic)
+}
+
+// lights dimmer than this (in 8-bit color steps) are not visible
+const lightCutoff = 5.0 / 256.0
+
+// Radius is how far the light reaches before it fades out of visibility.
+func (l *PointLight) Radius() float32 {
+	brightest := float32(math.Max(float64(l.Diffuse.X()), math.Max(float64(l.Diffuse.Y()), float64(l.Diffuse.Z()))))
+	if brightest <= lightCutoff {
+		return 0
+	}
+	return l.Attenuation.Range(brightest, lightCutoff)
+}
+
+func (l *DirectionalLight) SetUniforms(prog *Program, name string, view mgl32.Mat4) {
+	setUniformVec3(prog, name+".direction", viewDirection(view, l.Direction))
+	setUniformVec3(prog, name+".ambient", l.Ambient)
+	setUniformVec3(prog, name+".diffuse", l.Diffuse)
+	setUniformVec3(prog, name+".specular", l.Specular)
+}
+
+func (l *PointLight) SetUniforms(prog *Program, name string, view mgl32.Mat4) {
+	setUniformVec3(prog, name+".position", viewPosition(view, l.Position))
+	setUniformVec3(prog, name+".ambient", l.Ambient)
+	setUniformVec3(prog, name+".diffuse", l.Diffuse)
+	setUniformVec3(prog, name+".specular", l.Specular)
+	l.Attenuation.setUniforms(prog, name)
+}
+
+func (l *SpotLight) SetUniforms(prog *Program, name string, view mgl32.Mat4) {
+	setUniformVec3(prog, name+".position", viewPosition(view, l.Position))
+	setUniformVec3(prog, name+".direction", viewDirection(view, l.Direction))
+
+	// the shader compares against the dot product of directions so send cosines instead of angles
+	gl.Uniform1f(prog.GetUniformLocation(name+".innerCutoff"), cosDeg(l.InnerCutoff))
+	gl.Uniform1f(prog.GetUniformLocation(name+".outerCutoff"), cosDeg(l.OuterCutoff))
+
+	setUniformVec3(prog, name+".ambient", l.Ambient)
+	setUniformVec3(prog, name+".diffuse", l.Diffuse)
+	setUniformVec3(prog, name+".specular", l.Specular)
+	l.Attenuation.setUniforms(prog, name)
+}
+
+func (a Attenuation) setUniforms(prog *Program, name string) {
+	gl.Uniform1f(prog.GetUniformLocation(name+".constant"), a.Constant)
+	gl.Uniform1f(prog.GetUniformLocation(name+".linear"), a.Linear)
+	gl.Uniform1f(prog.GetUniformLocation(name+".quadratic"), a.Quadratic)
+}
+
+func setUniformVec3(prog *Program, name string, v mgl32.Vec3) {
+	gl.Uniform3f(prog.GetUniformLocation(name), v.X(), v.Y(), v.Z())
+}
+
+func viewPosition(view mgl32.Mat4, pos mgl32.Vec3) mgl32.Vec3 {
+	return view.Mul4x1(pos.Vec4(1)).Vec3()
+}
+
+// directions ignore the translation part of the view matrix
+func viewDirection(view mgl32.Mat4, dir mgl32.Vec3) mgl32.Vec3 {
+	return view.Mat3().Mul3x1(dir).Normalize()
+}
+
+func cosDeg(deg float32) float32 {
+	return float32(math.Cos(float64(mgl32.DegToRad(deg))))
+}
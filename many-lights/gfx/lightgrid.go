@@ -0,0 +1,239 @@
+package gfx
+
+import (
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// LightGrid does tiled light culling on the CPU.
+//
+// The screen is divided into square tiles and every point light is tested against the camera
+// frustum and assigned to the tiles that its sphere of influence (see PointLight.Radius) covers
+// on screen. The lights and the per tile light lists are uploaded as buffer textures so the
+// fragment shader only loops over the lights affecting the tile it is in.
+//
+// Shader side (see shaders/phong.frag):
+//
+//	samplerBuffer  lightData     3 RGBA32F texels per light: view position + radius,
+//	                             diffuse color, (constant, linear, quadratic, 0) attenuation
+//	usamplerBuffer tileLights    one RG32UI texel per tile: offset into lightIndices, count
+//	usamplerBuffer lightIndices  R32UI light indices for all tiles back to back
+type LightGrid struct {
+	tileSize int
+	tilesX   int
+	tilesY   int
+	width    int
+	height   int
+
+	lightData   *Buffer
+	tileData    *Buffer
+	indexData   *Buffer
+	lightTex    *Texture
+	tileTex     *Texture
+	indexTex    *Texture
+	numLights   int
+	numVisible  int
+	numIndices  int
+	tiles       [][]uint32 // light indices per tile, reused between frames
+	lightFloats []float32
+	tileUints   []uint32
+	indexUints  []uint32
+}
+
+// texels per light in lightData
+const lightGridTexels = 3
+
+// NewLightGrid covers a framebuffer of width x height pixels with tiles of tileSize x tileSize.
+func NewLightGrid(width, height, tileSize int) *LightGrid {
+	g := LightGrid{tileSize: tileSize}
+	g.Resize(width, height)
+
+	// the contents change every frame
+	g.lightData = NewBuffer(gl.TEXTURE_BUFFER, gl.STREAM_DRAW)
+	g.tileData = NewBuffer(gl.TEXTURE_BUFFER, gl.STREAM_DRAW)
+	g.indexData = NewBuffer(gl.TEXTURE_BUFFER, gl.STREAM_DRAW)
+
+	// buffer textures need a data store before they can be attached
+	g.lightData.Data(lightGridTexels*16, nil)
+	g.tileData.Data(len(g.tiles)*8, nil)
+	g.indexData.Data(4, nil)
+	g.indexData.UnBind()
+
+	g.lightTex = NewBufferTexture(g.lightData, gl.RGBA32F)
+	g.tileTex = NewBufferTexture(g.tileData, gl.RG32UI)
+	g.indexTex = NewBufferTexture(g.indexData, gl.R32UI)
+
+	return &g
+}
+
+// Resize changes the size of the screen the tiles cover, it must be the size in pixels of the
+// framebuffer that is drawn to. The tiles are empty until the next Update.
+func (g *LightGrid) Resize(width, height int) {
+	g.width = width
+	g.height = height
+	g.tilesX = (width + g.tileSize - 1) / g.tileSize
+	g.tilesY = (height + g.tileSize - 1) / g.tileSize
+	g.tiles = make([][]uint32, g.tilesX*g.tilesY)
+}
+
+func (g *LightGrid) TileSize() int {
+	return g.tileSize
+}
+
+// NumVisible is the number of lights that touched at least one tile in the last Update.
+func (g *LightGrid) NumVisible() int {
+	return g.numVisible
+}
+
+// AverageLightsPerTile is how many lights a fragment had to shade on average in the last Update.
+func (g *LightGrid) AverageLightsPerTile() float32 {
+	return float32(g.numIndices) / float32(len(g.tiles))
+}
+
+// Update culls the lights against the view frustum, assigns them to tiles and uploads the result.
+// view and project must be the same matrices used for drawing.
+func (g *LightGrid) Update(lights []PointLight, view, project mgl32.Mat4) {
+	for i := range g.tiles {
+		g.tiles[i] = g.tiles[i][:0]
+	}
+	g.lightFloats = g.lightFloats[:0]
+	g.numLights = len(lights)
+	g.numVisible = 0
+
+	near, far := perspectiveNearFar(project)
+
+	for i := range lights {
+		light := &lights[i]
+		pos := viewPosition(view, light.Position)
+		radius := light.Radius()
+
+		g.lightFloats = append(g.lightFloats,
+			pos.X(), pos.Y(), pos.Z(), radius,
+			light.Diffuse.X(), light.Diffuse.Y(), light.Diffuse.Z(), 0,
+			light.Constant, light.Linear, light.Quadratic, 0,
+		)
+
+		minX, minY, maxX, maxY, visible := g.tileBounds(pos, radius, project, near, far)
+		if !visible {
+			continue
+		}
+		g.numVisible++
+		for ty := minY; ty <= maxY; ty++ {
+			for tx := minX; tx <= maxX; tx++ {
+				tile := ty*g.tilesX + tx
+				g.tiles[tile] = append(g.tiles[tile], uint32(i))
+			}
+		}
+	}
+
+	// flatten the per tile lists into one index list
+	g.tileUints = g.tileUints[:0]
+	g.indexUints = g.indexUints[:0]
+	for _, tile := range g.tiles {
+		g.tileUints = append(g.tileUints, uint32(len(g.indexUints)), uint32(len(tile)))
+		g.indexUints = append(g.indexUints, tile...)
+	}
+	g.numIndices = len(g.indexUints)
+
+	if len(g.lightFloats) > 0 {
+		g.lightData.Stream(len(g.lightFloats)*4, gl.Ptr(g.lightFloats))
+	}
+	g.tileData.Stream(len(g.tileUints)*4, gl.Ptr(g.tileUints))
+	if len(g.indexUints) > 0 {
+		g.indexData.Stream(len(g.indexUints)*4, gl.Ptr(g.indexUints))
+	}
+	g.indexData.UnBind()
+}
+
+// tileBounds finds the range of tiles covered by a sphere in view space.
+// The screen rectangle is conservative: it is the projection of the box around the sphere.
+func (g *LightGrid) tileBounds(center mgl32.Vec3, radius float32, project mgl32.Mat4,
+	near, far float32) (minX, minY, maxX, maxY int, visible bool) {
+
+	// the camera looks down -z in view space
+	if center.Z()-radius > -near || center.Z()+radius < -far {
+		return 0, 0, 0, 0, false
+	}
+
+	// the sphere crosses the near plane so it can cover any part of the screen
+	if center.Z()+radius > -near {
+		return 0, 0, g.tilesX - 1, g.tilesY - 1, true
+	}
+
+	ndcMin := mgl32.Vec2{math.MaxFloat32, math.MaxFloat32}
+	ndcMax := mgl32.Vec2{-math.MaxFloat32, -math.MaxFloat32}
+	for _, corner := range [8]mgl32.Vec3{
+		{-1, -1, -1}, {1, -1, -1}, {-1, 1, -1}, {1, 1, -1},
+		{-1, -1, 1}, {1, -1, 1}, {-1, 1, 1}, {1, 1, 1},
+	} {
+		clip := project.Mul4x1(center.Add(corner.Mul(radius)).Vec4(1))
+		ndc := mgl32.Vec2{clip.X() / clip.W(), clip.Y() / clip.W()}
+		for axis := 0; axis < 2; axis++ {
+			if ndc[axis] < ndcMin[axis] {
+				ndcMin[axis] = ndc[axis]
+			}
+			if ndc[axis] > ndcMax[axis] {
+				ndcMax[axis] = ndc[axis]
+			}
+		}
+	}
+
+	// off to the side of the view frustum
+	if ndcMax.X() < -1 || ndcMax.Y() < -1 || ndcMin.X() > 1 || ndcMin.Y() > 1 {
+		return 0, 0, 0, 0, false
+	}
+
+	// NDC [-1, 1] to tiles, y goes up like gl_FragCoord
+	toTile := func(ndc float32, pixels, tiles int) int {
+		pixel := (ndc*0.5 + 0.5) * float32(pixels)
+		tile := int(math.Floor(float64(pixel))) / g.tileSize
+		if tile < 0 {
+			return 0
+		}
+		if tile >= tiles {
+			return tiles - 1
+		}
+		return tile
+	}
+
+	return toTile(ndcMin.X(), g.width, g.tilesX), toTile(ndcMin.Y(), g.height, g.tilesY),
+		toTile(ndcMax.X(), g.width, g.tilesX), toTile(ndcMax.Y(), g.height, g.tilesY), true
+}
+
+// perspectiveNearFar recovers the clipping planes from a matrix made by mgl32.Perspective
+func perspectiveNearFar(project mgl32.Mat4) (float32, float32) {
+	m22, m23 := project.At(2, 2), project.At(2, 3)
+	return m23 / (m22 - 1), m23 / (m22 + 1)
+}
+
+// Bind binds the grid's buffer textures to three texture units starting at firstUnit
+// and points the shader's samplers at them.
+func (g *LightGrid) Bind(prog *Program, firstUnit uint32) {
+	g.lightTex.Bind(firstUnit)
+	g.lightTex.SetUniform(prog.GetUniformLocation("lightData"))
+	g.tileTex.Bind(firstUnit + 1)
+	g.tileTex.SetUniform(prog.GetUniformLocation("tileLights"))
+	g.indexTex.Bind(firstUnit + 2)
+	g.indexTex.SetUniform(prog.GetUniformLocation("lightIndices"))
+
+	gl.Uniform1i(prog.GetUniformLocation("numLights"), int32(g.numLights))
+	gl.Uniform1i(prog.GetUniformLocation("tileSize"), int32(g.tileSize))
+	gl.Uniform1i(prog.GetUniformLocation("tilesX"), int32(g.tilesX))
+}
+
+func (g *LightGrid) UnBind() {
+	g.lightTex.UnBind()
+	g.tileTex.UnBind()
+	g.indexTex.UnBind()
+}
+
+func (g *LightGrid) Delete() {
+	g.lightTex.Delete()
+	g.tileTex.Delete()
+	g.indexTex.Delete()
+	g.lightData.Delete()
+	g.tileData.Delete()
+	g.indexData.Delete()
+}
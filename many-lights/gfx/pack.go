@@ -0,0 +1,127 @@
+package gfx
+
+import (
+	"math"
+)
+
+// Conversions between 32-bit floats and the compact formats accepted by VertexAttrib.
+// These are plain Go so vertex data can be packed offline or on any thread.
+
+// Float32ToHalf converts f to an IEEE 754 half precision float, rounding to nearest even.
+// Values too large for a half become +/-Inf.
+func Float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00 // Inf
+	}
+
+	// re-bias the exponent from float32 (127) to half (15)
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+
+	if e <= 0 {
+		// too small for a normal half, either a subnormal or zero
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000 // implicit leading 1
+		shift := uint32(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	// a carry out of the mantissa correctly bumps the exponent (up to Inf)
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// HalfToFloat32 converts an IEEE 754 half precision float to a float32. This is exact.
+func HalfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// subnormal half, normalize it since float32 has the range for it
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	}
+
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// PackUnorm8 maps f in [0, 1] to [0, 255] the way GL expects for normalized unsigned bytes.
+func PackUnorm8(f float32) uint8 {
+	return uint8(clamp(f, 0, 1)*255 + 0.5)
+}
+
+func UnpackUnorm8(b uint8) float32 {
+	return float32(b) / 255
+}
+
+// PackInt2101010Rev packs x, y, z into signed normalized 10 bit fields and w into a
+// signed normalized 2 bit field for gl.INT_2_10_10_10_REV (x in the lowest bits).
+// Uses the GL 4.2+ conversion (c / (2^(b-1) - 1)) which drivers use for 4.1 contexts as well.
+func PackInt2101010Rev(x, y, z, w float32) uint32 {
+	return packSnorm(x, 10) | packSnorm(y, 10)<<10 | packSnorm(z, 10)<<20 | packSnorm(w, 2)<<30
+}
+
+// UnpackInt2101010Rev is the inverse of PackInt2101010Rev.
+func UnpackInt2101010Rev(v uint32) (x, y, z, w float32) {
+	return unpackSnorm(v, 10), unpackSnorm(v>>10, 10), unpackSnorm(v>>20, 10), unpackSnorm(v>>30, 2)
+}
+
+func packSnorm(f float32, bits uint) uint32 {
+	max := float32(int32(1)<<(bits-1) - 1)
+	c := int32(math.Floor(float64(clamp(f, -1, 1)*max) + 0.5))
+	return uint32(c) & (1<<bits - 1)
+}
+
+func unpackSnorm(v uint32, bits uint) float32 {
+	// move the field to the top of the int so the shift back down sign extends it
+	c := int32(v<<(32-bits)) >> (32 - bits)
+	max := float32(int32(1)<<(bits-1) - 1)
+	if f := float32(c) / max; f > -1 {
+		return f
+	}
+	return -1
+}
+
+func clamp(f, low, high float32) float32 {
+	if f < low {
+		return low
+	}
+	if f > high {
+		return high
+	}
+	return f
+}
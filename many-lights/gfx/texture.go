@@ -0,0 +1,139 @@
+package gfx
+
+import (
+	"os"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/png"
+	_ "image/jpeg"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+type Texture struct {
+	handle uint32
+	target uint32  // same target as gl.BindTexture(<this param>, ...)
+	texUnit uint32 // Texture unit that is currently bound to ex: gl.TEXTURE0
+}
+
+var errUnsupportedStride = errors.New("unsupported stride, only 32-bit colors supported")
+
+var errTextureNotBound = errors.New("texture not bound")
+
+func NewTextureFromFile(file string, wrapR, wrapS int32) (*Texture, error) {
+	imgFile, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer imgFile.Close()
+
+	// Decode detexts the type of image as long as its image/<type> is imported
+	img, _, err := image.Decode(imgFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewTexture(img, wrapR, wrapS)
+}
+
+// NewLinearTextureFromFile is like NewTextureFromFile but for images holding data
+// rather than colors, see NewLinearTexture.
+func NewLinearTextureFromFile(file string, wrapR, wrapS int32) (*Texture, error) {
+	img, err := loadImageFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewLinearTexture(img, wrapR, wrapS)
+}
+
+// NewTexture creates a texture for color images which are stored in sRGB space
+// so they are converted to linear values when sampled.
+func NewTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
+	return newTexture(img, gl.SRGB_ALPHA, wrapR, wrapS)
+}
+
+// NewLinearTexture creates a texture whose texels are sampled as is (no sRGB conversion).
+// Use it for non-color data such as normal maps.
+func NewLinearTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
+	return newTexture(img, gl.RGBA8, wrapR, wrapS)
+}
+
+func newTexture(img image.Image, internalFmt int32, wrapR, wrapS int32) (*Texture, error) {
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, image.Pt(0, 0), draw.Src)
+	if rgba.Stride != rgba.Rect.Size().X*4 {  // TODO-cs: why?
+		return nil, errUnsupportedStride
+	}
+
+	var handle uint32
+	gl.GenTextures(1, &handle)
+
+	target      := uint32(gl.TEXTURE_2D)
+	format      := uint32(gl.RGBA)
+	width       := int32(rgba.Rect.Size().X)
+	height      := int32(rgba.Rect.Size().Y)
+	pixType     := uint32(gl.UNSIGNED_BYTE)
+	dataPtr     := gl.Ptr(rgba.Pix)
+
+	texture := Texture{
+		handle:handle,
+		target:target,
+	}
+
+	texture.Bind(gl.TEXTURE0)
+	defer texture.UnBind()
+
+	// set the texture wrapping/filtering options (applies to current bound texture obj)
+	// TODO-cs
+	gl.TexParameteri(texture.target, gl.TEXTURE_WRAP_R, wrapR)
+	gl.TexParameteri(texture.target, gl.TEXTURE_WRAP_S, wrapS)
+	gl.TexParameteri(texture.target, gl.TEXTURE_MIN_FILTER, gl.LINEAR)  // minification filter
+	gl.TexParameteri(texture.target, gl.TEXTURE_MAG_FILTER, gl.LINEAR)  // magnification filter
+
+
+	gl.TexImage2D(target, 0, internalFmt, width, height, 0, format, pixType, dataPtr)
+
+	gl.GenerateMipmap(texture.handle)
+
+	return &texture, nil
+}
+
+func (tex *Texture) Bind(texUnit uint32) {
+	gl.ActiveTexture(texUnit)
+	gl.BindTexture(tex.target, tex.handle)
+	tex.texUnit = texUnit
+}
+
+func (tex *Texture) UnBind() {
+	// unbind from the unit this texture was bound to, not whichever unit happens to be active
+	if tex.texUnit != 0 {
+		gl.ActiveTexture(tex.texUnit)
+	}
+	tex.texUnit = 0
+	gl.BindTexture(tex.target, 0)
+}
+
+func (tex *Texture) Delete() {
+	gl.DeleteTextures(1, &tex.handle)
+}
+
+func (tex *Texture) SetUniform(uniformLoc int32) error {
+	if tex.texUnit == 0 {
+		return errTextureNotBound
+	}
+	gl.Uniform1i(uniformLoc, int32(tex.texUnit - gl.TEXTURE0))
+	return nil
+}
+
+func loadImageFile(file string) (image.Image, error) {
+	infile, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer infile.Close()
+
+	// Decode automatically figures out the type of immage in the file
+	// as long as its image/<type> is imported
+	img, _, err := image.Decode(infile)
+	return img, err
+}
@@ -0,0 +1,379 @@
+package main
+
+/*
+Builds on light-casters, going from a handful of point lights to hundreds.
+
+Lights are culled on the CPU against the camera frustum and assigned to screen tiles
+(see gfx/lightgrid.go). The fragment shader then only shades the lights for its tile.
+Press C to toggle culling and compare frame times with shading every light everywhere.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/many-lights/gfx"
+	"github.com/cstegel/opengl-samples-golang/many-lights/win"
+	"github.com/cstegel/opengl-samples-golang/many-lights/cam"
+)
+
+// vertices to draw 6 faces of a cube
+var cubeVertices = []float32{
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+}
+
+var cubePositions = [][]float32 {
+	{ 0.0,  0.0,  -3.0},
+	{ 2.0,  5.0, -15.0},
+	{-1.5, -2.2, -2.5 },
+	{-3.8, -2.0, -12.3},
+	{ 2.4, -0.4, -3.5 },
+	{-1.7,  3.0, -7.5 },
+	{ 1.3, -2.0, -2.5 },
+	{ 1.5,  2.0, -2.5 },
+	{ 1.5,  0.2, -1.5 },
+	{-1.3,  1.0, -1.5 },
+}
+
+// number of point lights orbiting around the cubes
+const numLights = 256
+
+// size in pixels of the square screen tiles used for light culling
+const tileSize = 16
+
+// lightOrbit describes how one light moves around its cube
+type lightOrbit struct {
+	center mgl32.Vec3
+	radius float32
+	height float32
+	speed  float32
+	phase  float32
+}
+
+/*
+ * Spreads lights evenly over the cubes, each one circling its cube
+ * at a different distance, height and speed with a color from around the color wheel.
+ */
+func makeLights() ([]gfx.PointLight, []lightOrbit) {
+	lights := make([]gfx.PointLight, numLights)
+	orbits := make([]lightOrbit, numLights)
+
+	for i := range lights {
+		pos := cubePositions[i%len(cubePositions)]
+		t := float32(i) / float32(numLights)
+
+		orbits[i] = lightOrbit{
+			center: mgl32.Vec3{pos[0], pos[1], pos[2]},
+			radius: 0.9 + 1.5*float32(math.Mod(float64(t*7.3), 1)),
+			height: -1.0 + 2.0*float32(math.Mod(float64(t*13.7), 1)),
+			speed:  0.3 + 0.7*float32(math.Mod(float64(t*3.1), 1)),
+			phase:  2 * math.Pi * t * 5,
+		}
+
+		color := hueToRGB(t * 3).Mul(0.6)
+		lights[i] = gfx.PointLight{
+			Diffuse:     color,
+			Specular:    color,
+			Attenuation: gfx.AttenuationForDistance(7),
+		}
+	}
+
+	return lights, orbits
+}
+
+// hueToRGB converts a hue in [0, 1) (wrapping) to a fully saturated color
+func hueToRGB(hue float32) mgl32.Vec3 {
+	h := float64(hue) - math.Floor(float64(hue))
+	r := math.Abs(h*6-3) - 1
+	g := 2 - math.Abs(h*6-2)
+	b := 2 - math.Abs(h*6-4)
+	return mgl32.Vec3{
+		mgl32.Clamp(float32(r), 0, 1),
+		mgl32.Clamp(float32(g), 0, 1),
+		mgl32.Clamp(float32(b), 0, 1),
+	}
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Many lights")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+ * Creates the Vertex Array Object for a triangle.
+ * indices is leftover from earlier samples and not used here.
+ */
+func createVAO(vertices []float32, indices []uint32) uint32 {
+
+	var VAO uint32
+	gl.GenVertexArrays(1, &VAO)
+
+	// Bind the Vertex Array Object first, then bind and set vertex buffer(s) and attribute pointers()
+	gl.BindVertexArray(VAO)
+
+	// copy vertices data into VBO (it is left bound for the attribute pointers below)
+	gfx.NewBufferWithData(gl.ARRAY_BUFFER, gl.STATIC_DRAW, len(vertices)*4, gl.Ptr(vertices))
+
+	// position, normal, texture position
+	layout := gfx.NewVertexLayout(gfx.FloatAttrib(0, 3), gfx.FloatAttrib(1, 3), gfx.FloatAttrib(2, 2))
+	layout.Enable()
+
+	// unbind the VAO (safe practice so we don't accidentally (mis)configure it later)
+	gl.BindVertexArray(0)
+
+	return VAO
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+
+	VAO := createVAO(cubeVertices, nil)
+	lightVAO := createVAO(cubeVertices, nil)
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	lights, orbits := makeLights()
+
+	// tiles are in pixels, which are smaller than the window's units on high DPI screens
+	lightGrid := gfx.NewLightGrid(window.FramebufferWidth(), window.FramebufferHeight(), tileSize)
+	defer lightGrid.Delete()
+	cullLights := true
+
+	// frame time is averaged and shown in the title once per second
+	frames := 0
+	frameTime := 0.0
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		if window.FramebufferResized() {
+			lightGrid.Resize(window.FramebufferWidth(), window.FramebufferHeight())
+		}
+
+		if window.InputManager().IsTriggered(win.TOGGLE_LIGHT_CULLING) {
+			cullLights = !cullLights
+		}
+
+		frames++
+		frameTime += window.SinceLastFrame()
+		if frameTime >= 1.0 {
+			window.SetTitle(fmt.Sprintf("Many lights - %d lights, %d visible, %.1f per tile, culling %v - %.2f ms/frame",
+				len(lights), lightGrid.NumVisible(), lightGrid.AverageLightsPerTile(), cullLights,
+				1000*frameTime/float64(frames)))
+			frames = 0
+			frameTime = 0
+		}
+
+		// move the lights along their orbits
+		time := float32(glfw.GetTime())
+		for i, orbit := range orbits {
+			angle := orbit.phase + orbit.speed*time
+			lights[i].Position = orbit.center.Add(mgl32.Vec3{
+				orbit.radius * float32(math.Cos(float64(angle))),
+				orbit.height,
+				orbit.radius * float32(math.Sin(float64(angle))),
+			})
+		}
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)  // depth buffer needed for DEPTH_TEST
+
+
+		// cube rotation matrices
+		rotateX   := (mgl32.Rotate3DX(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+		rotateY   := (mgl32.Rotate3DY(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+		rotateZ   := (mgl32.Rotate3DZ(mgl32.DegToRad(-45 * float32(glfw.GetTime()))))
+
+		// creates perspective
+		fov := float32(60.0)
+		projectTransform := mgl32.Perspective(mgl32.DegToRad(fov),
+		                                      float32(window.Width())/float32(window.Height()),
+		                                      0.1,
+		                                      100.0)
+
+		camTransform := camera.GetTransform()
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false,
+		                    &projectTransform[0])
+
+		gl.BindVertexArray(VAO)
+
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		// assign the lights to screen tiles using the same transforms as the draw
+		lightGrid.Update(lights, camTransform, projectTransform)
+		lightGrid.Bind(program, gl.TEXTURE2)
+		gl.Uniform1i(program.GetUniformLocation("cullLights"), boolToInt(cullLights))
+		gl.Uniform3f(program.GetUniformLocation("ambientLight"), 0.05, 0.05, 0.05)
+
+		for _, pos := range cubePositions {
+
+			worldTranslate := mgl32.Translate3D(pos[0], pos[1], pos[2])
+			worldTransform := worldTranslate.Mul4(
+				rotateX.Mul3(rotateY).Mul3(rotateZ).Mat4(),
+			)
+
+			gl.UniformMatrix4fv(program.GetUniformLocation("model"), 1, false,
+			                    &worldTransform[0])
+
+			gl.DrawArrays(gl.TRIANGLES, 0, 36)
+		}
+		gl.BindVertexArray(0)
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+		lightGrid.UnBind()
+
+		// Draw the point lights after the other boxes using their separate shader program
+		// this means that we must re-bind any uniforms
+		lightProgram.Use()
+		gl.BindVertexArray(lightVAO)
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		for _, light := range lights {
+			lightTransform := mgl32.Translate3D(light.Position.X(), light.Position.Y(), light.Position.Z()).Mul4(
+			                                    mgl32.Scale3D(0.05, 0.05, 0.05))
+			gl.UniformMatrix4fv(lightProgram.GetUniformLocation("model"), 1, false, &lightTransform[0])
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"),
+			             light.Diffuse.X(), light.Diffuse.Y(), light.Diffuse.Z())
+			gl.DrawArrays(gl.TRIANGLES, 0, 36)
+		}
+
+		gl.BindVertexArray(0)
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+#version 410 core
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D specular;
+	float shininess;
+};
+
+in vec3 Normal;
+in vec3 FragPos;
+in vec2 TexCoords;
+out vec4 color;
+
+uniform Material material;
+uniform vec3 ambientLight;
+
+// point lights and the tiles they affect, see gfx/lightgrid.go for the layout
+uniform samplerBuffer lightData;
+uniform usamplerBuffer tileLights;
+uniform usamplerBuffer lightIndices;
+uniform int numLights;
+uniform int tileSize;
+uniform int tilesX;
+
+// false shades every light for every fragment so the cost of not culling can be compared
+uniform bool cullLights;
+
+vec3 calcPointLight(int light, vec3 norm, vec3 dirToView, vec3 diffuseColor, vec3 specularColor)
+{
+	vec4 posRadius = texelFetch(lightData, light * 3);
+	vec3 lightColor = texelFetch(lightData, light * 3 + 1).rgb;
+	vec3 atten = texelFetch(lightData, light * 3 + 2).xyz;
+
+	vec3 toLight = posRadius.xyz - FragPos;
+	float dist = length(toLight);
+	if (dist > posRadius.w) {
+		return vec3(0.0);
+	}
+	vec3 dirToLight = toLight / dist;
+
+	// attenuation never reaches 0 so window it to reach 0 at the culling radius,
+	// otherwise the edges of the tiles would show up as seams
+	float decay = 1.0 / (atten.x + atten.y * dist + atten.z * dist * dist);
+	float window = clamp(1.0 - pow(dist / posRadius.w, 4.0), 0.0, 1.0);
+	decay *= window * window;
+
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = lightColor * lightNormalDiff * diffuseColor;
+
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = lightColor * spec * specularColor;
+
+	return decay * (diffuse + specular);
+}
+
+void main()
+{
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 specularColor = vec3(texture(material.specular, TexCoords));
+
+	vec3 norm = normalize(Normal);
+	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
+	vec3 dirToView = normalize(viewPos - FragPos);
+
+	vec3 result = ambientLight * diffuseColor;
+
+	if (cullLights) {
+		// gl_FragCoord starts at the bottom left like the tiles
+		ivec2 tile = ivec2(gl_FragCoord.xy) / tileSize;
+		uvec2 range = texelFetch(tileLights, tile.y * tilesX + tile.x).xy;
+		for (uint i = 0u; i < range.y; i++) {
+			int light = int(texelFetch(lightIndices, int(range.x + i)).r);
+			result += calcPointLight(light, norm, dirToView, diffuseColor, specularColor);
+		}
+	} else {
+		for (int light = 0; light < numLights; light++) {
+			result += calcPointLight(light, norm, dirToView, diffuseColor, specularColor);
+		}
+	}
+
+	color = vec4(result, 1.0f);
+}
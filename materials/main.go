@@ -3,11 +3,13 @@ package main
 /*
 http://www.learnopengl.com/#!Lighting/Materials
 
-Shows basic materials with phong lighting
+Shows basic materials with phong lighting.
+Materials are loaded from materials.json, see gfx.Material for the format.
 */
 
 import (
 	"log"
+	"sort"
 	"runtime"
 	"math"
 
@@ -22,48 +24,48 @@ import (
 
 // vertices to draw 6 faces of a cube
 var cubeVertices = []float32{
-	// position        // normal vector
-	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,
-	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,
-
-	-0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	 0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	-0.5,  0.5,  0.5,  0.0,  0.0, 1.0,
-	-0.5, -0.5,  0.5,  0.0,  0.0, 1.0,
-
-	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,
-	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,
-	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,
-	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,
-
-	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,
-	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,
-	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,
-	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,
-
-	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,
-	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,
-
-	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,
-	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
 }
 
 var cubePositions = [][]float32 {
@@ -132,7 +134,7 @@ func createVAO(vertices []float32, indices []uint32) uint32 {
 	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
 
 	// size of one whole vertex (sum of attrib sizes)
-	var stride int32 = 3*4 + 3*4
+	var stride int32 = 3*4 + 3*4 + 2*4
 	var offset int = 0
 
 	// position
@@ -145,6 +147,11 @@ func createVAO(vertices []float32, indices []uint32) uint32 {
 	gl.EnableVertexAttribArray(1)
 	offset += 3*4
 
+	// texture position
+	gl.VertexAttribPointer(2, 2, gl.FLOAT, false, stride, gl.PtrOffset(offset))
+	gl.EnableVertexAttribArray(2)
+	offset += 2*4
+
 	// unbind the VAO (safe practice so we don't accidentally (mis)configure it later)
 	gl.BindVertexArray(0)
 
@@ -181,6 +188,18 @@ func programLoop(window *win.Window) error {
 		return err
 	}
 
+	materials, err := gfx.LoadMaterialLibrary("materials.json")
+	if err != nil {
+		return err
+	}
+
+	// give each cube a different material, map order is random so sort by name
+	var materialNames []string
+	for name := range materials {
+		materialNames = append(materialNames, name)
+	}
+	sort.Strings(materialNames)
+
 	VAO := createVAO(cubeVertices, nil)
 	lightVAO := createVAO(cubeVertices, nil)
 
@@ -228,12 +247,6 @@ func programLoop(window *win.Window) error {
 
 		// draw each cube after all coordinate system transforms are bound
 
-		// obj is colored, light is white
-		gl.Uniform3f(program.GetUniformLocation("material.ambient"), 1.0, 0.5, 0.31)
-		gl.Uniform3f(program.GetUniformLocation("material.diffuse"), 1.0, 0.5, 0.31)
-		gl.Uniform3f(program.GetUniformLocation("material.specular"), 0.5, 0.5, 0.5)
-		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
-
 		lightColor := mgl32.Vec3{
 			float32(math.Sin(glfw.GetTime() * 1)),
 			float32(math.Sin(glfw.GetTime() * 0.35)),
@@ -256,9 +269,14 @@ func programLoop(window *win.Window) error {
 		gl.Uniform3f(program.GetUniformLocation("light.diffuse"),
 		             diffuseColor[0], diffuseColor[1], diffuseColor[2])
 		gl.Uniform3f(program.GetUniformLocation("light.specular"), 1.0, 1.0, 1.0)
-		gl.Uniform3f(program.GetUniformLocation("light.position"), lightPos.X(), lightPos.Y(), lightPos.Z())
+		gl.Uniform3f(program.GetUniformLocation("lightPos"), lightPos.X(), lightPos.Y(), lightPos.Z())
+
+		for i, pos := range cubePositions {
 
-		for _, pos := range cubePositions {
+			mat := materials[materialNames[i%len(materialNames)]]
+			if err := mat.Bind(program, "material"); err != nil {
+				return err
+			}
 
 			// turn the cubes into rectangular prisms for more fun
 			worldTranslate := mgl32.Translate3D(pos[0], pos[1], pos[2])
@@ -270,6 +288,7 @@ func programLoop(window *win.Window) error {
 			                    &worldTransform[0])
 
 			gl.DrawArrays(gl.TRIANGLES, 0, 36)
+			mat.UnBind()
 		}
 		gl.BindVertexArray(0)
 
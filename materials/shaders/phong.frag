@@ -5,6 +5,12 @@ struct Material {
 	vec3 diffuse;
 	vec3 specular;
 	float shininess;
+
+	// when a map is present it replaces the color(s) above
+	bool hasDiffuseMap;
+	bool hasSpecularMap;
+	sampler2D diffuseMap;
+	sampler2D specularMap;
 };
 
 struct Light {
@@ -18,6 +24,7 @@ struct Light {
 in vec3 Normal;
 in vec3 FragPos;
 in vec3 LightPos;
+in vec2 TexCoords;
 out vec4 color;
 
 uniform Material material;
@@ -25,21 +32,32 @@ uniform Light light;
 
 void main()
 {
+	vec3 matAmbient = material.ambient;
+	vec3 matDiffuse = material.diffuse;
+	vec3 matSpecular = material.specular;
+	if (material.hasDiffuseMap) {
+		matDiffuse = vec3(texture(material.diffuseMap, TexCoords));
+		matAmbient = matDiffuse;
+	}
+	if (material.hasSpecularMap) {
+		matSpecular = vec3(texture(material.specularMap, TexCoords));
+	}
+
 	// ambient
-	vec3 ambient = light.ambient * material.ambient;
+	vec3 ambient = light.ambient * matAmbient;
 
 	// diffuse
 	vec3 norm = normalize(Normal);
 	vec3 dirToLight = normalize(LightPos - FragPos);
 	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
-	vec3 diffuse = light.diffuse * (material.diffuse * lightNormalDiff);
+	vec3 diffuse = light.diffuse * (matDiffuse * lightNormalDiff);
 
 	// specular
 	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
 	vec3 dirToView = normalize(viewPos - FragPos);
 	vec3 reflectDir = reflect(-dirToLight, norm);
 	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
-	vec3 specular = light.specular * (spec * material.specular);
+	vec3 specular = light.specular * (spec * matSpecular);
 
 	vec3 result = diffuse + specular + ambient;
 	color = vec4(result, 1.0f);
@@ -14,12 +14,17 @@ const (
 	PLAYER_LEFT Action = iota
 	PLAYER_RIGHT Action = iota
 	PROGRAM_QUIT Action = iota
+	TOGGLE_GAMMA_CORRECTION Action = iota
 )
 
 type InputManager struct {
 	actionToKeyMap map[Action]glfw.Key
 	keysPressed [glfw.KeyLast]bool
 
+	// presses since the last checkpoint, see CheckpointKeys()
+	keysTriggered [glfw.KeyLast]bool
+	bufferedKeysTriggered [glfw.KeyLast]bool
+
 	firstCursorAction bool
 	cursor mgl64.Vec2
 	cursorChange mgl64.Vec2
@@ -34,6 +39,7 @@ func NewInputManager() *InputManager {
 		PLAYER_LEFT: glfw.KeyA,
 		PLAYER_RIGHT: glfw.KeyD,
 		PROGRAM_QUIT: glfw.KeyEscape,
+		TOGGLE_GAMMA_CORRECTION: glfw.KeyG,
 	}
 
 	return &InputManager{
@@ -47,6 +53,20 @@ func (im *InputManager) IsActive(a Action) bool {
 	return im.keysPressed[im.actionToKeyMap[a]]
 }
 
+// IsTriggered returns whether the key for the given Action was pressed down
+// between the last two calls to CheckpointKeys(). Unlike IsActive this is only true
+// for one frame per press which is what toggles need.
+func (im *InputManager) IsTriggered(a Action) bool {
+	return im.keysTriggered[im.actionToKeyMap[a]]
+}
+
+// CheckpointKeys updates the publicly available IsTriggered() method to report
+// the key presses since the last time this method was called.
+func (im *InputManager) CheckpointKeys() {
+	im.keysTriggered = im.bufferedKeysTriggered
+	im.bufferedKeysTriggered = [glfw.KeyLast]bool{}
+}
+
 // Cursor returns the value of the cursor at the last time that CheckpointCursorChange() was called.
 func (im *InputManager) Cursor() mgl64.Vec2 {
 	return im.cursor
@@ -78,6 +98,7 @@ func (im *InputManager) keyCallback(window *glfw.Window, key glfw.Key, scancode
 	switch action {
 	case glfw.Press:
 		im.keysPressed[key] = true
+		im.bufferedKeysTriggered[key] = true
 	case glfw.Release:
 		im.keysPressed[key] = false
 	}
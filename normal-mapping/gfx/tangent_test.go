@@ -0,0 +1,148 @@
+package gfx
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func vecNear(a, b mgl32.Vec3) bool {
+	return a.Sub(b).Len() < 1e-5
+}
+
+// quad is one face of a mesh, u and v are the directions the texture's u and v go in and
+// u x v is the face's normal so its triangles wind counter-clockwise seen from the front
+type quad struct {
+	name string
+	u, v mgl32.Vec3
+}
+
+// cubeFaces wraps the texture around the cube the same way on every face
+var cubeFaces = []quad{
+	{"front", mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 1, 0}},
+	{"back", mgl32.Vec3{-1, 0, 0}, mgl32.Vec3{0, 1, 0}},
+	{"right", mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, 1, 0}},
+	{"left", mgl32.Vec3{0, 0, 1}, mgl32.Vec3{0, 1, 0}},
+	{"top", mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, -1}},
+	{"bottom", mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, 0, 1}},
+}
+
+// addQuad appends a unit face around u x v as two unindexed triangles. mirrored flips the
+// texture horizontally, so u goes the other way across the face.
+func addQuad(m *Mesh, q quad, mirrored bool) {
+	n := q.u.Cross(q.v)
+	corners := [4]mgl32.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	for _, c := range [6]int{0, 1, 2, 0, 2, 3} {
+		uv := corners[c]
+		pos := n.Add(q.u.Mul(2*uv.X() - 1)).Add(q.v.Mul(2*uv.Y() - 1))
+		if mirrored {
+			uv[0] = 1 - uv[0]
+		}
+		m.Positions = append(m.Positions, pos)
+		m.Normals = append(m.Normals, n)
+		m.UVs = append(m.UVs, uv)
+	}
+}
+
+func TestGenerateTangentsCube(t *testing.T) {
+	for _, mirrored := range []bool{false, true} {
+		m := &Mesh{}
+		for _, face := range cubeFaces {
+			addQuad(m, face, mirrored)
+		}
+		if err := m.GenerateTangents(); err != nil {
+			t.Fatal(err)
+		}
+
+		// the tangent follows +u, when the texture is mirrored that is the other way across
+		// the face and the bitangent needs the handedness flipped to still follow +v
+		wantW := float32(1)
+		if mirrored {
+			wantW = -1
+		}
+		for f, face := range cubeFaces {
+			wantTangent := face.u
+			if mirrored {
+				wantTangent = face.u.Mul(-1)
+			}
+			for i := 6 * f; i < 6*(f+1); i++ {
+				tangent := m.Tangents[i]
+				if !vecNear(tangent.Vec3(), wantTangent) || tangent.W() != wantW {
+					t.Errorf("%s face (mirrored %v): vertex %d tangent is %v, want %v with w %v",
+						face.name, mirrored, i, tangent, wantTangent, wantW)
+				}
+				if b := m.Bitangent(i); !vecNear(b, face.v) {
+					t.Errorf("%s face (mirrored %v): vertex %d bitangent is %v, want %v",
+						face.name, mirrored, i, b, face.v)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateTangentsSharedVertices(t *testing.T) {
+	// an indexed quad so both triangles add to the tangents of the diagonal's vertices, with
+	// normals leaning away from the face like a smoothed corner
+	face := cubeFaces[0]
+	n := face.u.Cross(face.v)
+	m := &Mesh{Indices: []uint32{0, 1, 2, 0, 2, 3}}
+	for _, uv := range []mgl32.Vec2{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		pos := n.Add(face.u.Mul(2*uv.X() - 1)).Add(face.v.Mul(2*uv.Y() - 1))
+		m.Positions = append(m.Positions, pos)
+		m.Normals = append(m.Normals, n.Add(pos.Mul(0.5)).Normalize())
+		m.UVs = append(m.UVs, uv)
+	}
+
+	if err := m.GenerateTangents(); err != nil {
+		t.Fatal(err)
+	}
+	for i, tangent := range m.Tangents {
+		// +u made perpendicular to the vertex's own normal
+		norm := m.Normals[i]
+		want := face.u.Sub(norm.Mul(norm.Dot(face.u))).Normalize()
+		if !vecNear(tangent.Vec3(), want) || tangent.W() != 1 {
+			t.Errorf("vertex %d tangent is %v, want %v with w 1", i, tangent, want)
+		}
+	}
+}
+
+func TestGenerateTangentsDegenerateUVs(t *testing.T) {
+	m := &Mesh{}
+	addQuad(m, cubeFaces[0], false)
+	for i := range m.UVs {
+		m.UVs[i] = mgl32.Vec2{0.5, 0.5}
+	}
+
+	// no direction can be found from the uvs but the frame still has to be usable
+	if err := m.GenerateTangents(); err != nil {
+		t.Fatal(err)
+	}
+	for i, tangent := range m.Tangents {
+		if d := tangent.Vec3().Dot(m.Normals[i]); d > 1e-5 || d < -1e-5 {
+			t.Errorf("vertex %d tangent %v is not perpendicular to its normal", i, tangent)
+		}
+	}
+}
+
+func TestGenerateTangentsErrors(t *testing.T) {
+	full := &Mesh{}
+	addQuad(full, cubeFaces[0], false)
+
+	tests := []struct {
+		name string
+		mesh *Mesh
+		want error
+	}{
+		{"no positions", &Mesh{}, errMeshNoPositions},
+		{"no normals", &Mesh{Positions: full.Positions, UVs: full.UVs}, errMeshTangentInputs},
+		{"no uvs", &Mesh{Positions: full.Positions, Normals: full.Normals}, errMeshTangentInputs},
+		{"missing normals", &Mesh{Positions: full.Positions, Normals: full.Normals[1:], UVs: full.UVs},
+			errMeshAttribCount},
+	}
+
+	for _, test := range tests {
+		if err := test.mesh.GenerateTangents(); err != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-Lighting/Normal-Mapping
+
+Shows tangent space normal mapping on top of phong lighting.
+Tangents are generated on the CPU for the cube mesh and the normal map
+is derived from the diffuse texture, treating its brightness as a height map.
+*/
+
+import (
+	"image"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/normal-mapping/cam"
+	"github.com/cstegel/opengl-samples-golang/normal-mapping/gfx"
+	"github.com/cstegel/opengl-samples-golang/normal-mapping/win"
+)
+
+// vertices to draw 6 faces of a cube
+var cubeVertices = []float32{
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+}
+
+var cubePositions = [][]float32 {
+	{ 0.0,  0.0,  -3.0},
+	{ 2.0,  5.0, -15.0},
+	{-1.5, -2.2, -2.5 },
+	{-3.8, -2.0, -12.3},
+	{ 2.4, -0.4, -3.5 },
+	{-1.7,  3.0, -7.5 },
+	{ 1.3, -2.0, -2.5 },
+	{ 1.5,  2.0, -2.5 },
+	{ 1.5,  0.2, -1.5 },
+	{-1.3,  1.0, -1.5 },
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Normal mapping")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+/*
+ * Builds a tangent space normal map from the brightness of img, treating it as a height map.
+ * Darker texels (the gaps between planks on the crate) become grooves.
+ * The y axis follows the image rows which is the same direction as the v texture coordinate
+ * since images are uploaded top row first.
+ */
+func heightToNormalMap(img image.Image, strength float64) *image.NRGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	height := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+			height[y*w+x] = float64(gray.Y) / 255
+		}
+	}
+
+	// clamp to the edge so the border does not wrap around
+	at := func(x, y int) float64 {
+		x = int(math.Max(0, math.Min(float64(w-1), float64(x))))
+		y = int(math.Max(0, math.Min(float64(h-1), float64(y))))
+		return height[y*w+x]
+	}
+
+	normalMap := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// sobel filter for the slope in each direction
+			dx := (at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1)) -
+				(at(x-1, y-1) + 2*at(x-1, y) + at(x-1, y+1))
+			dy := (at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1)) -
+				(at(x-1, y-1) + 2*at(x, y-1) + at(x+1, y-1))
+
+			n := mgl32.Vec3{float32(-dx * strength), float32(-dy * strength), 1}.Normalize()
+
+			// [-1, 1] -> [0, 255]
+			normalMap.SetNRGBA(x, y, color.NRGBA{
+				R: uint8((n.X()*0.5 + 0.5) * 255),
+				G: uint8((n.Y()*0.5 + 0.5) * 255),
+				B: uint8((n.Z()*0.5 + 0.5) * 255),
+				A: 255,
+			})
+		}
+	}
+
+	return normalMap
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+
+	// the tangents are generated once here and then become a regular vertex attribute
+	cube := gfx.NewMeshFromInterleaved(cubeVertices, true, true)
+	if err := cube.GenerateTangents(); err != nil {
+		return err
+	}
+	cubeVAO, err := cube.Upload()
+	if err != nil {
+		return err
+	}
+	defer cubeVAO.Delete()
+
+	diffuseImg, err := loadImage("../images/container2.png")
+	if err != nil {
+		return err
+	}
+	diffuseTexture, err := gfx.NewTexture(diffuseImg, gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// normal maps hold directions, not colors, so they must not go through sRGB decoding
+	normalTexture, err := gfx.NewLinearTexture(heightToNormalMap(diffuseImg, 2.0), gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)  // depth buffer needed for DEPTH_TEST
+
+		// cubes rotate slowly so that the light moving across the bumps is easy to see
+		rotateX   := (mgl32.Rotate3DX(mgl32.DegToRad(-10 * float32(glfw.GetTime()))))
+		rotateY   := (mgl32.Rotate3DY(mgl32.DegToRad(-10 * float32(glfw.GetTime()))))
+
+		// creates perspective
+		fov := float32(60.0)
+		projectTransform := mgl32.Perspective(mgl32.DegToRad(fov),
+		                                      float32(window.Width())/float32(window.Height()),
+		                                      0.1,
+		                                      100.0)
+
+		camTransform := camera.GetTransform()
+
+		// light circles around the first cube
+		lightPos := mgl32.Vec3{
+			float32(1.5 * math.Cos(glfw.GetTime())),
+			0.8,
+			float32(-3.0 + 1.5 * math.Sin(glfw.GetTime())),
+		}
+		lightTransform := mgl32.Translate3D(lightPos.X(), lightPos.Y(), lightPos.Z()).Mul4(
+		                                    mgl32.Scale3D(0.2, 0.2, 0.2))
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false,
+		                    &projectTransform[0])
+
+		diffuseTexture.Bind(gl.TEXTURE0)
+		diffuseTexture.SetUniform(program.GetUniformLocation("material.diffuse"))
+		normalTexture.Bind(gl.TEXTURE1)
+		normalTexture.SetUniform(program.GetUniformLocation("material.normal"))
+
+		gl.Uniform3f(program.GetUniformLocation("material.specular"), 0.5, 0.5, 0.5)
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		gl.Uniform3f(program.GetUniformLocation("light.ambient"), 0.1, 0.1, 0.1)
+		gl.Uniform3f(program.GetUniformLocation("light.diffuse"), 0.8, 0.8, 0.8)
+		gl.Uniform3f(program.GetUniformLocation("light.specular"), 1.0, 1.0, 1.0)
+		gl.Uniform3f(program.GetUniformLocation("lightPos"), lightPos.X(), lightPos.Y(), lightPos.Z())
+
+		cubeVAO.Bind()
+		for _, pos := range cubePositions {
+
+			worldTranslate := mgl32.Translate3D(pos[0], pos[1], pos[2])
+			worldTransform := worldTranslate.Mul4(rotateX.Mul3(rotateY).Mat4())
+
+			gl.UniformMatrix4fv(program.GetUniformLocation("model"), 1, false,
+			                    &worldTransform[0])
+
+			cubeVAO.Draw()
+		}
+
+		diffuseTexture.UnBind()
+		normalTexture.UnBind()
+
+		// Draw the light obj after the other boxes using its separate shader program
+		// this means that we must re-bind any uniforms
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("model"), 1, false, &lightTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		cubeVAO.Draw()
+
+		cubeVAO.UnBind()
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func loadImage(file string) (image.Image, error) {
+	imgFile, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer imgFile.Close()
+
+	// Decode detects the type of image as long as its image/<type> is imported
+	img, _, err := image.Decode(imgFile)
+	return img, err
+}
@@ -0,0 +1,51 @@
+#version 410 core
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D normal;  // tangent space normal map, loaded without sRGB conversion
+	vec3 specular;
+	float shininess;
+};
+
+struct Light {
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+in vec3 FragPos;
+in vec3 LightPos;
+in vec2 TexCoords;
+in mat3 TBN;
+out vec4 color;
+
+uniform Material material;
+uniform Light light;
+
+void main()
+{
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+
+	// ambient
+	vec3 ambient = light.ambient * diffuseColor;
+
+	// the normal map stores directions in [0,1] so map them back to [-1,1]
+	// and then move them from tangent space into view space
+	vec3 norm = texture(material.normal, TexCoords).rgb * 2.0 - 1.0;
+	norm = normalize(TBN * norm);
+
+	// diffuse
+	vec3 dirToLight = normalize(LightPos - FragPos);
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = light.diffuse * lightNormalDiff * diffuseColor;
+
+	// specular
+	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
+	vec3 dirToView = normalize(viewPos - FragPos);
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = light.specular * (spec * material.specular);
+
+	vec3 result = diffuse + specular + ambient;
+	color = vec4(result, 1.0f);
+}
@@ -0,0 +1,42 @@
+#version 410 core
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 normal;
+layout (location = 2) in vec2 texCoord;
+layout (location = 3) in vec4 tangent;  // xyz tangent, w bitangent handedness
+
+uniform mat4 model;
+uniform mat4 view;
+uniform mat4 project;
+
+uniform vec3 lightPos;  // only need one light for a basic example
+
+out vec3 FragPos;
+out vec3 LightPos;
+out vec2 TexCoords;
+out mat3 TBN;
+
+void main()
+{
+    gl_Position = project * view * model * vec4(position, 1.0);
+
+    // lighting is done in view space like the other phong samples so the viewer is at (0,0,0)
+    FragPos = vec3(view * model * vec4(position, 1.0));
+    LightPos = vec3(view * vec4(lightPos, 1.0));
+    TexCoords = texCoord;
+
+    // normals need the normal matrix (see basic-light) but tangents lie on the surface
+    // so they transform like any other direction
+    mat3 normMatrix = mat3(transpose(inverse(view * model)));
+    vec3 N = normalize(normMatrix * normal);
+    vec3 T = normalize(mat3(view * model) * tangent.xyz);
+
+    // re-orthogonalize in case non-uniform scaling skewed the tangent
+    T = normalize(T - dot(T, N) * N);
+
+    // MikkTSpace convention: the bitangent is rebuilt from the normal and tangent
+    vec3 B = tangent.w * cross(N, T);
+
+    // transforms from tangent space (what the normal map stores) to view space
+    TBN = mat3(T, B, N);
+}
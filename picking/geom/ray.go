@@ -0,0 +1,139 @@
+package geom
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Ray is a half line starting at Origin. Distances along it are in units of Direction's
+// length, which is 1 for rays made with NewRay.
+type Ray struct {
+	Origin    mgl32.Vec3
+	Direction mgl32.Vec3
+}
+
+func NewRay(origin, direction mgl32.Vec3) Ray {
+	return Ray{Origin: origin, Direction: direction.Normalize()}
+}
+
+// At is the point dist along the ray.
+func (r Ray) At(dist float32) mgl32.Vec3 {
+	return r.Origin.Add(r.Direction.Mul(dist))
+}
+
+// IntersectAABB returns the distance along the ray to where it enters b, or 0 when the
+// origin is already inside.
+func (r Ray) IntersectAABB(b AABB) (float32, bool) {
+	near, far := float32(0), float32(math.Inf(1))
+
+	// the ray is inside the box between where it has crossed all 3 pairs of planes (slabs)
+	for axis := 0; axis < 3; axis++ {
+		if r.Direction[axis] == 0 {
+			// parallel to the slab so it is either always or never between its planes
+			if r.Origin[axis] < b.Min[axis] || r.Origin[axis] > b.Max[axis] {
+				return 0, false
+			}
+			continue
+		}
+
+		inv := 1 / r.Direction[axis]
+		t0 := (b.Min[axis] - r.Origin[axis]) * inv
+		t1 := (b.Max[axis] - r.Origin[axis]) * inv
+		if t0 > t1 {
+			t0, t1 = t1, t0
+		}
+		if t0 > near {
+			near = t0
+		}
+		if t1 < far {
+			far = t1
+		}
+		if near > far {
+			return 0, false
+		}
+	}
+	return near, true
+}
+
+// IntersectSphere returns the distance along the ray to where it enters s, or 0 when the
+// origin is already inside.
+func (r Ray) IntersectSphere(s Sphere) (float32, bool) {
+	// solve |origin + t*dir - center|^2 = radius^2 for t
+	toOrigin := r.Origin.Sub(s.Center)
+	a := r.Direction.LenSqr()
+	halfB := toOrigin.Dot(r.Direction)
+	c := toOrigin.LenSqr() - s.Radius*s.Radius
+
+	if c <= 0 {
+		return 0, true
+	}
+	discriminant := halfB*halfB - a*c
+	if halfB > 0 || discriminant < 0 {
+		return 0, false
+	}
+	return (-halfB - float32(math.Sqrt(float64(discriminant)))) / a, true
+}
+
+// IntersectTriangle returns the distance along the ray to where it crosses triangle abc,
+// from either side (Möller and Trumbore, 1997).
+func (r Ray) IntersectTriangle(a, b, c mgl32.Vec3) (float32, bool) {
+	// solve origin + t*dir = a + u*(b-a) + v*(c-a) with Cramer's rule
+	ab := b.Sub(a)
+	ac := c.Sub(a)
+	p := r.Direction.Cross(ac)
+	det := ab.Dot(p)
+
+	// the ray is parallel to the triangle
+	if abs(det) < 1e-8 {
+		return 0, false
+	}
+	invDet := 1 / det
+
+	toOrigin := r.Origin.Sub(a)
+	u := toOrigin.Dot(p) * invDet
+	if u < 0 || u > 1 {
+		return 0, false
+	}
+
+	q := toOrigin.Cross(ab)
+	v := r.Direction.Dot(q) * invDet
+	if v < 0 || u+v > 1 {
+		return 0, false
+	}
+
+	dist := ac.Dot(q) * invDet
+	if dist < 0 {
+		return 0, false
+	}
+	return dist, true
+}
+
+// Transform moves the ray by m. The direction is not renormalized so distances along the
+// result match distances along r, ex: a hit found in model space is the same distance away
+// in world space.
+func (r Ray) Transform(m mgl32.Mat4) Ray {
+	return Ray{
+		Origin:    m.Mul4x1(r.Origin.Vec4(1)).Vec3(),
+		Direction: m.Mul4x1(r.Direction.Vec4(0)).Vec3(),
+	}
+}
+
+// NewRayFromScreen makes a ray from the camera through pixel (x, y) of a width x height window,
+// with y going down like cursor positions. project and view are the camera's matrices.
+func NewRayFromScreen(x, y float32, width, height int, project, view mgl32.Mat4) Ray {
+	// window to normalized device coordinates, which go from -1 to 1 with y going up
+	ndcX := 2*x/float32(width) - 1
+	ndcY := 1 - 2*y/float32(height)
+
+	// undo the projection and view for the points under the cursor on the near and far planes
+	inv := project.Mul4(view).Inv()
+	unproject := func(ndcZ float32) mgl32.Vec3 {
+		p := inv.Mul4x1(mgl32.Vec4{ndcX, ndcY, ndcZ, 1})
+		return p.Vec3().Mul(1 / p.W())
+	}
+
+	near := unproject(-1)
+	far := unproject(1)
+	return NewRay(near, far.Sub(near))
+}
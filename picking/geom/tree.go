@@ -0,0 +1,481 @@
+package geom
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Tree is a bounding volume hierarchy (BVH) over boxes that can change every frame,
+// the same design as the dynamic AABB tree in Box2D and Bullet.
+//
+// Every item is a leaf holding a "fat" box, its bounds grown by the tree's margin.
+// Moving an item only touches the tree once it leaves its fat box so things that move a
+// little every frame (ex: spinning) are cheap. Inner nodes hold the union of their two
+// children and are kept roughly balanced with tree rotations like an AVL tree. Where a new leaf
+// goes is decided by the surface area heuristic: the smaller the boxes, the less likely a
+// query has to look inside them.
+//
+// Items are identified by the id returned from Insert, ids are reused after Remove.
+type Tree struct {
+	nodes  []treeNode
+	root   int
+	free   int // first node of the free list, linked through parent
+	margin float32
+	count  int
+}
+
+// marks a missing parent or child
+const nullNode = -1
+
+type treeNode struct {
+	bounds AABB
+	item   interface{}
+
+	parent      int
+	left, right int // nullNode for leaves
+	height      int // leaves are 0
+}
+
+func (n *treeNode) isLeaf() bool {
+	return n.left == nullNode
+}
+
+// NewTree makes an empty tree. margin is how far an item can move (or grow) in each
+// direction before it has to be reinserted.
+func NewTree(margin float32) *Tree {
+	return &Tree{root: nullNode, free: nullNode, margin: margin}
+}
+
+// Len is the number of items in the tree.
+func (t *Tree) Len() int {
+	return t.count
+}
+
+// Height is the number of levels below the root, 0 for an empty tree or a single item.
+func (t *Tree) Height() int {
+	if t.root == nullNode {
+		return 0
+	}
+	return t.nodes[t.root].height
+}
+
+// Item returns what was passed to Insert for id.
+func (t *Tree) Item(id int) interface{} {
+	return t.nodes[id].item
+}
+
+// Bounds is the fat box stored for id, it contains the bounds last given for the item.
+func (t *Tree) Bounds(id int) AABB {
+	return t.nodes[id].bounds
+}
+
+// Insert adds an item with the given bounds and returns its id.
+func (t *Tree) Insert(bounds AABB, item interface{}) int {
+	id := t.allocate()
+	t.nodes[id].bounds = t.fatten(bounds)
+	t.nodes[id].item = item
+	t.insertLeaf(id)
+	t.count++
+	return id
+}
+
+// Remove takes the item out of the tree, its id may be handed out again by Insert.
+// It panics if id is not in the tree, ex: when it was already removed.
+func (t *Tree) Remove(id int) {
+	t.checkItem(id, "Remove")
+	t.removeLeaf(id)
+	t.release(id)
+	t.count--
+}
+
+// Update tells the tree the item's bounds changed. It returns whether the item had to be
+// reinserted because the new bounds are no longer inside its fat box.
+func (t *Tree) Update(id int, bounds AABB) bool {
+	t.checkItem(id, "Update")
+	if t.nodes[id].bounds.ContainsAABB(bounds) {
+		return false
+	}
+	t.removeLeaf(id)
+	t.nodes[id].bounds = t.fatten(bounds)
+	t.insertLeaf(id)
+	return true
+}
+
+// QueryAABB calls visit for every item whose fat box overlaps b until visit returns false.
+func (t *Tree) QueryAABB(b AABB, visit func(id int) bool) {
+	stack := t.stack()
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := &t.nodes[i]
+		if !n.bounds.Intersects(b) {
+			continue
+		}
+		if n.isLeaf() {
+			if !visit(i) {
+				return
+			}
+			continue
+		}
+		stack = append(stack, n.left, n.right)
+	}
+}
+
+// QueryFrustum calls visit for every item whose fat box is at least partly inside f until
+// visit returns false. Once a node is completely inside, everything below it is visited
+// without any more plane tests. Since the boxes are fat, callers wanting exact results
+// should test the item's own bounds as well.
+func (t *Tree) QueryFrustum(f *Frustum, visit func(id int) bool) {
+	stack := t.stack()
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := &t.nodes[i]
+		switch f.ClassifyAABB(n.bounds) {
+		case Outside:
+			continue
+		case Inside:
+			if !t.visitAll(i, visit) {
+				return
+			}
+			continue
+		}
+		if n.isLeaf() {
+			if !visit(i) {
+				return
+			}
+			continue
+		}
+		stack = append(stack, n.left, n.right)
+	}
+}
+
+// visitAll calls visit for every leaf at or below i, returning false if visit stopped early
+func (t *Tree) visitAll(i int, visit func(id int) bool) bool {
+	n := &t.nodes[i]
+	if n.isLeaf() {
+		return visit(i)
+	}
+	return t.visitAll(n.left, visit) && t.visitAll(n.right, visit)
+}
+
+// QueryRay finds the closest item along r within maxDist. hit tests the ray against the
+// item's real shape and returns the distance to it. It is only called for items whose fat
+// box the ray enters before the closest hit found so far, nearer boxes first.
+// A nil hit uses the fat boxes themselves.
+func (t *Tree) QueryRay(r Ray, maxDist float32, hit func(id int) (float32, bool)) (int, float32, bool) {
+	if hit == nil {
+		hit = func(id int) (float32, bool) {
+			return r.IntersectAABB(t.nodes[id].bounds)
+		}
+	}
+
+	best, bestDist := nullNode, maxDist
+	type entry struct {
+		node int
+		dist float32
+	}
+	var stack []entry
+	if t.root != nullNode {
+		if dist, ok := r.IntersectAABB(t.nodes[t.root].bounds); ok {
+			stack = append(stack, entry{t.root, dist})
+		}
+	}
+
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.dist > bestDist {
+			continue
+		}
+
+		n := &t.nodes[e.node]
+		if n.isLeaf() {
+			if dist, ok := hit(e.node); ok && dist <= bestDist {
+				best, bestDist = e.node, dist
+			}
+			continue
+		}
+
+		leftDist, leftHit := r.IntersectAABB(t.nodes[n.left].bounds)
+		rightDist, rightHit := r.IntersectAABB(t.nodes[n.right].bounds)
+		left, right := entry{n.left, leftDist}, entry{n.right, rightDist}
+
+		// push the nearer child last so it is searched first and shrinks bestDist sooner
+		if leftHit && rightHit && leftDist < rightDist {
+			left, right = right, left
+		}
+		if leftHit && rightHit {
+			stack = append(stack, left, right)
+		} else if leftHit {
+			stack = append(stack, left)
+		} else if rightHit {
+			stack = append(stack, right)
+		}
+	}
+
+	return best, bestDist, best != nullNode
+}
+
+// Nearest finds the item closest to p within maxDist. dist returns the distance from p to
+// the item's real shape, it must never be less than the distance to the item's bounds.
+// Nodes are searched closest box first so most of the tree is never looked at.
+// A nil dist uses the fat boxes themselves.
+func (t *Tree) Nearest(p mgl32.Vec3, maxDist float32, dist func(id int) float32) (int, float32, bool) {
+	if dist == nil {
+		dist = func(id int) float32 {
+			return t.nodes[id].bounds.Distance(p)
+		}
+	}
+
+	best, bestDist := nullNode, maxDist
+	queue := nodeQueue{}
+	if t.root != nullNode {
+		heap.Push(&queue, queuedNode{t.root, t.nodes[t.root].bounds.Distance(p)})
+	}
+
+	for queue.Len() > 0 {
+		q := heap.Pop(&queue).(queuedNode)
+		// everything left in the queue is at least this far away
+		if q.dist > bestDist {
+			break
+		}
+
+		n := &t.nodes[q.node]
+		if n.isLeaf() {
+			if d := dist(q.node); d <= bestDist {
+				best, bestDist = q.node, d
+			}
+			continue
+		}
+		for _, child := range [2]int{n.left, n.right} {
+			if d := t.nodes[child].bounds.Distance(p); d <= bestDist {
+				heap.Push(&queue, queuedNode{child, d})
+			}
+		}
+	}
+
+	return best, bestDist, best != nullNode
+}
+
+// queuedNode is a node waiting to be searched in Nearest, closest first
+type queuedNode struct {
+	node int
+	dist float32
+}
+
+type nodeQueue []queuedNode
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(queuedNode)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+// stack starts a depth first search from the root
+func (t *Tree) stack() []int {
+	stack := make([]int, 0, 64)
+	if t.root != nullNode {
+		stack = append(stack, t.root)
+	}
+	return stack
+}
+
+func (t *Tree) fatten(b AABB) AABB {
+	m := mgl32.Vec3{t.margin, t.margin, t.margin}
+	return AABB{Min: b.Min.Sub(m), Max: b.Max.Add(m)}
+}
+
+func (t *Tree) allocate() int {
+	id := t.free
+	if id == nullNode {
+		t.nodes = append(t.nodes, treeNode{})
+		id = len(t.nodes) - 1
+	} else {
+		t.free = t.nodes[id].parent
+	}
+	t.nodes[id] = treeNode{parent: nullNode, left: nullNode, right: nullNode}
+	return id
+}
+
+// checkItem panics unless id is a leaf currently in the tree. Removing a freed node again
+// would put it on the free list twice and hand it out to two items.
+func (t *Tree) checkItem(id int, op string) {
+	if id < 0 || id >= len(t.nodes) || t.nodes[id].height < 0 {
+		panic(fmt.Sprintf("geom: Tree.%s of id %d which is not in the tree", op, id))
+	}
+	if !t.nodes[id].isLeaf() {
+		panic(fmt.Sprintf("geom: Tree.%s of id %d which is an inner node, not an item", op, id))
+	}
+}
+
+func (t *Tree) release(id int) {
+	t.nodes[id] = treeNode{parent: t.free, left: nullNode, right: nullNode, height: -1}
+	t.free = id
+}
+
+func (t *Tree) insertLeaf(leaf int) {
+	if t.root == nullNode {
+		t.root = leaf
+		t.nodes[leaf].parent = nullNode
+		return
+	}
+
+	// walk down to the sibling that makes the new parent box cheapest. Going into a child
+	// costs at least the growth of every box on the way down (inherited).
+	leafBounds := t.nodes[leaf].bounds
+	sibling := t.root
+	for !t.nodes[sibling].isLeaf() {
+		n := &t.nodes[sibling]
+		area := n.bounds.SurfaceArea()
+		combined := n.bounds.Union(leafBounds).SurfaceArea()
+
+		// pairing with this node makes a new parent the size of combined
+		cost := 2 * combined
+		inherited := 2 * (combined - area)
+
+		childCost := func(child int) float32 {
+			c := &t.nodes[child]
+			grown := c.bounds.Union(leafBounds).SurfaceArea()
+			if c.isLeaf() {
+				return grown + inherited
+			}
+			return grown - c.bounds.SurfaceArea() + inherited
+		}
+		leftCost, rightCost := childCost(n.left), childCost(n.right)
+
+		if cost < leftCost && cost < rightCost {
+			break
+		}
+		if leftCost < rightCost {
+			sibling = n.left
+		} else {
+			sibling = n.right
+		}
+	}
+
+	// a new parent takes the sibling's place with the sibling and leaf below it
+	oldParent := t.nodes[sibling].parent
+	parent := t.allocate()
+	t.nodes[parent].parent = oldParent
+	t.nodes[parent].left = sibling
+	t.nodes[parent].right = leaf
+	t.replaceChild(oldParent, sibling, parent)
+	t.nodes[sibling].parent = parent
+	t.nodes[leaf].parent = parent
+
+	t.refit(parent)
+}
+
+func (t *Tree) removeLeaf(leaf int) {
+	if leaf == t.root {
+		t.root = nullNode
+		return
+	}
+
+	// the leaf's sibling takes its parent's place
+	parent := t.nodes[leaf].parent
+	grandParent := t.nodes[parent].parent
+	sibling := t.nodes[parent].left
+	if sibling == leaf {
+		sibling = t.nodes[parent].right
+	}
+
+	t.replaceChild(grandParent, parent, sibling)
+	t.nodes[sibling].parent = grandParent
+	t.nodes[leaf].parent = nullNode
+	t.release(parent)
+
+	t.refit(grandParent)
+}
+
+// refit rebalances and recomputes the boxes and heights from i up to the root
+func (t *Tree) refit(i int) {
+	for i != nullNode {
+		i = t.balance(i)
+		t.fix(i)
+		i = t.nodes[i].parent
+	}
+}
+
+// fix recomputes an inner node's box and height from its children
+func (t *Tree) fix(i int) {
+	n := &t.nodes[i]
+	left, right := &t.nodes[n.left], &t.nodes[n.right]
+	n.bounds = left.bounds.Union(right.bounds)
+	n.height = 1 + maxInt(left.height, right.height)
+}
+
+// balance rotates the taller child of i up if its children's heights differ by more than 1,
+// returning the node now in i's place
+func (t *Tree) balance(i int) int {
+	n := &t.nodes[i]
+	if n.isLeaf() || n.height < 2 {
+		return i
+	}
+
+	balance := t.nodes[n.right].height - t.nodes[n.left].height
+	if balance > 1 {
+		return t.rotate(i, n.right)
+	}
+	if balance < -1 {
+		return t.rotate(i, n.left)
+	}
+	return i
+}
+
+// rotate moves child up into i's place. i becomes a child of child and takes child's
+// shorter subtree, child keeps the taller one.
+func (t *Tree) rotate(i, child int) int {
+	n := t.nodes
+	tall, short := n[child].left, n[child].right
+	if n[tall].height < n[short].height {
+		tall, short = short, tall
+	}
+
+	n[child].parent = n[i].parent
+	t.replaceChild(n[child].parent, i, child)
+	n[i].parent = child
+	n[child].left = i
+	n[child].right = tall
+
+	if n[i].left == child {
+		n[i].left = short
+	} else {
+		n[i].right = short
+	}
+	n[short].parent = i
+
+	t.fix(i)
+	t.fix(child)
+	return child
+}
+
+// replaceChild points parent (or the root when parent is nullNode) at to instead of from
+func (t *Tree) replaceChild(parent, from, to int) {
+	if parent == nullNode {
+		t.root = to
+		return
+	}
+	if t.nodes[parent].left == from {
+		t.nodes[parent].left = to
+	} else {
+		t.nodes[parent].right = to
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,222 @@
+package gfx
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// IDBuffer is an offscreen render target for picking. Objects are drawn into it with their
+// ID as the color (see shaders/id.frag) and the pixels under the cursor are read back to see
+// which object is there. This works for any geometry the shaders can draw, including
+// alpha tested or vertex animated meshes that a CPU ray cast does not know about.
+//
+// Reading pixels normally stalls the CPU until the GPU has finished drawing. Instead the
+// read goes into a pixel buffer object (PBO) and the result is picked up with Result on a
+// later frame once a fence says the copy is done.
+type IDBuffer struct {
+	fbo    uint32
+	ids    uint32 // R32UI texture the IDs are drawn into, 0 is the background
+	depth  uint32 // renderbuffer so closer objects win
+	width  int
+	height int
+
+	pbo *Buffer
+
+	// what Bind replaced, for UnBind to put back
+	prevFramebuffer int32
+	prevViewport    [4]int32
+
+	// the read in flight, fence is 0 when there is none
+	fence        uintptr
+	readX, readY int // pixel the read is centered on
+	readW, readH int
+}
+
+// NoID is the ID of pixels that no object was drawn to.
+const NoID uint32 = 0
+
+var errIDBufferIncomplete = errors.New("ID framebuffer is incomplete")
+
+var errIDReadFailed = errors.New("failed waiting for the ID buffer read")
+
+var errIDBufferSize = errors.New("ID buffer needs a positive size")
+
+// NewIDBuffer makes an ID buffer the size of the window's framebuffer, Resize it when that
+// changes.
+func NewIDBuffer(width, height int) (*IDBuffer, error) {
+	idb := IDBuffer{}
+	gl.GenFramebuffers(1, &idb.fbo)
+	gl.GenTextures(1, &idb.ids)
+	gl.GenRenderbuffers(1, &idb.depth)
+	idb.pbo = NewBuffer(gl.PIXEL_PACK_BUFFER, gl.STREAM_READ)
+
+	if err := idb.Resize(width, height); err != nil {
+		idb.Delete()
+		return nil, err
+	}
+	return &idb, nil
+}
+
+// Resize reallocates the IDs and depth at the new size, a read in flight is dropped since
+// its pixels may no longer be where the cursor was.
+func (idb *IDBuffer) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errIDBufferSize
+	}
+	idb.cancel()
+	idb.width, idb.height = width, height
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, idb.fbo)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	// integer textures can not be filtered, NEAREST is required for them to be complete
+	gl.BindTexture(gl.TEXTURE_2D, idb.ids)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.R32UI, int32(width), int32(height), 0,
+		gl.RED_INTEGER, gl.UNSIGNED_INT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, idb.ids, 0)
+
+	gl.BindRenderbuffer(gl.RENDERBUFFER, idb.depth)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT24, int32(width), int32(height))
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+	gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.RENDERBUFFER, idb.depth)
+
+	if gl.CheckFramebufferStatus(gl.FRAMEBUFFER) != gl.FRAMEBUFFER_COMPLETE {
+		return errIDBufferIncomplete
+	}
+	return nil
+}
+
+// Bind makes the ID buffer the draw target and clears it to NoID.
+func (idb *IDBuffer) Bind() {
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &idb.prevFramebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &idb.prevViewport[0])
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, idb.fbo)
+	gl.Viewport(0, 0, int32(idb.width), int32(idb.height))
+
+	clear := [4]uint32{NoID, 0, 0, 0}
+	gl.ClearBufferuiv(gl.COLOR, 0, &clear[0])
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+}
+
+// UnBind goes back to the framebuffer and viewport that were in use before Bind.
+func (idb *IDBuffer) UnBind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(idb.prevFramebuffer))
+	v := idb.prevViewport
+	gl.Viewport(v[0], v[1], v[2], v[3])
+}
+
+// Read starts copying the IDs within radius pixels of (x, y) into the PBO. x and y are
+// framebuffer pixels with y going down like cursor positions. Any read still in flight
+// is dropped.
+func (idb *IDBuffer) Read(x, y, radius int) {
+	idb.cancel()
+
+	// GL's window origin is the bottom left
+	y = idb.height - 1 - y
+
+	x0, y0 := clampInt(x-radius, 0, idb.width-1), clampInt(y-radius, 0, idb.height-1)
+	x1, y1 := clampInt(x+radius, 0, idb.width-1), clampInt(y+radius, 0, idb.height-1)
+	idb.readX, idb.readY = x-x0, y-y0
+	idb.readW, idb.readH = x1-x0+1, y1-y0+1
+
+	size := idb.readW * idb.readH * 4
+	if idb.pbo.Size() < size {
+		idb.pbo.Data(size, nil)
+	}
+
+	// with a PIXEL_PACK_BUFFER bound ReadPixels writes to it at the given offset instead of
+	// client memory, so it returns right away
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, idb.fbo)
+	gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	idb.pbo.Bind()
+	gl.ReadPixels(int32(x0), int32(y0), int32(idb.readW), int32(idb.readH),
+		gl.RED_INTEGER, gl.UNSIGNED_INT, gl.PtrOffset(0))
+	idb.pbo.UnBind()
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+
+	idb.fence = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+}
+
+// Pending returns whether a read has been started and its result not collected yet.
+func (idb *IDBuffer) Pending() bool {
+	return idb.fence != 0
+}
+
+// Result returns the ID of the last Read without waiting. ready is false while the GPU is
+// still working on it (try again next frame) or when there is no read in flight. The ID is
+// the one at the center of the read or, when that is NoID, the closest one to it so thin
+// objects are easier to hit.
+func (idb *IDBuffer) Result() (id uint32, ready bool, err error) {
+	if idb.fence == 0 {
+		return NoID, false, nil
+	}
+
+	// a timeout of 0 only checks, flushing makes sure the fence is going to be reached
+	status := gl.ClientWaitSync(idb.fence, gl.SYNC_FLUSH_COMMANDS_BIT, 0)
+	if status == gl.TIMEOUT_EXPIRED {
+		return NoID, false, nil
+	}
+	idb.cancel()
+	if status == gl.WAIT_FAILED {
+		return NoID, false, errIDReadFailed
+	}
+
+	data, err := idb.pbo.MapRange(0, idb.readW*idb.readH*4, gl.MAP_READ_BIT)
+	if err != nil {
+		return NoID, false, err
+	}
+
+	pixels := unsafe.Slice((*uint32)(unsafe.Pointer(&data[0])), idb.readW*idb.readH)
+
+	id = NoID
+	bestDist := -1
+	for row := 0; row < idb.readH; row++ {
+		for col := 0; col < idb.readW; col++ {
+			pixel := pixels[row*idb.readW+col]
+			dx, dy := col-idb.readX, row-idb.readY
+			if dist := dx*dx + dy*dy; pixel != NoID && (bestDist < 0 || dist < bestDist) {
+				id, bestDist = pixel, dist
+			}
+		}
+	}
+
+	if err := idb.pbo.Unmap(); err != nil {
+		return NoID, false, err
+	}
+	idb.pbo.UnBind()
+	return id, true, nil
+}
+
+// cancel forgets the read in flight
+func (idb *IDBuffer) cancel() {
+	if idb.fence != 0 {
+		gl.DeleteSync(idb.fence)
+		idb.fence = 0
+	}
+}
+
+func (idb *IDBuffer) Delete() {
+	idb.cancel()
+	if idb.pbo != nil {
+		idb.pbo.Delete()
+	}
+	gl.DeleteRenderbuffers(1, &idb.depth)
+	gl.DeleteTextures(1, &idb.ids)
+	gl.DeleteFramebuffers(1, &idb.fbo)
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,200 @@
+package gfx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// VertexAttrib describes a single attribute of an interleaved vertex.
+type VertexAttrib struct {
+	Index      uint32 // matches layout (location = Index) in the vertex shader
+	Size       int32  // number of components, 1-4 (always 4 for gl.INT_2_10_10_10_REV)
+	Type       uint32 // gl.FLOAT, gl.HALF_FLOAT, gl.UNSIGNED_BYTE, gl.INT_2_10_10_10_REV, ...
+	Normalized bool   // integer types are read as [0, 1] (unsigned) or [-1, 1] (signed) floats
+
+	// Inputs is the number of floats per vertex VertexLayout.Pack takes for the attribute,
+	// 0 means Size. It can be less than Size, the missing components are stored as 0.
+	Inputs int32
+}
+
+// FloatAttrib is a plain 32-bit float attribute, ex: FloatAttrib(0, 3) for positions.
+func FloatAttrib(index uint32, size int32) VertexAttrib {
+	return VertexAttrib{Index: index, Size: size, Type: gl.FLOAT}
+}
+
+// HalfAttrib stores each component as a 16-bit float. Good enough for positions of
+// meshes with moderate extent and for UVs.
+func HalfAttrib(index uint32, size int32) VertexAttrib {
+	return VertexAttrib{Index: index, Size: size, Type: gl.HALF_FLOAT}
+}
+
+// UnormByteAttrib stores each component in [0, 1] as an unsigned byte, ex: vertex colors.
+func UnormByteAttrib(index uint32, size int32) VertexAttrib {
+	return VertexAttrib{Index: index, Size: size, Type: gl.UNSIGNED_BYTE, Normalized: true}
+}
+
+// PackedNormalAttrib stores a unit vector in a single 32-bit int (10 bits per xyz, 2 bits for w).
+// Pack takes 3 floats per vertex for it and w is 0.
+func PackedNormalAttrib(index uint32) VertexAttrib {
+	return VertexAttrib{Index: index, Size: 4, Type: gl.INT_2_10_10_10_REV, Normalized: true, Inputs: 3}
+}
+
+// InputSize is the number of floats per vertex VertexLayout.Pack takes for the attribute.
+func (a VertexAttrib) InputSize() int {
+	if a.Inputs > 0 {
+		return int(a.Inputs)
+	}
+	return int(a.Size)
+}
+
+// ByteSize is the number of bytes the attribute takes up in one vertex.
+func (a VertexAttrib) ByteSize() int {
+	switch a.Type {
+	case gl.INT_2_10_10_10_REV, gl.UNSIGNED_INT_2_10_10_10_REV:
+		return 4
+	}
+	return int(a.Size) * componentSize(a.Type)
+}
+
+func componentSize(glType uint32) int {
+	switch glType {
+	case gl.BYTE, gl.UNSIGNED_BYTE:
+		return 1
+	case gl.SHORT, gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2
+	default:
+		return 4
+	}
+}
+
+// VertexLayout is the set of attributes making up one interleaved vertex.
+// Every attribute starts on a 4 byte boundary as recommended for vertex fetching.
+type VertexLayout struct {
+	attribs []VertexAttrib
+	offsets []int
+	stride  int
+}
+
+var errVertexDataSize = errors.New("vertex data length does not match the layout")
+
+// NewVertexLayout panics when an attribute does not have 1-4 components (or takes more
+// inputs than it has components), layouts are written in code rather than read from data.
+func NewVertexLayout(attribs ...VertexAttrib) *VertexLayout {
+	for _, attrib := range attribs {
+		if attrib.Size < 1 || attrib.Size > 4 || attrib.Inputs < 0 || attrib.Inputs > attrib.Size {
+			panic(fmt.Sprintf("gfx: vertex attribute %d has %d components and %d inputs",
+				attrib.Index, attrib.Size, attrib.Inputs))
+		}
+	}
+
+	layout := VertexLayout{
+		attribs: attribs,
+		offsets: make([]int, len(attribs)),
+	}
+
+	for i, attrib := range attribs {
+		layout.offsets[i] = layout.stride
+		layout.stride += align4(attrib.ByteSize())
+	}
+
+	return &layout
+}
+
+func align4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// Stride is the size in bytes of one whole vertex (sum of aligned attrib sizes).
+func (l *VertexLayout) Stride() int {
+	return l.stride
+}
+
+func (l *VertexLayout) Attribs() []VertexAttrib {
+	return l.attribs
+}
+
+// Offset returns the byte offset of the i-th attribute within a vertex.
+func (l *VertexLayout) Offset(i int) int {
+	return l.offsets[i]
+}
+
+// Enable sets up the attribute pointers for this layout.
+// The VAO and the ARRAY_BUFFER holding the vertices must be bound first.
+func (l *VertexLayout) Enable() {
+	for i, attrib := range l.attribs {
+		gl.VertexAttribPointer(attrib.Index, attrib.Size, attrib.Type, attrib.Normalized,
+			int32(l.stride), gl.PtrOffset(l.offsets[i]))
+		gl.EnableVertexAttribArray(attrib.Index)
+	}
+}
+
+// Pack converts float data into interleaved vertices of this layout.
+// There is one stream per attribute holding InputSize floats per vertex, so
+// Pack(positions, normals) for a position+normal layout.
+func (l *VertexLayout) Pack(streams ...[]float32) ([]byte, error) {
+	if len(streams) != len(l.attribs) {
+		return nil, fmt.Errorf("%v: got %d streams for %d attributes",
+			errVertexDataSize, len(streams), len(l.attribs))
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+
+	numVertices := 0
+	for i, stream := range streams {
+		n := l.attribs[i].InputSize()
+		if len(stream)%n != 0 {
+			return nil, fmt.Errorf("%v: attribute %d has %d floats, not a whole number of %d per vertex",
+				errVertexDataSize, l.attribs[i].Index, len(stream), n)
+		}
+		if i == 0 {
+			numVertices = len(stream) / n
+		} else if len(stream)/n != numVertices {
+			return nil, fmt.Errorf("%v: attribute %d has %d vertices, attribute %d has %d",
+				errVertexDataSize, l.attribs[i].Index, len(stream)/n, l.attribs[0].Index, numVertices)
+		}
+	}
+
+	data := make([]byte, numVertices*l.stride)
+	for v := 0; v < numVertices; v++ {
+		for i, attrib := range l.attribs {
+			n := attrib.InputSize()
+			putComponents(data[v*l.stride+l.offsets[i]:], attrib, streams[i][v*n:(v+1)*n])
+		}
+	}
+
+	return data, nil
+}
+
+// GL reads vertex data in the machine's byte order which is little-endian on every
+// platform that these samples run on.
+func putComponents(dst []byte, attrib VertexAttrib, src []float32) {
+	switch attrib.Type {
+	case gl.FLOAT:
+		for i, f := range src {
+			binary.LittleEndian.PutUint32(dst[i*4:], math.Float32bits(f))
+		}
+	case gl.HALF_FLOAT:
+		for i, f := range src {
+			binary.LittleEndian.PutUint16(dst[i*2:], Float32ToHalf(f))
+		}
+	case gl.UNSIGNED_BYTE:
+		for i, f := range src {
+			if attrib.Normalized {
+				dst[i] = PackUnorm8(f)
+			} else {
+				dst[i] = uint8(f)
+			}
+		}
+	case gl.INT_2_10_10_10_REV:
+		var c [4]float32
+		copy(c[:], src)
+		binary.LittleEndian.PutUint32(dst, PackInt2101010Rev(c[0], c[1], c[2], c[3]))
+	default:
+		panic(fmt.Sprintf("gfx: packing vertex attribute type 0x%x is not supported", attrib.Type))
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+/*
+Builds on frustum-culling to select objects with the mouse. The cursor is unprojected
+through the camera's projection and view into a ray in world space (geom.NewRayFromScreen),
+the scene's bounding volume hierarchy finds the nodes whose bounds the ray passes through
+and the triangles of those nodes give the exact hit point and normal (scene.Index.Pick).
+
+Press P to pick with an ID buffer instead: every object is drawn offscreen with its ID as
+the color and the pixels under the cursor are read back a frame or two later without
+stalling (gfx.IDBuffer). That sees exactly what was drawn, so clicking through the holes
+in the cut out faces at the front misses them while the ray cast hits their quads.
+
+Left click selects the object under the cursor: it is highlighted and, for ray casts, a
+marker shows where it was hit and the surface normal there. Hold the right mouse button
+to look around.
+*/
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/picking/cam"
+	"github.com/cstegel/opengl-samples-golang/picking/geom"
+	"github.com/cstegel/opengl-samples-golang/picking/gfx"
+	"github.com/cstegel/opengl-samples-golang/picking/scene"
+	"github.com/cstegel/opengl-samples-golang/picking/win"
+)
+
+// the objects fill a gridSize x gridSize grid around the origin
+const (
+	gridSize    = 12
+	gridSpacing = 2.5
+)
+
+// how far the index lets nodes move before reinserting them, nothing moves here
+const indexMargin = 0.1
+
+// ID picking takes the closest object within this many pixels of the cursor
+const pickRadius = 3
+
+// nodes drawn with the alpha tested cut out texture instead of the crate
+type cutoutSet map[*scene.Node]bool
+
+var (
+	selectedColor = mgl32.Vec3{0.25, 0.2, 0.0}
+	markerColor   = mgl32.Vec3{1.0, 0.8, 0.2}
+)
+
+/*
+ * Builds the scene: a sun, a ground plane, a grid of crates and balls with different
+ * rotations and (sometimes uneven) scales so the hit normals have something to show
+ * and a row of cut out faces in front of them.
+ */
+func buildScene(cube, sphere, floor, cutout *gfx.VertexArray) (*scene.Scene, cutoutSet) {
+	s := scene.New()
+	cutouts := cutoutSet{}
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.3, -1.0, -0.4},
+		Ambient:   mgl32.Vec3{0.2, 0.2, 0.22},
+		Diffuse:   mgl32.Vec3{0.8, 0.8, 0.8},
+		Specular:  mgl32.Vec3{0.4, 0.4, 0.4},
+	}
+
+	ground := s.Add(scene.NewNode("ground"))
+	ground.SetScale(mgl32.Vec3{gridSize * gridSpacing * 1.5, 1, gridSize * gridSpacing * 1.5})
+	ground.Mesh = floor
+
+	half := float32(gridSize-1) * gridSpacing / 2
+	for z := 0; z < gridSize; z++ {
+		for x := 0; x < gridSize; x++ {
+			i := z*gridSize + x
+			node := s.Add(scene.NewNode(fmt.Sprintf("object %d", i)))
+
+			scale := mgl32.Vec3{1, 1, 1}
+			if i%4 == 1 {
+				scale = mgl32.Vec3{1.4, 0.6, 0.9}
+			}
+			node.SetScale(scale)
+			node.SetPosition(mgl32.Vec3{float32(x)*gridSpacing - half, scale.Y() / 2, float32(z)*gridSpacing - half})
+
+			angle := mgl32.DegToRad(float32((i * 37) % 90))
+			node.SetRotation(mgl32.QuatRotate(angle, mgl32.Vec3{0, 1, 0}))
+			if i%5 == 2 {
+				// tipped over on an edge
+				node.Rotate(mgl32.DegToRad(45), mgl32.Vec3{1, 0, 0})
+				node.Translate(mgl32.Vec3{0, 0.2, 0})
+			}
+
+			node.Mesh = cube
+			if i%3 == 0 {
+				node.Mesh = sphere
+			}
+		}
+	}
+
+	// the plane faces +y, stand it up to face the camera with the top of the image up
+	for i := 0; i < 4; i++ {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cutout %d", i)))
+		node.SetPosition(mgl32.Vec3{(float32(i) - 1.5) * 3 * gridSpacing, 1.5, half + gridSpacing})
+		node.SetRotation(mgl32.QuatRotate(mgl32.DegToRad(180), mgl32.Vec3{0, 1, 0}).
+			Mul(mgl32.QuatRotate(mgl32.DegToRad(-90), mgl32.Vec3{1, 0, 0})))
+		node.SetScale(mgl32.Vec3{3, 1, 3})
+		node.Mesh = cutout
+		cutouts[node] = true
+	}
+
+	return s, cutouts
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Picking - left click to select, hold right click to look around")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	idFragShader, err := gfx.NewShaderFromFile("shaders/id.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// same vertices as the lit pass but writing object IDs
+	idProgram, err := gfx.NewProgram(vertShader, idFragShader)
+	if err != nil {
+		return err
+	}
+	defer idProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	sphere, err := gfx.NewSphereMesh(16, 32).Upload()
+	if err != nil {
+		return err
+	}
+	defer sphere.Delete()
+
+	// the crate texture repeats across the ground but the cut outs show their image once
+	floor, err := gfx.NewPlaneMesh(gridSize).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	plane, err := gfx.NewPlaneMesh(1).Upload()
+	if err != nil {
+		return err
+	}
+	defer plane.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	cutoutMap, err := gfx.NewTextureFromFile("../images/trollface-transparent.png",
+		gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+	if err != nil {
+		return err
+	}
+
+	idBuffer, err := gfx.NewIDBuffer(window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+	defer idBuffer.Delete()
+
+	world, cutouts := buildScene(cube, sphere, floor, plane)
+	index := scene.NewIndex(world, indexMargin)
+
+	// everything except cut outs uses the crate textures
+	draw := func(prog *gfx.Program, frustum *geom.Frustum, before func(*scene.Node)) {
+		alphaTestedLoc := prog.GetUniformLocation("alphaTested")
+		for _, cutout := range []bool{false, true} {
+			diffuse, specular := diffuseMap, specularMap
+			if cutout {
+				diffuse, specular = cutoutMap, cutoutMap
+			}
+			diffuse.Bind(gl.TEXTURE0)
+			diffuse.SetUniform(prog.GetUniformLocation("material.diffuse"))
+			specular.Bind(gl.TEXTURE1)
+			specular.SetUniform(prog.GetUniformLocation("material.specular"))
+			gl.Uniform1i(alphaTestedLoc, boolToInt(cutout))
+
+			index.DrawCulled(prog, frustum, func(n *scene.Node) bool {
+				if cutouts[n] != cutout {
+					return false
+				}
+				before(n)
+				return true
+			})
+
+			diffuse.UnBind()
+			specular.UnBind()
+		}
+	}
+
+	lens := scene.Camera{Fov: 60, Near: 0.1, Far: 100}
+
+	// the selected node, for ray casts the hit also has the point and normal
+	var selected *scene.Node
+	var hit scene.Hit
+	hasHit := false
+
+	pickWithIDs := false
+	var idNodes []*scene.Node // the node for each ID in the read in flight, offset by 1
+
+	// the cursor is for pointing until the right mouse button is held
+	window.SetCursorCaptured(false)
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 8, 22}, mgl32.Vec3{0, 1, 0}, -90, -25, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		im := window.InputManager()
+		looking := im.IsButtonActive(glfw.MouseButtonRight)
+		window.SetCursorCaptured(looking)
+
+		if im.IsTriggered(win.TOGGLE_PICK_MODE) {
+			pickWithIDs = !pickWithIDs
+			mode := "ray casting"
+			if pickWithIDs {
+				mode = "ID buffer"
+			}
+			window.SetTitle("Picking - " + mode)
+		}
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+		index.Update()
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		if window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0 {
+			if err := idBuffer.Resize(window.FramebufferWidth(), window.FramebufferHeight()); err != nil {
+				return err
+			}
+		}
+
+		aspect := float32(window.Width()) / float32(window.Height())
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(aspect)
+
+		frustum := geom.NewFrustum(projectTransform.Mul4(camTransform))
+		clicked := !looking && im.IsButtonTriggered(glfw.MouseButtonLeft)
+
+		if clicked && !pickWithIDs {
+			cursor := im.Cursor()
+			ray := geom.NewRayFromScreen(float32(cursor.X()), float32(cursor.Y()),
+				window.Width(), window.Height(), projectTransform, camTransform)
+
+			hit, hasHit = index.Pick(ray, lens.Far)
+			selected = hit.Node
+			if hasHit {
+				window.SetTitle(fmt.Sprintf("Picking - %s at (%.2f, %.2f, %.2f), normal (%.2f, %.2f, %.2f), %.2f away",
+					hit.Node.Name, hit.Point.X(), hit.Point.Y(), hit.Point.Z(),
+					hit.Normal.X(), hit.Normal.Y(), hit.Normal.Z(), hit.Dist))
+			} else {
+				window.SetTitle("Picking - nothing under the cursor")
+			}
+		}
+
+		if clicked && pickWithIDs {
+			// the IDs only have to be drawn when there is something to read back
+			idBuffer.Bind()
+			idProgram.Use()
+			gl.UniformMatrix4fv(idProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+			gl.UniformMatrix4fv(idProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+			idNodes = idNodes[:0]
+			objectIDLoc := idProgram.GetUniformLocation("objectID")
+			draw(idProgram, &frustum, func(n *scene.Node) {
+				idNodes = append(idNodes, n)
+				gl.Uniform1ui(objectIDLoc, uint32(len(idNodes)))
+			})
+
+			// the cursor is in screen units and the IDs are in pixels, they differ on high DPI screens
+			cursor := im.Cursor()
+			scaleX := float64(window.FramebufferWidth()) / float64(window.Width())
+			scaleY := float64(window.FramebufferHeight()) / float64(window.Height())
+			idBuffer.Read(int(cursor.X()*scaleX), int(cursor.Y()*scaleY), pickRadius)
+			idBuffer.UnBind()
+		}
+
+		// a read started on an earlier frame may have finished
+		if id, ready, err := idBuffer.Result(); err != nil {
+			return err
+		} else if ready {
+			hasHit = false
+			selected = nil
+			if id != gfx.NoID {
+				selected = idNodes[id-1]
+				window.SetTitle("Picking - " + selected.Name + " (ID buffer)")
+			} else {
+				window.SetTitle("Picking - nothing under the cursor (ID buffer)")
+			}
+		}
+
+		// background color
+		gl.ClearColor(0.1, 0.1, 0.12, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		// the scene gives back the lights in world space, they then convert themselves
+		// to view space using the camera transform
+		lights := world.Lights()
+
+		gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+		if len(lights.Directional) > 0 {
+			lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+		}
+		gl.Uniform1i(program.GetUniformLocation("numPointLights"), 0)
+		gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), 0)
+
+		highlightLoc := program.GetUniformLocation("highlight")
+		draw(program, &frustum, func(n *scene.Node) {
+			color := mgl32.Vec3{}
+			if selected != nil && n == selected {
+				color = selectedColor
+			}
+			gl.Uniform3f(highlightLoc, color.X(), color.Y(), color.Z())
+		})
+
+		if hasHit {
+			gl.Uniform1i(program.GetUniformLocation("alphaTested"), 0)
+			drawMarker(program, hit, cube, sphere)
+		}
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+/*
+ * Draws a small ball at the hit point with a stick pointing along the normal.
+ * The marker is not part of the scene so it can never be picked itself.
+ */
+func drawMarker(program *gfx.Program, hit scene.Hit, cube, sphere *gfx.VertexArray) {
+	modelLoc := program.GetUniformLocation("model")
+	gl.Uniform3f(program.GetUniformLocation("highlight"), markerColor.X(), markerColor.Y(), markerColor.Z())
+
+	ball := mgl32.Translate3D(hit.Point.X(), hit.Point.Y(), hit.Point.Z()).
+		Mul4(mgl32.Scale3D(0.1, 0.1, 0.1))
+	gl.UniformMatrix4fv(modelLoc, 1, false, &ball[0])
+	sphere.Bind()
+	sphere.Draw()
+
+	// a thin cube along +y, rotated onto the normal and moved up so it starts at the point
+	const stickLength = 0.6
+	toNormal := mgl32.QuatBetweenVectors(mgl32.Vec3{0, 1, 0}, hit.Normal)
+	center := hit.Point.Add(hit.Normal.Mul(stickLength / 2))
+	stick := mgl32.Translate3D(center.X(), center.Y(), center.Z()).
+		Mul4(toNormal.Mat4()).
+		Mul4(mgl32.Scale3D(0.03, stickLength, 0.03))
+	gl.UniformMatrix4fv(modelLoc, 1, false, &stick[0])
+	cube.Bind()
+	cube.Draw()
+
+	gl.BindVertexArray(0)
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
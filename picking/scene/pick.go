@@ -0,0 +1,63 @@
+package scene
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/picking/geom"
+)
+
+// Hit is where a ray hit a node's mesh, in world space.
+type Hit struct {
+	Node     *Node
+	Dist     float32 // along the ray
+	Point    mgl32.Vec3
+	Normal   mgl32.Vec3 // of the triangle that was hit, facing back towards the ray
+	Triangle int        // index into the node's mesh, see gfx.Mesh.Triangle
+}
+
+// Pick finds the triangle of an indexed node that r hits first within maxDist.
+// The index narrows the search down to nodes whose bounds r passes through, nearest first,
+// and only those nodes have their triangles tested.
+func (idx *Index) Pick(r geom.Ray, maxDist float32) (Hit, bool) {
+	var hit Hit
+	bestDist := maxDist
+
+	id, dist, ok := idx.tree.QueryRay(r, maxDist, func(id int) (float32, bool) {
+		n := idx.node(id)
+
+		// test in model space so the mesh does not have to be transformed, the ray's direction
+		// is not normalized there so distances still match world space
+		local := r.Transform(n.WorldTransform().Inv())
+		if _, ok := local.IntersectAABB(n.Mesh.Bounds()); !ok {
+			return 0, false
+		}
+		dist, triangle, ok := n.Mesh.Mesh().IntersectRay(local)
+		if !ok {
+			return 0, false
+		}
+
+		// keep the details of the closest hit the same way the tree picks it
+		if dist <= bestDist {
+			bestDist = dist
+			hit = Hit{Node: n, Triangle: triangle}
+		}
+		return dist, true
+	})
+	if !ok {
+		return Hit{}, false
+	}
+
+	hit.Node = idx.node(id)
+	hit.Dist = dist
+	hit.Point = r.At(dist)
+
+	// normals transform by the inverse transpose to stay perpendicular under non-uniform scale
+	local := hit.Node.Mesh.Mesh().TriangleNormal(hit.Triangle)
+	normal := hit.Node.WorldTransform().Mat3().Inv().Transpose().Mul3x1(local).Normalize()
+	if normal.Dot(r.Direction) > 0 {
+		normal = normal.Mul(-1)
+	}
+	hit.Normal = normal
+
+	return hit, true
+}
@@ -0,0 +1,26 @@
+#version 410 core
+
+// writes the ID of the object being drawn for picking, see gfx/idbuffer.go
+// this runs after the same vertex shader as the lit pass so everything lines up
+
+in vec2 TexCoords;
+out uint id;
+
+uniform uint objectID;
+
+// only the diffuse map of phong.frag's material is needed, for its alpha
+struct Material {
+	sampler2D diffuse;
+};
+
+// must cut out the same pixels as phong.frag so clicking through a hole misses
+uniform bool alphaTested;
+uniform Material material;
+
+void main()
+{
+	if (alphaTested && texture(material.diffuse, TexCoords).a < 0.5) {
+		discard;
+	}
+	id = objectID;
+}
@@ -0,0 +1,224 @@
+package gfx
+
+import (
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// PostShaderDir is where passes load shaders/post/fullscreen.vert and the ready made effects
+// (see effects.go) load their fragment shaders from. Like every other shader path it is
+// relative to the working directory.
+var PostShaderDir = "shaders/post"
+
+// Pass is one fullscreen draw of a PostProcessor. Its fragment shader reads the output of the
+// previous pass from the "screenTexture" sampler at TexCoords and writes the new color.
+type Pass struct {
+	Name string
+
+	// disabled passes are skipped as if they were not in the chain
+	Enabled bool
+
+	program *Program
+
+	// sets the pass's own uniforms with its program in use, may be nil
+	uniforms func(prog *Program)
+}
+
+// NewPass compiles the fragment shader in fragFile with the fullscreen triangle vertex shader.
+// uniforms is called every time the pass is drawn to set anything besides screenTexture.
+func NewPass(name, fragFile string, uniforms func(prog *Program)) (*Pass, error) {
+	vertShader, err := NewShaderFromFile(filepath.Join(PostShaderDir, "fullscreen.vert"), gl.VERTEX_SHADER)
+	if err != nil {
+		return nil, err
+	}
+
+	fragShader, err := NewShaderFromFile(fragFile, gl.FRAGMENT_SHADER)
+	if err != nil {
+		vertShader.Delete()
+		return nil, err
+	}
+
+	prog, err := NewProgram(vertShader, fragShader)
+	if err != nil {
+		vertShader.Delete()
+		fragShader.Delete()
+		return nil, err
+	}
+
+	return &Pass{Name: name, Enabled: true, program: prog, uniforms: uniforms}, nil
+}
+
+// draw runs the pass over input into whatever framebuffer is bound
+func (p *Pass) draw(input *Texture, vao uint32) {
+	p.program.Use()
+	input.Bind(gl.TEXTURE0)
+	input.SetUniform(p.program.GetUniformLocation("screenTexture"))
+	if p.uniforms != nil {
+		p.uniforms(p.program)
+	}
+
+	gl.BindVertexArray(vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+
+	input.UnBind()
+}
+
+func (p *Pass) Delete() {
+	p.program.Delete()
+}
+
+// PostProcessor renders a scene into an offscreen framebuffer and then runs it through a chain
+// of fullscreen passes before it reaches the window. Drawing code does not need to know about
+// it, only be wrapped with Begin and End:
+//
+//	post.Begin()
+//	... clear and draw the scene as usual ...
+//	post.End() // runs the passes into the framebuffer that was bound before Begin
+//
+// Each pass reads the previous one's output. Two framebuffers are used in turn ("ping-pong")
+// for the outputs in the middle of the chain since a texture can not be read while it is
+// being drawn to. The last pass draws straight to the destination.
+type PostProcessor struct {
+	// run in order, they can be added, removed, reordered, enabled or disabled between frames
+	Passes []*Pass
+
+	scene    *Framebuffer
+	pingPong [2]*Framebuffer
+
+	// copies the scene to the destination when no pass is enabled
+	copyPass *Pass
+
+	// the fullscreen triangle's positions come from gl_VertexID (see fullscreen.vert) but
+	// the core profile still requires a vertex array to be bound to draw
+	vao uint32
+}
+
+// NewPostProcessor makes the offscreen framebuffers at the given size, usually the window's.
+// The scene framebuffer has a depth/stencil buffer so it can be drawn to like the window.
+func NewPostProcessor(width, height int) (*PostProcessor, error) {
+	pp := PostProcessor{}
+
+	var err error
+	pp.scene, err = NewFramebuffer(width, height, FramebufferSpec{
+		Color: []Attachment{{Format: gl.RGBA8}},
+		Depth: &Attachment{Format: gl.DEPTH24_STENCIL8, Renderbuffer: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range pp.pingPong {
+		pp.pingPong[i], err = NewFramebuffer(width, height, FramebufferSpec{
+			Color: []Attachment{{Format: gl.RGBA8}},
+		})
+		if err != nil {
+			pp.Delete()
+			return nil, err
+		}
+	}
+
+	pp.copyPass, err = NewPass("copy", filepath.Join(PostShaderDir, "copy.frag"), nil)
+	if err != nil {
+		pp.Delete()
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &pp.vao)
+
+	return &pp, nil
+}
+
+// Add appends passes to the end of the chain.
+func (pp *PostProcessor) Add(passes ...*Pass) {
+	pp.Passes = append(pp.Passes, passes...)
+}
+
+// Move takes the pass at index from out of the chain and puts it back in at index to.
+func (pp *PostProcessor) Move(from, to int) {
+	pass := pp.Passes[from]
+	pp.Passes = append(pp.Passes[:from], pp.Passes[from+1:]...)
+	pp.Passes = append(pp.Passes[:to], append([]*Pass{pass}, pp.Passes[to:]...)...)
+}
+
+// Enabled returns the passes that End will run, in order.
+func (pp *PostProcessor) Enabled() []*Pass {
+	var enabled []*Pass
+	for _, pass := range pp.Passes {
+		if pass.Enabled {
+			enabled = append(enabled, pass)
+		}
+	}
+	return enabled
+}
+
+// Resize reallocates the framebuffers, ex: after the window changed size.
+func (pp *PostProcessor) Resize(width, height int) error {
+	if err := pp.scene.Resize(width, height); err != nil {
+		return err
+	}
+	for _, fb := range pp.pingPong {
+		if err := fb.Resize(width, height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Begin redirects drawing into the scene framebuffer until End.
+func (pp *PostProcessor) Begin() {
+	pp.scene.Bind()
+}
+
+// End goes back to the framebuffer bound before Begin and draws the scene into it through
+// every enabled pass.
+func (pp *PostProcessor) End() {
+	pp.scene.UnBind()
+
+	// every pass covers the whole screen so depth has nothing to sort out
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+
+	passes := pp.Enabled()
+	if len(passes) == 0 {
+		passes = []*Pass{pp.copyPass}
+	}
+
+	input := pp.scene.Color(0)
+	for i, pass := range passes {
+		if i == len(passes)-1 {
+			pass.draw(input, pp.vao)
+			break
+		}
+
+		output := pp.pingPong[i%2]
+		output.Bind()
+		pass.draw(input, pp.vao)
+		output.UnBind()
+		input = output.Color(0)
+	}
+
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+// Delete frees the framebuffers and every pass, including the ones in Passes.
+func (pp *PostProcessor) Delete() {
+	for _, pass := range pp.Passes {
+		pass.Delete()
+	}
+	if pp.copyPass != nil {
+		pp.copyPass.Delete()
+	}
+	for _, fb := range pp.pingPong {
+		if fb != nil {
+			fb.Delete()
+		}
+	}
+	if pp.scene != nil {
+		pp.scene.Delete()
+	}
+	gl.DeleteVertexArrays(1, &pp.vao)
+}
@@ -0,0 +1,354 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-OpenGL/Framebuffers
+
+The lit crates are drawn exactly as in the lighting samples except that the drawing is wrapped
+with gfx.PostProcessor's Begin and End. Begin sends it to an offscreen framebuffer and End runs
+that image through a chain of fullscreen passes on the way to the window.
+
+Press 1 to 7 to toggle the effects and O to move the first effect to the end of the chain,
+the order is shown in the title with the enabled effects in brackets.
+*/
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/post-processing/cam"
+	"github.com/cstegel/opengl-samples-golang/post-processing/gfx"
+	"github.com/cstegel/opengl-samples-golang/post-processing/scene"
+	"github.com/cstegel/opengl-samples-golang/post-processing/win"
+)
+
+var cubePositions = [][]float32{
+	{0.0, 0.0, -3.0},
+	{2.0, 5.0, -15.0},
+	{-1.5, -2.2, -2.5},
+	{-3.8, -2.0, -12.3},
+	{2.4, -0.4, -3.5},
+	{-1.7, 3.0, -7.5},
+	{1.3, -2.0, -2.5},
+	{1.5, 2.0, -2.5},
+	{1.5, 0.2, -1.5},
+	{-1.3, 1.0, -1.5},
+}
+
+// colored point lights so the color effects have something to work with
+var pointLightPositions = []mgl32.Vec3{
+	{0.7, 0.2, 2.0},
+	{2.3, -3.3, -4.0},
+	{-4.0, 2.0, -12.0},
+}
+
+var pointLightColors = []mgl32.Vec3{
+	{1.0, 0.3, 0.2},
+	{0.2, 1.0, 0.4},
+	{0.3, 0.4, 1.0},
+}
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 8
+
+// the keys that toggle the passes by their place in the chain
+var togglePassActions = []win.Action{
+	win.TOGGLE_PASS_1,
+	win.TOGGLE_PASS_2,
+	win.TOGGLE_PASS_3,
+	win.TOGGLE_PASS_4,
+	win.TOGGLE_PASS_5,
+	win.TOGGLE_PASS_6,
+	win.TOGGLE_PASS_7,
+}
+
+func buildScene(cube *gfx.VertexArray) (*scene.Scene, []*scene.Node, *scene.Node) {
+	s := scene.New()
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.05, 0.05, 0.05},
+		Diffuse:   mgl32.Vec3{0.3, 0.3, 0.3},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	for i, pos := range pointLightPositions {
+		light := s.Add(scene.NewNode(fmt.Sprintf("point light %d", i)))
+		light.SetPosition(pos)
+		light.PointLight = &gfx.PointLight{
+			Ambient:     pointLightColors[i].Mul(0.05),
+			Diffuse:     pointLightColors[i],
+			Specular:    pointLightColors[i],
+			Attenuation: gfx.AttenuationForDistance(20),
+		}
+	}
+
+	// the spot light points down the node's -z like a camera
+	flashlight := s.Add(scene.NewNode("flashlight"))
+	flashlight.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	var cubes []*scene.Node
+	for i, pos := range cubePositions {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{pos[0], pos[1], pos[2]})
+		node.Mesh = cube
+		cubes = append(cubes, node)
+	}
+
+	return s, cubes, flashlight
+}
+
+// makes every ready made effect, all disabled to start with
+func newPasses() ([]*gfx.Pass, error) {
+	var passes []*gfx.Pass
+	add := func(pass *gfx.Pass, err error) error {
+		if err != nil {
+			return err
+		}
+		pass.Enabled = false
+		passes = append(passes, pass)
+		return nil
+	}
+
+	err := add(gfx.NewGrayscalePass())
+	if err == nil {
+		err = add(gfx.NewInvertPass())
+	}
+	if err == nil {
+		err = add(gfx.NewSharpenPass())
+	}
+	if err == nil {
+		err = add(gfx.NewKernelPass("blur", gfx.BlurKernel))
+	}
+	if err == nil {
+		err = add(gfx.NewKernelPass("edges", gfx.EdgeDetectKernel))
+	}
+	if err == nil {
+		err = add(gfx.NewKernelPass("emboss", gfx.EmbossKernel))
+	}
+	if err == nil {
+		err = add(gfx.NewVignettePass(0.4, 0.5))
+	}
+
+	if err != nil {
+		for _, pass := range passes {
+			pass.Delete()
+		}
+		return nil, err
+	}
+	return passes, nil
+}
+
+// the chain in order with the enabled passes in brackets, ex: "1 grayscale  2 [vignette]"
+func describeChain(passes []*gfx.Pass) string {
+	names := make([]string, len(passes))
+	for i, pass := range passes {
+		name := pass.Name
+		if pass.Enabled {
+			name = "[" + name + "]"
+		}
+		names[i] = fmt.Sprintf("%d %s", i+1, name)
+	}
+	return strings.Join(names, "  ")
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Post-processing")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	// the offscreen framebuffers are in pixels, which are smaller than the window's units on
+	// high DPI screens
+	post, err := gfx.NewPostProcessor(window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+	defer post.Delete()
+
+	passes, err := newPasses()
+	if err != nil {
+		return err
+	}
+	post.Add(passes...)
+
+	world, cubes, flashlight := buildScene(cube)
+
+	// lens for the camera
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 100}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		if window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0 {
+			if err := post.Resize(window.FramebufferWidth(), window.FramebufferHeight()); err != nil {
+				return err
+			}
+		}
+
+		for i, action := range togglePassActions {
+			if i < len(post.Passes) && window.InputManager().IsTriggered(action) {
+				post.Passes[i].Enabled = !post.Passes[i].Enabled
+			}
+		}
+		if window.InputManager().IsTriggered(win.ROTATE_PASSES) && len(post.Passes) > 0 {
+			post.Move(0, len(post.Passes)-1)
+		}
+		window.SetTitle("Post-processing - " + describeChain(post.Passes))
+
+		// animate the nodes
+		time := float32(glfw.GetTime())
+		for _, node := range cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+
+		// the flashlight is held by the camera
+		flashlight.SetPosition(camera.Position())
+		flashlight.LookAt(camera.Position().Add(camera.Front()), mgl32.Vec3{0, 1, 0})
+
+		// everything until End is drawn to the post processor instead of the window
+		post.Begin()
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		program.Use()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(float32(window.FramebufferWidth()) / float32(window.FramebufferHeight()))
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		setLightUniforms(program, world.Lights(), camTransform)
+		world.Draw(program, nil)
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// the effects are drawn to the window
+		post.End()
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+// sets the lights in view space, a scene may have no sun or flashlight
+func setLightUniforms(program *gfx.Program, lights scene.Lights, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	numPointLights := len(lights.Point)
+	if numPointLights > maxPointLights {
+		numPointLights = maxPointLights
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+	for i := 0; i < numPointLights; i++ {
+		lights.Point[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+	}
+
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+#version 410 core
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D screenTexture;
+
+void main()
+{
+	vec4 texel = texture(screenTexture, TexCoords);
+
+	// the eye is most sensitive to green and least to blue so an even average looks wrong
+	float luminance = dot(texel.rgb, vec3(0.2126, 0.7152, 0.0722));
+	color = vec4(vec3(luminance), texel.a);
+}
@@ -0,0 +1,195 @@
+package win
+
+import (
+	"log"
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+)
+
+type Window struct {
+	width int
+	height int
+	glfw *glfw.Window
+
+	// the framebuffer is in pixels while the window is in screen units, they are not the same
+	// on high DPI screens
+	framebufferWidth int
+	framebufferHeight int
+	framebufferResized bool
+
+	inputManager *InputManager
+	cursorCaptured bool
+	gammaCorrection bool
+	firstFrame bool
+	dTime float64
+	lastFrameTime float64
+}
+
+func (w *Window) InputManager() *InputManager {
+	return w.inputManager
+}
+
+func NewWindow(width, height int, title string) *Window {
+
+	// ask for a window that can convert the linear colors shaders write to sRGB,
+	// see SetGammaCorrection
+	glfw.WindowHint(glfw.SRGBCapable, glfw.True)
+
+	gWindow, err := glfw.CreateWindow(width, height, title, nil, nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	gWindow.MakeContextCurrent()
+	gWindow.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+
+	im := NewInputManager()
+
+	gWindow.SetKeyCallback(im.keyCallback)
+	gWindow.SetCursorPosCallback(im.mouseCallback)
+	gWindow.SetMouseButtonCallback(im.mouseButtonCallback)
+
+	w := &Window{
+		width: width,
+		height: height,
+		glfw: gWindow,
+		inputManager: im,
+		cursorCaptured: true,
+		gammaCorrection: true,
+		firstFrame: true,
+	}
+	w.framebufferWidth, w.framebufferHeight = gWindow.GetFramebufferSize()
+	gWindow.SetFramebufferSizeCallback(w.framebufferSizeCallback)
+
+	return w
+}
+
+func (w *Window) framebufferSizeCallback(window *glfw.Window, width, height int) {
+	w.framebufferWidth = width
+	w.framebufferHeight = height
+	w.framebufferResized = true
+}
+
+func (w *Window) Width() int {
+	return w.width
+}
+
+func (w *Window) Height() int {
+	return w.height
+}
+
+// FramebufferWidth is the width in pixels of the window's framebuffer, anything drawn to the
+// window should be sized from it rather than from Width.
+func (w *Window) FramebufferWidth() int {
+	return w.framebufferWidth
+}
+
+func (w *Window) FramebufferHeight() int {
+	return w.framebufferHeight
+}
+
+// FramebufferResized reports whether the window's framebuffer changed size during the last
+// StartFrame, ex: when the window moved to a screen with a different DPI.
+func (w *Window) FramebufferResized() bool {
+	return w.framebufferResized
+}
+
+// CursorCaptured returns whether the cursor is hidden and locked to the window
+// so that moving the mouse turns the camera.
+func (w *Window) CursorCaptured() bool {
+	return w.cursorCaptured
+}
+
+// SetCursorCaptured switches between the captured cursor used for looking around
+// and a normal cursor that can point at things on screen.
+func (w *Window) SetCursorCaptured(captured bool) {
+	if captured == w.cursorCaptured {
+		return
+	}
+	w.cursorCaptured = captured
+
+	if captured {
+		w.glfw.SetInputMode(glfw.CursorMode, glfw.CursorDisabled)
+	} else {
+		w.glfw.SetInputMode(glfw.CursorMode, glfw.CursorNormal)
+	}
+	w.inputManager.setCursorCaptured(captured)
+}
+
+func (w *Window) SetTitle(title string) {
+	w.glfw.SetTitle(title)
+}
+
+func (w *Window) ShouldClose() bool {
+	return w.glfw.ShouldClose()
+}
+
+// StartFrame sets everything up to start rendering a new frame.
+// This includes swapping in last rendered buffer, polling for window events,
+// checkpointing cursor tracking, and updating the time since last frame.
+func (w *Window) StartFrame() {
+	// swap in the previous rendered buffer
+	w.glfw.SwapBuffers()
+
+	// poll for UI window events
+	w.framebufferResized = false
+	glfw.PollEvents()
+
+	// the viewport keeps the size the context was created with otherwise
+	if w.framebufferResized {
+		gl.Viewport(0, 0, int32(w.framebufferWidth), int32(w.framebufferHeight))
+	}
+
+	if w.inputManager.IsActive(PROGRAM_QUIT) {
+		w.glfw.SetShouldClose(true)
+	}
+
+	// base calculations of time since last frame (basic program loop idea)
+	// For better advanced impl, read: http://gafferongames.com/game-physics/fix-your-timestep/
+	curFrameTime  := glfw.GetTime()
+
+	if w.firstFrame {
+		w.lastFrameTime = curFrameTime
+		w.firstFrame = false
+	}
+
+	w.dTime          = curFrameTime - w.lastFrameTime
+	w.lastFrameTime  = curFrameTime
+
+	w.inputManager.CheckpointCursorChange()
+	w.inputManager.CheckpointKeys()
+
+	if w.inputManager.IsTriggered(TOGGLE_GAMMA_CORRECTION) {
+		w.gammaCorrection = !w.gammaCorrection
+	}
+	w.applyGammaCorrection()
+}
+
+func (w *Window) SinceLastFrame() float64 {
+	return w.dTime
+}
+
+// GammaCorrection returns whether colors written to the window are converted to sRGB.
+func (w *Window) GammaCorrection() bool {
+	return w.gammaCorrection
+}
+
+// SetGammaCorrection turns the conversion of colors written to the window from linear to sRGB
+// on or off, it takes effect at the next StartFrame. It starts on and G toggles it to compare.
+//
+// Lighting is only correct with linear values and textures are decoded from sRGB to linear
+// when they are sampled (see gfx/texture.go) but monitors expect sRGB. Without the conversion
+// everything looks too dark and light falls off too quickly.
+func (w *Window) SetGammaCorrection(enabled bool) {
+	w.gammaCorrection = enabled
+}
+
+// applyGammaCorrection sets up GL for the gamma correction setting, it can only be done once
+// GL has been initialized so it is done every frame
+func (w *Window) applyGammaCorrection() {
+	if w.gammaCorrection {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	} else {
+		gl.Disable(gl.FRAMEBUFFER_SRGB)
+	}
+}
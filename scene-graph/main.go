@@ -0,0 +1,402 @@
+package main
+
+/*
+Builds on light-casters, moving the cubes, lights and cameras into a scene graph
+(see the scene package). Every object is a node with a transform relative to its parent
+so a moon carrying its own light can orbit a spinning cube without any special math.
+
+Press V to switch between the free camera and a camera riding along with the moon.
+*/
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/scene-graph/gfx"
+	"github.com/cstegel/opengl-samples-golang/scene-graph/win"
+	"github.com/cstegel/opengl-samples-golang/scene-graph/cam"
+	"github.com/cstegel/opengl-samples-golang/scene-graph/scene"
+)
+
+// vertices to draw 6 faces of a cube
+var cubeVertices = []float32{
+	// position        // normal vector    // texture position
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+	 0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  1.0, 1.0,
+	-0.5,  0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5,  0.0,  0.0, -1.0,  0.0, 0.0,
+
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  1.0, 1.0,
+	-0.5,  0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5,  0.0,  0.0,  1.0,  0.0, 0.0,
+
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5, -0.5, -1.0,  0.0,  0.0,  1.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5, -0.5, -1.0,  0.0,  0.0,  0.0, 1.0,
+	-0.5, -0.5,  0.5, -1.0,  0.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5,  0.5, -1.0,  0.0,  0.0,  1.0, 0.0,
+
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5, -0.5,  1.0,  0.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  1.0,  0.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5,  0.5,  1.0,  0.0,  0.0,  0.0, 0.0,
+	 0.5,  0.5,  0.5,  1.0,  0.0,  0.0,  1.0, 0.0,
+
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+	 0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  1.0, 1.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	 0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  1.0, 0.0,
+	-0.5, -0.5,  0.5,  0.0, -1.0,  0.0,  0.0, 0.0,
+	-0.5, -0.5, -0.5,  0.0, -1.0,  0.0,  0.0, 1.0,
+
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+	 0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  1.0, 1.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	 0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  1.0, 0.0,
+	-0.5,  0.5,  0.5,  0.0,  1.0,  0.0,  0.0, 0.0,
+	-0.5,  0.5, -0.5,  0.0,  1.0,  0.0,  0.0, 1.0,
+}
+
+var cubePositions = [][]float32 {
+	{ 0.0,  0.0,  -3.0},
+	{ 2.0,  5.0, -15.0},
+	{-1.5, -2.2, -2.5 },
+	{-3.8, -2.0, -12.3},
+	{ 2.4, -0.4, -3.5 },
+	{-1.7,  3.0, -7.5 },
+	{ 1.3, -2.0, -2.5 },
+	{ 1.5,  2.0, -2.5 },
+	{ 1.5,  0.2, -1.5 },
+	{-1.3,  1.0, -1.5 },
+}
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 8
+
+// colored point lights spread around the cubes
+var pointLights = []gfx.PointLight{
+	{
+		Ambient:     mgl32.Vec3{0.05, 0.05, 0.05},
+		Diffuse:     mgl32.Vec3{0.8, 0.8, 0.8},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Ambient:     mgl32.Vec3{0.05, 0.0, 0.0},
+		Diffuse:     mgl32.Vec3{0.8, 0.1, 0.1},
+		Specular:    mgl32.Vec3{1.0, 0.2, 0.2},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Ambient:     mgl32.Vec3{0.0, 0.05, 0.0},
+		Diffuse:     mgl32.Vec3{0.1, 0.8, 0.1},
+		Specular:    mgl32.Vec3{0.2, 1.0, 0.2},
+		Attenuation: gfx.AttenuationForDistance(50),
+	},
+	{
+		Ambient:     mgl32.Vec3{0.0, 0.0, 0.05},
+		Diffuse:     mgl32.Vec3{0.1, 0.1, 0.8},
+		Specular:    mgl32.Vec3{0.2, 0.2, 1.0},
+		Attenuation: gfx.AttenuationForDistance(20),
+	},
+}
+
+var pointLightPositions = []mgl32.Vec3{
+	{0.7, 0.2, 2.0},
+	{2.3, -3.3, -4.0},
+	{-4.0, 2.0, -12.0},
+	{-1.0, -0.5, -5.0},
+}
+
+// nodes that are animated or looked up every frame
+type sceneNodes struct {
+	cubes      []*scene.Node
+	moonOrbit  *scene.Node
+	flashlight *scene.Node
+	moonCamera *scene.Node
+}
+
+/*
+ * Builds the scene:
+ *
+ *   root
+ *   ├── sun                  directional light
+ *   ├── flashlight           spot light that follows the free camera
+ *   ├── light 0..3           point lights with a small gizmo cube
+ *   └── cube 0..9            spinning crates
+ *       └── moon orbit       (cube 0 only) spins around the crate
+ *           ├── moon         small crate
+ *           │   └── lamp     point light carried by the moon
+ *           └── moon camera  looks back at the crate
+ */
+func buildScene(cube *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.2, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.05, 0.05, 0.08},
+		Diffuse:   mgl32.Vec3{0.2, 0.2, 0.3},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	// the spot light points down the node's -z like a camera
+	nodes.flashlight = s.Add(scene.NewNode("flashlight"))
+	nodes.flashlight.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 12.5,
+		OuterCutoff: 17.5,
+		Diffuse:     mgl32.Vec3{1.0, 1.0, 1.0},
+		Specular:    mgl32.Vec3{1.0, 1.0, 1.0},
+		Attenuation: gfx.AttenuationForDistance(32),
+	}
+
+	for i := range pointLights {
+		light := s.Add(scene.NewNode(fmt.Sprintf("light %d", i)))
+		light.SetPosition(pointLightPositions[i])
+		light.SetScale(mgl32.Vec3{0.2, 0.2, 0.2})
+		light.PointLight = &pointLights[i]
+		light.Mesh = cube
+	}
+
+	for i, pos := range cubePositions {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{pos[0], pos[1], pos[2]})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	// the orbit node has no mesh, it only exists so that spinning it carries its children around
+	nodes.moonOrbit = nodes.cubes[0].AddChild(scene.NewNode("moon orbit"))
+
+	moon := nodes.moonOrbit.AddChild(scene.NewNode("moon"))
+	moon.SetPosition(mgl32.Vec3{1.5, 0, 0})
+	moon.SetScale(mgl32.Vec3{0.3, 0.3, 0.3})
+	moon.Mesh = cube
+
+	// children inherit the moon's scale so the gizmo ends up 0.3 * 0.5 = 0.15 across
+	lamp := moon.AddChild(scene.NewNode("lamp"))
+	lamp.SetPosition(mgl32.Vec3{0, 1.5, 0})
+	lamp.SetScale(mgl32.Vec3{0.5, 0.5, 0.5})
+	lamp.Mesh = cube
+	lamp.PointLight = &gfx.PointLight{
+		Ambient:     mgl32.Vec3{0.05, 0.04, 0.0},
+		Diffuse:     mgl32.Vec3{0.9, 0.7, 0.2},
+		Specular:    mgl32.Vec3{1.0, 0.8, 0.3},
+		Attenuation: gfx.AttenuationForDistance(13),
+	}
+
+	nodes.moonCamera = nodes.moonOrbit.AddChild(scene.NewNode("moon camera"))
+	nodes.moonCamera.SetPosition(mgl32.Vec3{2.5, 1.0, 0})
+	nodes.moonCamera.LookAt(mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	nodes.moonCamera.Camera = &scene.Camera{Fov: 75, Near: 0.1, Far: 100}
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Scene graph")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+
+	cube, err := gfx.NewMeshFromInterleaved(cubeVertices, true, true).Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	world, nodes := buildScene(cube)
+
+	// lens for the free camera, the moon camera brings its own
+	freeCamera := scene.Camera{Fov: 60, Near: 0.1, Far: 100}
+	useMoonCamera := false
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		if window.InputManager().IsTriggered(win.SWITCH_CAMERA) {
+			useMoonCamera = !useMoonCamera
+		}
+
+		// animate the nodes, their children follow along
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+		nodes.moonOrbit.SetRotation(mgl32.QuatRotate(time, mgl32.Vec3{0, 1, 0}))
+
+		// the flashlight is held by the free camera
+		nodes.flashlight.SetPosition(camera.Position())
+		nodes.flashlight.LookAt(camera.Position().Add(camera.Front()), mgl32.Vec3{0, 1, 0})
+		nodes.flashlight.Hidden = useMoonCamera
+
+		// background color
+		gl.ClearColor(0, 0, 0, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)  // depth buffer needed for DEPTH_TEST
+
+		aspect := float32(window.Width())/float32(window.Height())
+		camTransform := camera.GetTransform()
+		projectTransform := freeCamera.Projection(aspect)
+		if useMoonCamera {
+			camTransform = nodes.moonCamera.ViewTransform()
+			projectTransform = nodes.moonCamera.Camera.Projection(aspect)
+		}
+
+		program.Use()
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false,
+		                    &projectTransform[0])
+
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		// the scene gives back the lights in world space, they then convert themselves
+		// to view space using the camera transform
+		lights := world.Lights()
+
+		gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+		if len(lights.Directional) > 0 {
+			lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+		}
+
+		numPointLights := len(lights.Point)
+		if numPointLights > maxPointLights {
+			numPointLights = maxPointLights
+		}
+		for i := 0; i < numPointLights; i++ {
+			lights.Point[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+		}
+		gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+
+		gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+		if len(lights.Spot) > 0 {
+			lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+		}
+
+		// everything except the light gizmos is lit
+		world.Draw(program, func(n *scene.Node) bool {
+			return n.PointLight == nil
+		})
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// Draw the point lights after the other boxes using their separate shader program
+		// this means that we must re-bind any uniforms
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(lightProgram, func(n *scene.Node) bool {
+			if n.PointLight == nil {
+				return false
+			}
+			light := n.PointLight
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"),
+			             light.Diffuse.X(), light.Diffuse.Y(), light.Diffuse.Z())
+			return true
+		})
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package geom
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Sphere is a bounding sphere. It is a looser fit than an AABB for most meshes but
+// cheaper to test and it does not change when the object rotates.
+type Sphere struct {
+	Center mgl32.Vec3
+	Radius float32
+}
+
+// NewSphereFromPoints finds a sphere around all of points with Ritter's algorithm,
+// which is within about 5-20% of the smallest possible sphere.
+func NewSphereFromPoints(points []mgl32.Vec3) Sphere {
+	if len(points) == 0 {
+		return Sphere{}
+	}
+
+	// start with the sphere through two points that are far apart:
+	// the point furthest from an arbitrary point and the point furthest from that one
+	a := furthest(points, points[0])
+	b := furthest(points, a)
+	s := Sphere{Center: a.Add(b).Mul(0.5), Radius: b.Sub(a).Len() / 2}
+
+	// grow it just enough to cover any point left outside
+	for _, p := range points {
+		s = s.Extend(p)
+	}
+	return s
+}
+
+func furthest(points []mgl32.Vec3, from mgl32.Vec3) mgl32.Vec3 {
+	best, bestDist := from, float32(-1)
+	for _, p := range points {
+		if dist := p.Sub(from).LenSqr(); dist > bestDist {
+			best, bestDist = p, dist
+		}
+	}
+	return best
+}
+
+// Extend returns the smallest sphere containing s and p.
+func (s Sphere) Extend(p mgl32.Vec3) Sphere {
+	toPoint := p.Sub(s.Center)
+	dist := toPoint.Len()
+	if dist <= s.Radius {
+		return s
+	}
+
+	// the new sphere touches the far side of the old one and p
+	radius := (s.Radius + dist) / 2
+	center := s.Center.Add(toPoint.Mul((radius - s.Radius) / dist))
+	return Sphere{Center: center, Radius: radius}
+}
+
+func (s Sphere) Contains(p mgl32.Vec3) bool {
+	return p.Sub(s.Center).LenSqr() <= s.Radius*s.Radius
+}
+
+func (s Sphere) Intersects(other Sphere) bool {
+	r := s.Radius + other.Radius
+	return s.Center.Sub(other.Center).LenSqr() <= r*r
+}
+
+func (s Sphere) IntersectsAABB(b AABB) bool {
+	return b.ClosestPoint(s.Center).Sub(s.Center).LenSqr() <= s.Radius*s.Radius
+}
+
+// Transform moves the sphere by m. The radius grows by the largest scale in m so the result
+// still contains everything when m scales unevenly.
+func (s Sphere) Transform(m mgl32.Mat4) Sphere {
+	maxScaleSqr := float32(0)
+	for col := 0; col < 3; col++ {
+		if scaleSqr := m.Col(col).Vec3().LenSqr(); scaleSqr > maxScaleSqr {
+			maxScaleSqr = scaleSqr
+		}
+	}
+	return Sphere{
+		Center: m.Mul4x1(s.Center.Vec4(1)).Vec3(),
+		Radius: s.Radius * float32(math.Sqrt(float64(maxScaleSqr))),
+	}
+}
+
+// AABB is the box around the sphere.
+func (s Sphere) AABB() AABB {
+	r := mgl32.Vec3{s.Radius, s.Radius, s.Radius}
+	return AABB{Min: s.Center.Sub(r), Max: s.Center.Add(r)}
+}
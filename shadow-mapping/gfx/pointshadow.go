@@ -0,0 +1,237 @@
+package gfx
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/geom"
+)
+
+// the smallest depth step of a DEPTH_COMPONENT24 texture, the units of ShadowBias.Constant
+const depth24Step = 1.0 / (1 << 24)
+
+// the direction and up vector of each cube map face in the order GL numbers them
+// (TEXTURE_CUBE_MAP_POSITIVE_X + i), from the GL spec's table of cube map face selection
+var cubeFaces = [6]struct{ dir, up mgl32.Vec3 }{
+	{mgl32.Vec3{1, 0, 0}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{-1, 0, 0}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{0, 1, 0}, mgl32.Vec3{0, 0, 1}},
+	{mgl32.Vec3{0, -1, 0}, mgl32.Vec3{0, 0, -1}},
+	{mgl32.Vec3{0, 0, 1}, mgl32.Vec3{0, -1, 0}},
+	{mgl32.Vec3{0, 0, -1}, mgl32.Vec3{0, -1, 0}},
+}
+
+// PointShadowMap is a shadow map for a point light, which shines in every direction so one
+// view is not enough. The scene is drawn into the 6 faces of a cube map around the light in a
+// single pass, a geometry shader sends each triangle to every face (see
+// shaders/shadow/cube_depth.geom). Instead of the depths of a projection each texel stores the
+// distance to the light divided by Far so the lighting shader can compare distances directly.
+// It is used like a ShadowMap:
+//
+//	shadow.Fit(&light, 0.1, 20)
+//	world.DrawCulled(shadow.Begin(), shadow.Frustum(), casters)
+//	shadow.End()
+//	program.Use()
+//	shadow.SetUniforms(program, "pointShadows[0]", camTransform, gl.TEXTURE4)
+type PointShadowMap struct {
+	// a disabled shadow map is still bound by SetUniforms but the shader does not read it
+	Enabled bool
+
+	// applied to the distances when they are drawn the same way GL applies a ShadowMap's bias
+	// to depths, GL's own polygon offset does not work on depths written by a shader
+	Bias ShadowBias
+
+	// how far around a fragment, in world units, the map is sampled to soften the edges of the
+	// shadows, 0 is a single lookup
+	Softness float32
+
+	// where the light is and how far its shadows reach, set by Fit
+	Position  mgl32.Vec3
+	Near, Far float32
+
+	fb    *Framebuffer
+	depth *Program
+	debug *Program
+	vao   uint32
+}
+
+// NewPointShadowMap makes a shadow map whose cube map faces are size x size.
+func NewPointShadowMap(size int) (*PointShadowMap, error) {
+	sm := PointShadowMap{
+		Enabled:  true,
+		Bias:     DefaultShadowBias,
+		Softness: 0.02,
+		Near:     0.1,
+		Far:      1,
+	}
+
+	var err error
+	sm.depth, err = newProgramFromFiles(filepath.Join(ShadowShaderDir, "cube_depth.vert"),
+		filepath.Join(ShadowShaderDir, "cube_depth.geom"),
+		filepath.Join(ShadowShaderDir, "cube_depth.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	sm.debug, err = newProgramFromFiles(filepath.Join(ShadowShaderDir, "debug.vert"),
+		filepath.Join(ShadowShaderDir, "debug_cube.frag"))
+	if err != nil {
+		sm.Delete()
+		return nil, err
+	}
+
+	sm.fb, err = NewFramebuffer(size, size, FramebufferSpec{
+		Depth: &Attachment{Format: gl.DEPTH_COMPONENT24, Cube: true},
+	})
+	if err != nil {
+		sm.Delete()
+		return nil, err
+	}
+	sm.Texture().SetDepthCompare(true)
+
+	// blend across the edges of the faces when filtering instead of clamping at each edge,
+	// which leaves seams in soft shadows
+	gl.Enable(gl.TEXTURE_CUBE_MAP_SEAMLESS)
+
+	// the debug view's positions come from gl_VertexID but the core profile still requires
+	// a vertex array to be bound to draw
+	gl.GenVertexArrays(1, &sm.vao)
+
+	return &sm, nil
+}
+
+// Size is the width and height of each face in texels.
+func (sm *PointShadowMap) Size() int {
+	return sm.fb.Width()
+}
+
+// Resize reallocates the faces at size x size, the map is empty until the casters are drawn
+// again.
+func (sm *PointShadowMap) Resize(size int) error {
+	if err := sm.fb.Resize(size, size); err != nil {
+		return err
+	}
+	sm.Texture().SetDepthCompare(true)
+	return nil
+}
+
+// Texture is the depth cube map, set up for comparison sampling with a samplerCubeShadow.
+func (sm *PointShadowMap) Texture() *Texture {
+	return sm.fb.Depth()
+}
+
+// Fit places the map at light, anything closer than near or further than far does not cast
+// shadows. Far should be about where the light has faded out since the precision of the
+// distances is spread over it.
+func (sm *PointShadowMap) Fit(light *PointLight, near, far float32) {
+	sm.Position = light.Position
+	sm.Near, sm.Far = near, far
+}
+
+// Frustum is the box around the sphere the map covers, for culling casters.
+func (sm *PointShadowMap) Frustum() *geom.Frustum {
+	box := mgl32.Ortho(-sm.Far, sm.Far, -sm.Far, sm.Far, -sm.Far, sm.Far)
+	frustum := geom.NewFrustum(box.Mul4(mgl32.Translate3D(-sm.Position.X(), -sm.Position.Y(), -sm.Position.Z())))
+	return &frustum
+}
+
+// faceTransforms are the view and projection of each face of the cube map, they all have a
+// 90 degree field of view so together they see everything around the light
+func (sm *PointShadowMap) faceTransforms() [6]mgl32.Mat4 {
+	project := mgl32.Perspective(mgl32.DegToRad(90), 1, sm.Near, sm.Far)
+
+	var transforms [6]mgl32.Mat4
+	for i, face := range cubeFaces {
+		view := mgl32.LookAtV(sm.Position, sm.Position.Add(face.dir), face.up)
+		transforms[i] = project.Mul4(view)
+	}
+	return transforms
+}
+
+// Begin clears the map and sets up drawing the casters into it, it returns the program to draw
+// them with. The program has a "model" uniform like the lighting programs so the same
+// scene drawing code works for both.
+func (sm *PointShadowMap) Begin() *Program {
+	sm.fb.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+	sm.depth.Use()
+	transforms := sm.faceTransforms()
+	for i := range transforms {
+		gl.UniformMatrix4fv(sm.depth.GetUniformLocation(fmt.Sprintf("faceTransforms[%d]", i)),
+			1, false, &transforms[i][0])
+	}
+	setUniformVec3(sm.depth, "lightPos", sm.Position)
+	gl.Uniform1f(sm.depth.GetUniformLocation("far"), sm.Far)
+	gl.Uniform1f(sm.depth.GetUniformLocation("slopeBias"), sm.Bias.Slope)
+	gl.Uniform1f(sm.depth.GetUniformLocation("constantBias"), sm.Bias.Constant*depth24Step)
+	return sm.depth
+}
+
+// End goes back to the framebuffer and viewport that were in use before Begin.
+func (sm *PointShadowMap) End() {
+	sm.fb.UnBind()
+}
+
+// SetUniforms binds the map to texUnit and sets the PointShadow struct called name in prog,
+// which must be in use. view is the camera's view matrix, the shader works in view space but
+// the faces of the cube map line up with the world's axes.
+func (sm *PointShadowMap) SetUniforms(prog *Program, name string, view mgl32.Mat4, texUnit uint32) {
+	tex := sm.Texture()
+	tex.Bind(texUnit)
+	tex.SetUniform(prog.GetUniformLocation(name + ".map"))
+
+	// directions only, so the rotation part is enough
+	toWorld := view.Mat3().Inv()
+	gl.UniformMatrix3fv(prog.GetUniformLocation(name+".toWorld"), 1, false, &toWorld[0])
+	gl.Uniform1i(prog.GetUniformLocation(name+".enabled"), boolToInt(sm.Enabled))
+	gl.Uniform1f(prog.GetUniformLocation(name+".far"), sm.Far)
+	gl.Uniform1f(prog.GetUniformLocation(name+".softness"), sm.Softness)
+}
+
+// DrawDebug draws the whole cube map unwrapped like a map of the world, with the horizon
+// across the middle, into the rectangle of the given width and half as high with its bottom
+// left corner at x, y of the bound framebuffer. Near the light is black and Far is white.
+func (sm *PointShadowMap) DrawDebug(x, y, width int) {
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Viewport(int32(x), int32(y), int32(width), int32(width/2))
+
+	// the distances themselves are needed, not comparisons
+	tex := sm.Texture()
+	tex.SetDepthCompare(false)
+
+	sm.debug.Use()
+	tex.Bind(gl.TEXTURE0)
+	tex.SetUniform(sm.debug.GetUniformLocation("depthMap"))
+
+	gl.BindVertexArray(sm.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+
+	tex.UnBind()
+	tex.SetDepthCompare(true)
+
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (sm *PointShadowMap) Delete() {
+	if sm.fb != nil {
+		sm.fb.Delete()
+	}
+	if sm.depth != nil {
+		sm.depth.Delete()
+	}
+	if sm.debug != nil {
+		sm.debug.Delete()
+	}
+	gl.DeleteVertexArrays(1, &sm.vao)
+}
@@ -0,0 +1,314 @@
+package gfx
+
+import (
+	"math"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/geom"
+)
+
+// ShadowShaderDir is where shadow maps load the shaders for their depth pass and debug view
+// from. Like every other shader path it is relative to the working directory.
+var ShadowShaderDir = "shaders/shadow"
+
+// ShadowBias pushes the depths in a shadow map away from the light so surfaces do not shadow
+// themselves. The map stores one depth per texel but a surface tilted away from the light
+// covers a range of depths inside each texel, so some of its own fragments end up behind the
+// stored depth and are shadowed in a pattern of stripes ("shadow acne"). Too much bias and
+// shadows detach from the objects casting them ("peter panning").
+type ShadowBias struct {
+	// in the smallest steps the depth buffer can store
+	Constant float32
+
+	// multiplied by how much the depth of a triangle changes across one texel, so surfaces
+	// seen at a grazing angle from the light, which need it most, get the most bias
+	Slope float32
+}
+
+// DefaultShadowBias removes acne from the scenes in these samples without visible peter panning.
+var DefaultShadowBias = ShadowBias{
+	Constant: 4,
+	Slope:    2,
+}
+
+// ShadowMap is the depth of the scene as seen from a light, anything further from the light
+// than the depth stored in the direction of a fragment is in shadow. Each frame the light's
+// view is fit with FitDirectional or FitSpot, the casters are drawn between Begin and End and
+// SetUniforms passes the map to the lighting shader:
+//
+//	shadow.FitSpot(&light, 0.5, 30)
+//	world.DrawCulled(shadow.Begin(), shadow.Frustum(), casters)
+//	shadow.End()
+//	program.Use()
+//	shadow.SetUniforms(program, "spotShadow", camTransform, gl.TEXTURE2)
+type ShadowMap struct {
+	// a disabled shadow map is still bound by SetUniforms but the shader does not read it
+	Enabled bool
+
+	Bias ShadowBias
+
+	// how many texels around the one a fragment falls in are compared (percentage closer
+	// filtering), 0 is a single lookup and 2 a 5x5 block. Every lookup already blends 4
+	// texels since the map is linearly filtered.
+	PCFRadius int
+
+	// the light's view and projection, for the whole map
+	View       mgl32.Mat4
+	Projection mgl32.Mat4
+
+	// near and far plane of Projection, spot lights have a perspective projection whose
+	// depths the debug view has to make linear
+	near, far   float32
+	perspective bool
+
+	fb    *Framebuffer
+	depth *Program
+	debug *Program
+	vao   uint32
+}
+
+// NewShadowMap makes a size x size shadow map. It can be resized with Resize, the detail of
+// the shadows depends on how much of the scene the light's view covers.
+func NewShadowMap(size int) (*ShadowMap, error) {
+	sm := ShadowMap{
+		Enabled:    true,
+		Bias:       DefaultShadowBias,
+		PCFRadius:  1,
+		View:       mgl32.Ident4(),
+		Projection: mgl32.Ident4(),
+	}
+
+	var err error
+	sm.depth, err = newProgramFromFiles(filepath.Join(ShadowShaderDir, "depth.vert"),
+		filepath.Join(ShadowShaderDir, "depth.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	sm.debug, err = newProgramFromFiles(filepath.Join(ShadowShaderDir, "debug.vert"),
+		filepath.Join(ShadowShaderDir, "debug.frag"))
+	if err != nil {
+		sm.Delete()
+		return nil, err
+	}
+
+	// only depth is drawn, there is no color to draw or read
+	sm.fb, err = NewFramebuffer(size, size, FramebufferSpec{
+		Depth: &Attachment{Format: gl.DEPTH_COMPONENT24},
+	})
+	if err != nil {
+		sm.Delete()
+		return nil, err
+	}
+	sm.setupTexture()
+
+	// the debug view's positions come from gl_VertexID but the core profile still requires
+	// a vertex array to be bound to draw
+	gl.GenVertexArrays(1, &sm.vao)
+
+	return &sm, nil
+}
+
+// setupTexture makes the depth texture ready to be sampled as a shadow map
+func (sm *ShadowMap) setupTexture() {
+	tex := sm.fb.Depth()
+	tex.SetDepthCompare(true)
+
+	// lookups outside the map are lit, ex: past the edge of a directional light's view
+	border := [4]float32{1, 1, 1, 1}
+	gl.BindTexture(tex.target, tex.handle)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_BORDER)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_BORDER)
+	gl.TexParameterfv(tex.target, gl.TEXTURE_BORDER_COLOR, &border[0])
+	gl.BindTexture(tex.target, 0)
+}
+
+// Size is the width and height of the map in texels.
+func (sm *ShadowMap) Size() int {
+	return sm.fb.Width()
+}
+
+// Resize reallocates the map at size x size, it is empty until the casters are drawn again.
+func (sm *ShadowMap) Resize(size int) error {
+	if err := sm.fb.Resize(size, size); err != nil {
+		return err
+	}
+	sm.setupTexture()
+	return nil
+}
+
+// Texture is the depth texture, set up for comparison sampling with a sampler2DShadow.
+func (sm *ShadowMap) Texture() *Texture {
+	return sm.fb.Depth()
+}
+
+// LightSpace transforms world space to the light's clip space.
+func (sm *ShadowMap) LightSpace() mgl32.Mat4 {
+	return sm.Projection.Mul4(sm.View)
+}
+
+// Frustum is the part of the world the map covers, for culling casters.
+func (sm *ShadowMap) Frustum() *geom.Frustum {
+	frustum := geom.NewFrustum(sm.LightSpace())
+	return &frustum
+}
+
+// FitDirectional points the map along light's direction and covers bounds with an orthographic
+// projection since the light's rays are parallel. The smaller bounds is the sharper the
+// shadows are so it should only hold what casts and receives shadows.
+func (sm *ShadowMap) FitDirectional(light *DirectionalLight, bounds geom.Sphere) {
+	dir := light.Direction.Normalize()
+	eye := bounds.Center.Sub(dir.Mul(bounds.Radius))
+
+	sm.View = mgl32.LookAtV(eye, bounds.Center, upFor(dir))
+	sm.near, sm.far = 0, 2*bounds.Radius
+	sm.Projection = mgl32.Ortho(-bounds.Radius, bounds.Radius, -bounds.Radius, bounds.Radius, sm.near, sm.far)
+	sm.perspective = false
+}
+
+// FitSpot looks out of light with a perspective projection that covers its outer cone,
+// anything closer than near or further than far does not cast shadows.
+func (sm *ShadowMap) FitSpot(light *SpotLight, near, far float32) {
+	dir := light.Direction.Normalize()
+
+	sm.View = mgl32.LookAtV(light.Position, light.Position.Add(dir), upFor(dir))
+	sm.near, sm.far = near, far
+	sm.Projection = mgl32.Perspective(mgl32.DegToRad(2*light.OuterCutoff), 1, near, far)
+	sm.perspective = true
+}
+
+// upFor is an up vector for looking in dir, which must not be parallel to it
+func upFor(dir mgl32.Vec3) mgl32.Vec3 {
+	if math.Abs(float64(dir.Y())) > 0.99 {
+		return mgl32.Vec3{0, 0, 1}
+	}
+	return mgl32.Vec3{0, 1, 0}
+}
+
+// Begin clears the map and sets up drawing the casters into it, it returns the program to draw
+// them with. The program has a "model" uniform like the lighting programs so the same
+// scene drawing code works for both.
+func (sm *ShadowMap) Begin() *Program {
+	sm.fb.Bind()
+	gl.Clear(gl.DEPTH_BUFFER_BIT)
+
+	// GL adds Slope * the depth slope of each triangle + Constant * the smallest depth step
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(sm.Bias.Slope, sm.Bias.Constant)
+
+	sm.depth.Use()
+	lightSpace := sm.LightSpace()
+	gl.UniformMatrix4fv(sm.depth.GetUniformLocation("lightSpace"), 1, false, &lightSpace[0])
+	return sm.depth
+}
+
+// End goes back to the framebuffer and viewport that were in use before Begin.
+func (sm *ShadowMap) End() {
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+	sm.fb.UnBind()
+}
+
+// SetUniforms binds the map to texUnit and sets the Shadow struct called name in prog, which
+// must be in use. Like the lights the shader works in view space so view is the camera's
+// view matrix.
+func (sm *ShadowMap) SetUniforms(prog *Program, name string, view mgl32.Mat4, texUnit uint32) {
+	tex := sm.Texture()
+	tex.Bind(texUnit)
+	tex.SetUniform(prog.GetUniformLocation(name + ".map"))
+
+	// view space -> world space -> the light's clip space
+	fromView := sm.LightSpace().Mul4(view.Inv())
+	gl.UniformMatrix4fv(prog.GetUniformLocation(name+".fromView"), 1, false, &fromView[0])
+	gl.Uniform1i(prog.GetUniformLocation(name+".enabled"), boolToInt(sm.Enabled))
+	gl.Uniform1i(prog.GetUniformLocation(name+".pcfRadius"), int32(sm.PCFRadius))
+}
+
+// DrawDebug draws the map as a grayscale image into the square of the given size with its
+// bottom left corner at x, y of the bound framebuffer, near is black and far is white.
+func (sm *ShadowMap) DrawDebug(x, y, size int) {
+	var viewport [4]int32
+	gl.GetIntegerv(gl.VIEWPORT, &viewport[0])
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.Viewport(int32(x), int32(y), int32(size), int32(size))
+
+	// the depths themselves are needed, not comparisons
+	tex := sm.Texture()
+	tex.SetDepthCompare(false)
+
+	sm.debug.Use()
+	tex.Bind(gl.TEXTURE0)
+	tex.SetUniform(sm.debug.GetUniformLocation("depthMap"))
+	gl.Uniform1i(sm.debug.GetUniformLocation("perspective"), boolToInt(sm.perspective))
+	gl.Uniform1f(sm.debug.GetUniformLocation("near"), sm.near)
+	gl.Uniform1f(sm.debug.GetUniformLocation("far"), sm.far)
+
+	gl.BindVertexArray(sm.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+
+	tex.UnBind()
+	tex.SetDepthCompare(true)
+
+	gl.Viewport(viewport[0], viewport[1], viewport[2], viewport[3])
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (sm *ShadowMap) Delete() {
+	if sm.fb != nil {
+		sm.fb.Delete()
+	}
+	if sm.depth != nil {
+		sm.depth.Delete()
+	}
+	if sm.debug != nil {
+		sm.debug.Delete()
+	}
+	gl.DeleteVertexArrays(1, &sm.vao)
+}
+
+// shader types by file extension, for newProgramFromFiles
+var shaderTypes = map[string]uint32{
+	".vert": gl.VERTEX_SHADER,
+	".geom": gl.GEOMETRY_SHADER,
+	".frag": gl.FRAGMENT_SHADER,
+}
+
+// newProgramFromFiles compiles the shaders in files, whose types come from their extensions,
+// and links them
+func newProgramFromFiles(files ...string) (*Program, error) {
+	var shaders []*Shader
+	deleteShaders := func() {
+		for _, shader := range shaders {
+			shader.Delete()
+		}
+	}
+
+	for _, file := range files {
+		shader, err := NewShaderFromFile(file, shaderTypes[filepath.Ext(file)])
+		if err != nil {
+			deleteShaders()
+			return nil, err
+		}
+		shaders = append(shaders, shader)
+	}
+
+	prog, err := NewProgram(shaders...)
+	if err != nil {
+		deleteShaders()
+		return nil, err
+	}
+	return prog, nil
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,569 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-Lighting/Shadows/Shadow-Mapping
+
+Before the scene is lit it is drawn once from the sun and once from a spot light, keeping
+only the depths (gfx.ShadowMap). While lighting a fragment its position is transformed into
+each light's view and compared to the depth stored there, if something was closer to the
+light the fragment is in shadow. The sun covers the crates with an orthographic projection
+since its rays are parallel, the spot light sweeping over the floor uses a perspective one
+like a camera.
+
+Point lights shine in every direction so their shadows are drawn into the 6 faces of a cube
+map around them in one pass (gfx.PointShadowMap), storing the distance to the light instead
+of a depth. Two colored lights between the crates cast them, one of them circling.
+
+The maps are sampled with hardware comparison (sampler2DShadow and samplerCubeShadow) and
+percentage closer filtering softens the edges. Depth bias stops surfaces from shadowing
+themselves (shadow acne), turn it down to see what it fixes.
+
+Press P to toggle shadows, V to cycle the debug view between the sun's, the spot light's and
+the point lights' shadow maps and N to cycle the shadow map size. [ and ] change the PCF
+radius (and the softness of the point light shadows), hold up and down to change the slope
+scaled bias and left and right to change the constant bias.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/cam"
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/geom"
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/gfx"
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/scene"
+	"github.com/cstegel/opengl-samples-golang/shadow-mapping/win"
+)
+
+var cubePositions = [][]float32{
+	{0.0, 0.0, -3.0},
+	{2.0, 5.0, -15.0},
+	{-1.5, -2.2, -2.5},
+	{-3.8, -2.0, -12.3},
+	{2.4, -0.4, -3.5},
+	{-1.7, 3.0, -7.5},
+	{1.3, -2.0, -2.5},
+	{1.5, 2.0, -2.5},
+	{1.5, 0.2, -1.5},
+	{-1.3, 1.0, -1.5},
+}
+
+var pointLightPositions = [...]mgl32.Vec3{
+	{0.0, 0.5, -5.5},
+	{2.5, -2.0, -9.0},
+}
+
+var pointLightColors = []mgl32.Vec3{
+	{0.2, 0.4, 1.0},
+	{1.0, 0.3, 0.2},
+}
+
+// must match MAX_POINT_LIGHTS and MAX_POINT_SHADOWS in shaders/phong.frag
+const (
+	maxPointLights  = 8
+	maxPointShadows = 4
+)
+
+// shadow map sizes N cycles through
+var shadowSizes = []int{512, 1024, 2048, 4096}
+
+const (
+	maxPCFRadius = 4
+
+	// how fast holding the bias keys changes them
+	slopeBiasPerSecond    = 2.0
+	constantBiasPerSecond = 8.0
+
+	// what the spot light's shadow map covers, it does not reach much further
+	spotShadowNear = 0.5
+	spotShadowFar  = 32
+
+	// the same for the point lights
+	pointShadowNear = 0.1
+	pointShadowFar  = 20
+
+	// the point light shadows have no texels to filter over, they get this much softer in
+	// world units for every step of the PCF radius instead
+	pointSoftnessPerPCF = 0.02
+
+	// each face of a cube map is this much smaller than a shadow map since there are 6 of them
+	pointShadowSizeDivisor = 4
+)
+
+// what the debug view shows, the views after debugSpot show the point lights' maps in order
+const (
+	debugOff = iota
+	debugSun
+	debugSpot
+	debugPoint
+	numDebugViews = debugPoint + len(pointLightPositions)
+)
+
+func debugName(view int) string {
+	switch view {
+	case debugOff:
+		return "off"
+	case debugSun:
+		return "sun"
+	case debugSpot:
+		return "spot light"
+	}
+	return fmt.Sprintf("point light %d", view-debugPoint)
+}
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes    []*scene.Node
+	ground   *scene.Node
+	lamp     *scene.Node
+	circling *scene.Node
+}
+
+func buildScene(cube, floor *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	// low in the sky so the shadows are long
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.5, -1.0, -0.4},
+		Ambient:   mgl32.Vec3{0.05, 0.05, 0.05},
+		Diffuse:   mgl32.Vec3{0.5, 0.5, 0.5},
+		Specular:  mgl32.Vec3{0.3, 0.3, 0.3},
+	}
+
+	nodes.ground = s.Add(scene.NewNode("ground"))
+	nodes.ground.SetPosition(mgl32.Vec3{0, -3.5, -8})
+	nodes.ground.SetScale(mgl32.Vec3{30, 1, 30})
+	nodes.ground.Mesh = floor
+
+	// the spot light points down the node's -z like a camera, its gizmo is drawn in its color
+	nodes.lamp = s.Add(scene.NewNode("lamp"))
+	nodes.lamp.SetPosition(mgl32.Vec3{0, 6, -4})
+	nodes.lamp.SetScale(mgl32.Vec3{0.2, 0.2, 0.2})
+	nodes.lamp.Mesh = cube
+	nodes.lamp.SpotLight = &gfx.SpotLight{
+		Direction:   mgl32.Vec3{0, 0, -1},
+		InnerCutoff: 20,
+		OuterCutoff: 25,
+		Diffuse:     mgl32.Vec3{1.0, 0.85, 0.6},
+		Specular:    mgl32.Vec3{1.0, 0.85, 0.6},
+		Attenuation: gfx.AttenuationForDistance(spotShadowFar),
+	}
+
+	// the gizmo cubes are drawn in the light's color
+	for i, pos := range pointLightPositions {
+		light := s.Add(scene.NewNode(fmt.Sprintf("point light %d", i)))
+		light.SetPosition(pos)
+		light.SetScale(mgl32.Vec3{0.2, 0.2, 0.2})
+		light.Mesh = cube
+		light.PointLight = &gfx.PointLight{
+			Ambient:     pointLightColors[i].Mul(0.05),
+			Diffuse:     pointLightColors[i],
+			Specular:    pointLightColors[i],
+			Attenuation: gfx.AttenuationForDistance(pointShadowFar),
+			CastShadows: true,
+		}
+		if i == 0 {
+			nodes.circling = light
+		}
+	}
+
+	for i, pos := range cubePositions {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{pos[0], pos[1], pos[2]})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Shadow mapping")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	// the linked shader program determines how the data will be rendered
+	vertShader, err := gfx.NewShaderFromFile("shaders/phong.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	fragShader, err := gfx.NewShaderFromFile("shaders/phong.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	program, err := gfx.NewProgram(vertShader, fragShader)
+	if err != nil {
+		return err
+	}
+	defer program.Delete()
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(vertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+	defer lightProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	floor, err := gfx.NewPlaneMesh(15).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	shadowSize := 2
+	sunShadow, err := gfx.NewShadowMap(shadowSizes[shadowSize])
+	if err != nil {
+		return err
+	}
+	defer sunShadow.Delete()
+
+	spotShadow, err := gfx.NewShadowMap(shadowSizes[shadowSize])
+	if err != nil {
+		return err
+	}
+	defer spotShadow.Delete()
+
+	// every map is bound when lighting, even when fewer lights cast shadows, since samplers
+	// of different types can not share a texture unit
+	var pointShadows [maxPointShadows]*gfx.PointShadowMap
+	for i := range pointShadows {
+		pointShadows[i], err = gfx.NewPointShadowMap(shadowSizes[shadowSize] / pointShadowSizeDivisor)
+		if err != nil {
+			return err
+		}
+		defer pointShadows[i].Delete()
+	}
+
+	// all maps are changed together by the keys
+	shadowMaps := []*gfx.ShadowMap{sunShadow, spotShadow}
+	shadows := true
+	debugView := debugOff
+
+	world, nodes := buildScene(cube, floor)
+
+	// lens for the camera
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 100}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{0, 0, 3}, mgl32.Vec3{0, 1, 0}, -90, 0, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		dt := float32(window.SinceLastFrame())
+		input := window.InputManager()
+
+		if input.IsTriggered(win.TOGGLE_SHADOWS) {
+			shadows = !shadows
+		}
+		if input.IsTriggered(win.NEXT_SHADOW_DEBUG) {
+			debugView = (debugView + 1) % numDebugViews
+		}
+		if input.IsTriggered(win.NEXT_SHADOW_SIZE) {
+			shadowSize = (shadowSize + 1) % len(shadowSizes)
+			for _, sm := range shadowMaps {
+				if err := sm.Resize(shadowSizes[shadowSize]); err != nil {
+					return err
+				}
+			}
+			for _, sm := range pointShadows {
+				if err := sm.Resize(shadowSizes[shadowSize] / pointShadowSizeDivisor); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, sm := range shadowMaps {
+			sm.Enabled = shadows
+			if input.IsTriggered(win.MORE_PCF) && sm.PCFRadius < maxPCFRadius {
+				sm.PCFRadius++
+			}
+			if input.IsTriggered(win.LESS_PCF) && sm.PCFRadius > 0 {
+				sm.PCFRadius--
+			}
+			if input.IsActive(win.SLOPE_BIAS_UP) {
+				sm.Bias.Slope += slopeBiasPerSecond * dt
+			}
+			if input.IsActive(win.SLOPE_BIAS_DOWN) {
+				sm.Bias.Slope = float32(math.Max(0, float64(sm.Bias.Slope-slopeBiasPerSecond*dt)))
+			}
+			if input.IsActive(win.CONSTANT_BIAS_UP) {
+				sm.Bias.Constant += constantBiasPerSecond * dt
+			}
+			if input.IsActive(win.CONSTANT_BIAS_DOWN) {
+				sm.Bias.Constant = float32(math.Max(0, float64(sm.Bias.Constant-constantBiasPerSecond*dt)))
+			}
+		}
+		for _, sm := range pointShadows {
+			sm.Enabled = shadows
+			sm.Bias = sunShadow.Bias
+			sm.Softness = pointSoftnessPerPCF * float32(sunShadow.PCFRadius)
+		}
+
+		size := shadowSizes[shadowSize]
+		pcfWidth := 2*sunShadow.PCFRadius + 1
+		window.SetTitle(fmt.Sprintf("Shadow mapping - shadows %s, %dx%d, PCF %dx%d, bias slope %.2f "+
+			"constant %.1f, debug view %s, gamma correction %s", onOff(shadows), size, size,
+			pcfWidth, pcfWidth, sunShadow.Bias.Slope, sunShadow.Bias.Constant, debugName(debugView),
+			onOff(window.GammaCorrection())))
+
+		// animate the nodes
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+
+		// the lamp sweeps its light around the floor under the crates
+		sweep := float64(time) * 0.4
+		target := mgl32.Vec3{4 * float32(math.Cos(sweep)), -3.5, -6 + 4*float32(math.Sin(sweep))}
+		nodes.lamp.LookAt(target, mgl32.Vec3{0, 1, 0})
+
+		orbit := float64(time) * 0.7
+		nodes.circling.SetPosition(pointLightPositions[0].Add(
+			mgl32.Vec3{2.5 * float32(math.Cos(orbit)), 0, 2.5 * float32(math.Sin(orbit))}))
+
+		lights := world.Lights()
+		pointShadowIndexes := assignPointShadows(lights.Point)
+
+		// lights do not block themselves, or each other
+		casters := func(n *scene.Node) bool {
+			return !isLight(n)
+		}
+
+		if shadows {
+			if len(lights.Directional) > 0 {
+				sunShadow.FitDirectional(&lights.Directional[0], shadowBounds(world, nodes.ground))
+				world.DrawCulled(sunShadow.Begin(), sunShadow.Frustum(), casters)
+				sunShadow.End()
+			}
+			if len(lights.Spot) > 0 {
+				spotShadow.FitSpot(&lights.Spot[0], spotShadowNear, spotShadowFar)
+				world.DrawCulled(spotShadow.Begin(), spotShadow.Frustum(), casters)
+				spotShadow.End()
+			}
+			for i, index := range pointShadowIndexes {
+				if index < 0 {
+					continue
+				}
+				sm := pointShadows[index]
+				sm.Fit(&lights.Point[i], pointShadowNear, pointShadowFar)
+				world.DrawCulled(sm.Begin(), sm.Frustum(), casters)
+				sm.End()
+			}
+		}
+
+		// background color
+		gl.ClearColor(0.1, 0.1, 0.1, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		program.Use()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(program.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(program.GetUniformLocation("material.specular"))
+		gl.Uniform1f(program.GetUniformLocation("material.shininess"), 32.0)
+
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(float32(window.Width()) / float32(window.Height()))
+		gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+
+		setLightUniforms(program, lights, pointShadowIndexes, camTransform)
+
+		// the maps are bound even when shadows are off since samplers of different types
+		// can not share a texture unit
+		sunShadow.SetUniforms(program, "dirShadow", camTransform, gl.TEXTURE2)
+		spotShadow.SetUniforms(program, "spotShadow", camTransform, gl.TEXTURE3)
+		for i, sm := range pointShadows {
+			sm.SetUniforms(program, fmt.Sprintf("pointShadows[%d]", i), camTransform, gl.TEXTURE4+uint32(i))
+		}
+
+		// everything except the light gizmos is lit
+		world.Draw(program, func(n *scene.Node) bool {
+			return !isLight(n)
+		})
+
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+		sunShadow.Texture().UnBind()
+		spotShadow.Texture().UnBind()
+		for _, sm := range pointShadows {
+			sm.Texture().UnBind()
+		}
+
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(lightProgram, func(n *scene.Node) bool {
+			var color mgl32.Vec3
+			switch {
+			case n.SpotLight != nil:
+				color = n.SpotLight.Diffuse
+			case n.PointLight != nil:
+				color = n.PointLight.Diffuse
+			default:
+				return false
+			}
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"), color.X(), color.Y(), color.Z())
+			return true
+		})
+
+		// the shadow map in the bottom right corner, cube maps are twice as wide as high
+		debugSize := window.Height() / 3
+		switch {
+		case debugView == debugSun:
+			sunShadow.DrawDebug(window.Width()-debugSize-10, 10, debugSize)
+		case debugView == debugSpot:
+			spotShadow.DrawDebug(window.Width()-debugSize-10, 10, debugSize)
+		case debugView >= debugPoint:
+			pointShadows[debugView-debugPoint].DrawDebug(window.Width()-2*debugSize-10, 10, 2*debugSize)
+		}
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+func isLight(n *scene.Node) bool {
+	return n.PointLight != nil || n.SpotLight != nil
+}
+
+// shadowBounds is what the sun's shadow map has to cover, the crates and the floor under them.
+// The rest of the floor is left out since nothing casts shadows on it and covering less makes
+// the shadows sharper.
+func shadowBounds(world *scene.Scene, ground *scene.Node) geom.Sphere {
+	bounds := geom.EmptyAABB()
+	world.Walk(func(n *scene.Node) {
+		if n.Mesh != nil && n != ground && !isLight(n) {
+			bounds = bounds.Union(n.WorldBounds())
+		}
+	})
+	bounds = bounds.Extend(mgl32.Vec3{bounds.Center().X(), ground.WorldPosition().Y(), bounds.Center().Z()})
+
+	sphere := bounds.BoundingSphere()
+
+	// room for the shadows that fall past the crates on the floor
+	sphere.Radius += 2
+	return sphere
+}
+
+// assignPointShadows gives the first maxPointShadows point lights that cast shadows a shadow
+// map, it returns the index of the map for each light or -1
+func assignPointShadows(lights []gfx.PointLight) []int32 {
+	indexes := make([]int32, len(lights))
+	next := int32(0)
+	for i, light := range lights {
+		indexes[i] = -1
+		if light.CastShadows && next < maxPointShadows && i < maxPointLights {
+			indexes[i] = next
+			next++
+		}
+	}
+	return indexes
+}
+
+// sets the lights in view space, a scene may have no sun or spot light. pointShadows is the
+// shadow map of each point light from assignPointShadows.
+func setLightUniforms(program *gfx.Program, lights scene.Lights, pointShadows []int32, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	numPointLights := len(lights.Point)
+	if numPointLights > maxPointLights {
+		numPointLights = maxPointLights
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+	for i := 0; i < numPointLights; i++ {
+		name := fmt.Sprintf("pointLights[%d]", i)
+		lights.Point[i].SetUniforms(program, name, camTransform)
+		gl.Uniform1i(program.GetUniformLocation(name+".shadow"), pointShadows[i])
+	}
+
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
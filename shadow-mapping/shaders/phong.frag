@@ -0,0 +1,250 @@
+#version 410 core
+
+// must match maxPointLights and maxPointShadows in main.go
+#define MAX_POINT_LIGHTS 8
+#define MAX_POINT_SHADOWS 4
+
+struct Material {
+	sampler2D diffuse;
+	sampler2D specular;
+	float shininess;
+};
+
+// these structs match the light types in gfx/light.go
+// all positions and directions are in view space
+
+struct DirLight {
+	vec3 direction;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+};
+
+struct PointLight {
+	vec3 position;
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+
+	int shadow;  // index into pointShadows, -1 when the light does not cast shadows
+};
+
+struct SpotLight {
+	vec3 position;
+	vec3 direction;
+	float innerCutoff;  // cosine of the angle
+	float outerCutoff;  // cosine of the angle
+
+	vec3 ambient;
+	vec3 diffuse;
+	vec3 specular;
+
+	float constant;
+	float linear;
+	float quadratic;
+};
+
+// a shadow map and how to read it, see gfx/shadow.go
+struct Shadow {
+	bool enabled;
+	sampler2DShadow map;
+	mat4 fromView;  // view space to the light's clip space
+	int pcfRadius;  // compares a (2 * pcfRadius + 1)^2 block of texels
+};
+
+// a point light's cube map of distances, see gfx/pointshadow.go
+struct PointShadow {
+	bool enabled;
+	samplerCubeShadow map;
+	mat3 toWorld;    // view space directions to world space, which the faces line up with
+	float far;       // the distances are stored divided by this
+	float softness;  // how far around the fragment is sampled, in world units
+};
+
+in vec3 Normal;
+in vec3 FragPos;
+in vec2 TexCoords;
+out vec4 color;
+
+uniform Material material;
+uniform DirLight dirLight;
+uniform PointLight pointLights[MAX_POINT_LIGHTS];
+uniform int numPointLights;
+uniform SpotLight spotLight;
+
+// the scene may not have a sun or flashlight
+uniform bool hasDirLight;
+uniform bool hasSpotLight;
+
+uniform Shadow dirShadow;
+uniform Shadow spotShadow;
+uniform PointShadow pointShadows[MAX_POINT_SHADOWS];
+
+// the fraction of the light that reaches the fragment, 0 is completely in shadow
+float shadowed(Shadow shadow)
+{
+	if (!shadow.enabled) {
+		return 1.0;
+	}
+
+	vec4 lightClip = shadow.fromView * vec4(FragPos, 1.0);
+
+	// behind a spot light
+	if (lightClip.w <= 0.0) {
+		return 1.0;
+	}
+
+	// [-1, 1] clip space to the [0, 1] of texture coordinates and depths
+	vec3 coords = lightClip.xyz / lightClip.w * 0.5 + 0.5;
+
+	// further than the light's far plane, nothing there was drawn into the map
+	if (coords.z > 1.0) {
+		return 1.0;
+	}
+
+	// every lookup compares coords.z to the 4 nearest texels and blends the results, averaging
+	// a block of them around the fragment softens the edges further
+	vec2 texelSize = 1.0 / vec2(textureSize(shadow.map, 0));
+	float lit = 0.0;
+	for (int x = -shadow.pcfRadius; x <= shadow.pcfRadius; x++) {
+		for (int y = -shadow.pcfRadius; y <= shadow.pcfRadius; y++) {
+			vec2 offset = vec2(x, y) * texelSize;
+			lit += texture(shadow.map, vec3(coords.xy + offset, coords.z));
+		}
+	}
+
+	float width = float(2 * shadow.pcfRadius + 1);
+	return lit / (width * width);
+}
+
+// soft lookups in a cube map are spread over these directions around the fragment, there is
+// no grid of texels to step over like in a 2D map since it depends on the face
+const vec3 pointSampleOffsets[20] = vec3[](
+	vec3( 1,  1,  1), vec3( 1, -1,  1), vec3(-1, -1,  1), vec3(-1,  1,  1),
+	vec3( 1,  1, -1), vec3( 1, -1, -1), vec3(-1, -1, -1), vec3(-1,  1, -1),
+	vec3( 1,  1,  0), vec3( 1, -1,  0), vec3(-1, -1,  0), vec3(-1,  1,  0),
+	vec3( 1,  0,  1), vec3(-1,  0,  1), vec3( 1,  0, -1), vec3(-1,  0, -1),
+	vec3( 0,  1,  1), vec3( 0, -1,  1), vec3( 0, -1, -1), vec3( 0,  1, -1)
+);
+
+// like shadowed but for a point light at lightPos (in view space)
+float pointShadowed(PointShadow shadow, vec3 lightPos)
+{
+	if (!shadow.enabled) {
+		return 1.0;
+	}
+
+	// the direction picks the texel and its length is compared to the distance stored there
+	vec3 fromLight = shadow.toWorld * (FragPos - lightPos);
+	float dist = length(fromLight) / shadow.far;
+
+	// further than the shadows reach
+	if (dist > 1.0) {
+		return 1.0;
+	}
+
+	if (shadow.softness <= 0.0) {
+		return texture(shadow.map, vec4(fromLight, dist));
+	}
+
+	float lit = 0.0;
+	for (int i = 0; i < 20; i++) {
+		lit += texture(shadow.map, vec4(fromLight + pointSampleOffsets[i] * shadow.softness, dist));
+	}
+	return lit / 20.0;
+}
+
+// the parts of phong lighting shared by every light type, dirToLight must be normalized
+// lit scales the diffuse and specular parts, shadows still get the ambient part
+vec3 phong(vec3 dirToLight, vec3 norm, vec3 dirToView, float lit,
+           vec3 lightAmbient, vec3 lightDiffuse, vec3 lightSpecular)
+{
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 specularColor = vec3(texture(material.specular, TexCoords));
+
+	vec3 ambient = lightAmbient * diffuseColor;
+
+	float lightNormalDiff = max(dot(norm, dirToLight), 0.0);
+	vec3 diffuse = lightDiffuse * lightNormalDiff * diffuseColor;
+
+	vec3 reflectDir = reflect(-dirToLight, norm);
+	float spec = pow(max(dot(dirToView, reflectDir), 0.0), material.shininess);
+	vec3 specular = lightSpecular * spec * specularColor;
+
+	return ambient + lit * (diffuse + specular);
+}
+
+// intensity left after the light has travelled dist
+float attenuation(float dist, float constant, float linear, float quadratic)
+{
+	return 1.0 / (constant + linear * dist + quadratic * (dist * dist));
+}
+
+vec3 calcDirLight(DirLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(-light.direction);
+	return phong(dirToLight, norm, dirToView, shadowed(dirShadow),
+	             light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcPointLight(PointLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	// the index is the same for every fragment so picking the sampler with it is allowed
+	float lit = 1.0;
+	if (light.shadow >= 0) {
+		lit = pointShadowed(pointShadows[light.shadow], light.position);
+	}
+
+	return decay * phong(dirToLight, norm, dirToView, lit,
+	                     light.ambient, light.diffuse, light.specular);
+}
+
+vec3 calcSpotLight(SpotLight light, vec3 norm, vec3 dirToView)
+{
+	vec3 dirToLight = normalize(light.position - FragPos);
+	float decay = attenuation(length(light.position - FragPos),
+	                          light.constant, light.linear, light.quadratic);
+
+	// 1 inside the inner cone, 0 outside the outer cone and a smooth blend between them
+	float theta = dot(dirToLight, normalize(-light.direction));
+	float epsilon = light.innerCutoff - light.outerCutoff;
+	float intensity = clamp((theta - light.outerCutoff) / epsilon, 0.0, 1.0);
+
+	// keep the ambient term so the area outside the cone is not pitch black
+	vec3 diffuseColor = vec3(texture(material.diffuse, TexCoords));
+	vec3 ambient = light.ambient * diffuseColor;
+	vec3 lit = phong(dirToLight, norm, dirToView, shadowed(spotShadow),
+	                 vec3(0.0), light.diffuse, light.specular);
+
+	return decay * (ambient + intensity * lit);
+}
+
+void main()
+{
+	vec3 norm = normalize(Normal);
+	vec3 viewPos = vec3(0.0f, 0.0f, 0.0f);
+	vec3 dirToView = normalize(viewPos - FragPos);
+
+	vec3 result = vec3(0.0f);
+	if (hasDirLight) {
+		result += calcDirLight(dirLight, norm, dirToView);
+	}
+	for (int i = 0; i < numPointLights; i++) {
+		result += calcPointLight(pointLights[i], norm, dirToView);
+	}
+	if (hasSpotLight) {
+		result += calcSpotLight(spotLight, norm, dirToView);
+	}
+
+	color = vec4(result, 1.0f);
+}
@@ -0,0 +1,21 @@
+#version 410 core
+
+// stores the distance to the light instead of the depth of the face's projection, so the
+// lighting shader can compare distances without knowing which face a direction falls on
+
+in vec3 WorldPos;
+
+uniform vec3 lightPos;
+uniform float far;
+
+// what glPolygonOffset would add, it does not apply to depths written by the shader
+uniform float slopeBias;
+uniform float constantBias;  // already in depth units
+
+void main()
+{
+	float dist = length(WorldPos - lightPos);
+
+	// fwidth is how much the distance changes across one texel of the face
+	gl_FragDepth = (dist + slopeBias * fwidth(dist)) / far + constantBias;
+}
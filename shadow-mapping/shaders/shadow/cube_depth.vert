@@ -0,0 +1,14 @@
+#version 410 core
+
+// draws the casters into every face of a point light's shadow cube map, see
+// gfx/pointshadow.go. Positions only go to world space here, the geometry shader projects
+// them once for each face.
+
+layout (location = 0) in vec3 position;
+
+uniform mat4 model;
+
+void main()
+{
+	gl_Position = model * vec4(position, 1.0);
+}
@@ -0,0 +1,27 @@
+#version 410 core
+
+// shows the depths of a shadow map, black near the light and white far from it
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D depthMap;
+
+// perspective projections (spot lights) store depths that are packed towards the far plane,
+// they are turned back into distances between near and far so the image is not all white
+uniform bool perspective;
+uniform float near;
+uniform float far;
+
+void main()
+{
+	float depth = texture(depthMap, TexCoords).r;
+
+	if (perspective) {
+		float z = depth * 2.0 - 1.0;
+		float dist = (2.0 * near * far) / (far + near - z * (far - near));
+		depth = (dist - near) / (far - near);
+	}
+
+	color = vec4(vec3(depth), 1.0);
+}
@@ -0,0 +1,23 @@
+#version 410 core
+
+// shows every direction of a point light's shadow cube map at once, unwrapped like a map of
+// the world: left to right goes once around the light and bottom to top from straight down
+// to straight up. Black is at the light and white is at its far distance.
+
+#define PI 3.14159265
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform samplerCube depthMap;
+
+void main()
+{
+	float longitude = (TexCoords.x * 2.0 - 1.0) * PI;
+	float latitude = (TexCoords.y - 0.5) * PI;
+	vec3 dir = vec3(cos(latitude) * sin(longitude), sin(latitude), -cos(latitude) * cos(longitude));
+
+	// the distances are stored divided by the far distance already
+	float depth = texture(depthMap, dir).r;
+	color = vec4(vec3(depth), 1.0);
+}
@@ -0,0 +1,433 @@
+package gfx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// Attachment describes one image of a Framebuffer.
+type Attachment struct {
+	// sized internal format, ex: gl.RGBA8, gl.RGBA16F, gl.DEPTH_COMPONENT24 or
+	// gl.DEPTH24_STENCIL8 for a combined depth and stencil attachment
+	Format int32
+
+	// Renderbuffer stores the image in a renderbuffer instead of a texture. GL can render to
+	// those more efficiently but they can not be sampled, only blitted or read.
+	Renderbuffer bool
+
+	// Cube stores the image in a cube map texture with 6 square faces, ex: for the shadows of a
+	// point light. The faces are attached as layers so a geometry shader picks the face each
+	// triangle is drawn to by setting gl_Layer. Every attachment of the framebuffer must then
+	// be a cube map.
+	Cube bool
+
+	// Layers stores the image in a 2D array texture with this many layers instead of a 2D
+	// texture, ex: for the cascades of a directional light's shadows. Only one layer is drawn
+	// to at a time, see Framebuffer.SetLayer.
+	Layers int
+}
+
+// FramebufferSpec lists the attachments of a Framebuffer, any of them may be left out.
+type FramebufferSpec struct {
+	// drawn to by fragment shader outputs 0, 1, ... in order
+	Color []Attachment
+
+	// a depth or combined depth and stencil format
+	Depth *Attachment
+
+	// only for a stencil buffer separate from the depth buffer (gl.STENCIL_INDEX8)
+	Stencil *Attachment
+}
+
+// Framebuffer is a set of images that can be rendered to instead of the window, ex: to
+// render to a texture that is then used when drawing something else.
+//
+//	fb.Bind()
+//	... draw ...
+//	fb.UnBind() // back to whatever was bound before, with its viewport
+//	fb.Color(0).Bind(gl.TEXTURE0)
+type Framebuffer struct {
+	handle uint32
+	width  int
+	height int
+	spec   FramebufferSpec
+
+	color   []attachmentImage
+	depth   *attachmentImage
+	stencil *attachmentImage
+
+	// what to restore in UnBind, saved by Bind
+	prevFramebuffer int32
+	prevViewport    [4]int32
+}
+
+// attachmentImage is the texture or renderbuffer storing an Attachment
+type attachmentImage struct {
+	point  uint32 // where it is attached, ex: gl.DEPTH_ATTACHMENT
+	layers int
+
+	texture      *Texture
+	renderbuffer uint32
+}
+
+// FramebufferError is returned when GL considers a framebuffer incomplete
+// (not usable for rendering).
+type FramebufferError struct {
+	Status uint32 // from gl.CheckFramebufferStatus
+}
+
+func (e *FramebufferError) Error() string {
+	reasons := map[uint32]string{
+		gl.FRAMEBUFFER_UNDEFINED:                     "the default framebuffer does not exist",
+		gl.FRAMEBUFFER_INCOMPLETE_ATTACHMENT:         "an attachment is incomplete, its format may not be renderable",
+		gl.FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT: "it has no attachments",
+		gl.FRAMEBUFFER_INCOMPLETE_DRAW_BUFFER:        "a draw buffer has no attachment",
+		gl.FRAMEBUFFER_INCOMPLETE_READ_BUFFER:        "the read buffer has no attachment",
+		gl.FRAMEBUFFER_UNSUPPORTED:                   "the combination of attachment formats is not supported",
+		gl.FRAMEBUFFER_INCOMPLETE_MULTISAMPLE:        "the attachments have different numbers of samples",
+		gl.FRAMEBUFFER_INCOMPLETE_LAYER_TARGETS:      "the attachments are not all layered in the same way",
+	}
+	reason, ok := reasons[e.Status]
+	if !ok {
+		reason = "unknown status"
+	}
+	return fmt.Sprintf("framebuffer is incomplete: %s (0x%x)", reason, e.Status)
+}
+
+var errFramebufferSize = errors.New("framebuffer width and height must be positive")
+
+var errUnknownFormat = errors.New("unknown texture format for a framebuffer attachment")
+
+var errCubeRenderbuffer = errors.New("cube map attachments can not be renderbuffers")
+
+var errCubeSize = errors.New("cube map attachments must have the same width and height")
+
+var errArrayAttachment = errors.New("array attachments can not be renderbuffers or cube maps")
+
+var errLayerOutOfRange = errors.New("layer is outside an array attachment")
+
+// pixel format and type passed with each internal format when allocating texture storage,
+// GL requires them even though no pixels are uploaded
+type pixelFormat struct {
+	format uint32
+	xtype  uint32
+}
+
+var attachmentFormats = map[int32]pixelFormat{
+	gl.R8:                 {gl.RED, gl.UNSIGNED_BYTE},
+	gl.RG8:                {gl.RG, gl.UNSIGNED_BYTE},
+	gl.RGB8:               {gl.RGB, gl.UNSIGNED_BYTE},
+	gl.RGBA8:              {gl.RGBA, gl.UNSIGNED_BYTE},
+	gl.SRGB8_ALPHA8:       {gl.RGBA, gl.UNSIGNED_BYTE},
+	gl.R16F:               {gl.RED, gl.FLOAT},
+	gl.RG16F:              {gl.RG, gl.FLOAT},
+	gl.RGB16F:             {gl.RGB, gl.FLOAT},
+	gl.RGBA16F:            {gl.RGBA, gl.FLOAT},
+	gl.R32F:               {gl.RED, gl.FLOAT},
+	gl.RG32F:              {gl.RG, gl.FLOAT},
+	gl.RGBA32F:            {gl.RGBA, gl.FLOAT},
+	gl.R11F_G11F_B10F:     {gl.RGB, gl.FLOAT},
+	gl.R32UI:              {gl.RED_INTEGER, gl.UNSIGNED_INT},
+	gl.DEPTH_COMPONENT16:  {gl.DEPTH_COMPONENT, gl.FLOAT},
+	gl.DEPTH_COMPONENT24:  {gl.DEPTH_COMPONENT, gl.FLOAT},
+	gl.DEPTH_COMPONENT32F: {gl.DEPTH_COMPONENT, gl.FLOAT},
+	gl.DEPTH24_STENCIL8:   {gl.DEPTH_STENCIL, gl.UNSIGNED_INT_24_8},
+	gl.DEPTH32F_STENCIL8:  {gl.DEPTH_STENCIL, gl.FLOAT_32_UNSIGNED_INT_24_8_REV},
+	gl.STENCIL_INDEX8:     {gl.STENCIL_INDEX, gl.UNSIGNED_BYTE},
+}
+
+// NewFramebuffer creates a framebuffer with the attachments in spec, all width x height.
+// Texture attachments are clamped to the edge and linearly filtered unless their format
+// can only be sampled with NEAREST (integer, depth and stencil formats).
+func NewFramebuffer(width, height int, spec FramebufferSpec) (*Framebuffer, error) {
+	fb := Framebuffer{spec: spec}
+	gl.GenFramebuffers(1, &fb.handle)
+
+	if err := fb.Resize(width, height); err != nil {
+		fb.Delete()
+		return nil, err
+	}
+	return &fb, nil
+}
+
+func (fb *Framebuffer) Width() int {
+	return fb.width
+}
+
+func (fb *Framebuffer) Height() int {
+	return fb.height
+}
+
+// Bounds is the whole framebuffer, for blitting.
+func (fb *Framebuffer) Bounds() Rect {
+	return Rect{0, 0, fb.width, fb.height}
+}
+
+// Color returns the texture of color attachment i, or nil for a renderbuffer.
+func (fb *Framebuffer) Color(i int) *Texture {
+	return fb.color[i].texture
+}
+
+// Depth returns the depth (and stencil) texture, or nil when there is none or it is a renderbuffer.
+func (fb *Framebuffer) Depth() *Texture {
+	if fb.depth == nil {
+		return nil
+	}
+	return fb.depth.texture
+}
+
+// Bind makes fb the target of drawing and reading and sets the viewport to cover it.
+// The previous framebuffer and viewport are remembered for UnBind.
+func (fb *Framebuffer) Bind() {
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &fb.prevFramebuffer)
+	gl.GetIntegerv(gl.VIEWPORT, &fb.prevViewport[0])
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.handle)
+	gl.Viewport(0, 0, int32(fb.width), int32(fb.height))
+}
+
+// UnBind goes back to the framebuffer and viewport that were in use before Bind.
+func (fb *Framebuffer) UnBind() {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(fb.prevFramebuffer))
+	v := fb.prevViewport
+	gl.Viewport(v[0], v[1], v[2], v[3])
+}
+
+// Resize reallocates every attachment at the new size, their contents are lost and textures
+// returned by Color and Depth before are deleted.
+func (fb *Framebuffer) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errFramebufferSize
+	}
+	fb.deleteAttachments()
+	fb.width, fb.height = width, height
+
+	var prev int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &prev)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.handle)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(prev))
+
+	drawBuffers := make([]uint32, len(fb.spec.Color))
+	for i, spec := range fb.spec.Color {
+		img, err := fb.attach(gl.COLOR_ATTACHMENT0+uint32(i), spec)
+		if err != nil {
+			return err
+		}
+		fb.color = append(fb.color, img)
+		drawBuffers[i] = gl.COLOR_ATTACHMENT0 + uint32(i)
+	}
+
+	// without color attachments (ex: a shadow map) nothing is drawn or read as color
+	if len(drawBuffers) > 0 {
+		gl.DrawBuffers(int32(len(drawBuffers)), &drawBuffers[0])
+		gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	} else {
+		gl.DrawBuffer(gl.NONE)
+		gl.ReadBuffer(gl.NONE)
+	}
+
+	if spec := fb.spec.Depth; spec != nil {
+		point := uint32(gl.DEPTH_ATTACHMENT)
+		if spec.Format == gl.DEPTH24_STENCIL8 || spec.Format == gl.DEPTH32F_STENCIL8 {
+			point = gl.DEPTH_STENCIL_ATTACHMENT
+		}
+		img, err := fb.attach(point, *spec)
+		if err != nil {
+			return err
+		}
+		fb.depth = &img
+	}
+
+	if spec := fb.spec.Stencil; spec != nil {
+		img, err := fb.attach(gl.STENCIL_ATTACHMENT, *spec)
+		if err != nil {
+			return err
+		}
+		fb.stencil = &img
+	}
+
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		return &FramebufferError{Status: status}
+	}
+	return nil
+}
+
+// attach allocates storage for spec and attaches it at point of the bound framebuffer
+func (fb *Framebuffer) attach(point uint32, spec Attachment) (attachmentImage, error) {
+	img := attachmentImage{point: point, layers: spec.Layers}
+
+	if spec.Layers > 0 && (spec.Renderbuffer || spec.Cube) {
+		return img, errArrayAttachment
+	}
+	if spec.Cube && spec.Renderbuffer {
+		return img, errCubeRenderbuffer
+	}
+	if spec.Cube && fb.width != fb.height {
+		return img, errCubeSize
+	}
+
+	if spec.Renderbuffer {
+		gl.GenRenderbuffers(1, &img.renderbuffer)
+		gl.BindRenderbuffer(gl.RENDERBUFFER, img.renderbuffer)
+		gl.RenderbufferStorage(gl.RENDERBUFFER, uint32(spec.Format), int32(fb.width), int32(fb.height))
+		gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+		gl.FramebufferRenderbuffer(gl.FRAMEBUFFER, point, gl.RENDERBUFFER, img.renderbuffer)
+		return img, nil
+	}
+
+	pixels, ok := attachmentFormats[spec.Format]
+	if !ok {
+		return img, errUnknownFormat
+	}
+
+	// integer and depth/stencil textures can not be linearly filtered
+	filter := int32(gl.LINEAR)
+	switch pixels.format {
+	case gl.RED_INTEGER, gl.DEPTH_COMPONENT, gl.DEPTH_STENCIL, gl.STENCIL_INDEX:
+		filter = gl.NEAREST
+	}
+
+	tex := Texture{target: gl.TEXTURE_2D}
+	faces := []uint32{gl.TEXTURE_2D}
+	if spec.Cube {
+		tex.target = gl.TEXTURE_CUBE_MAP
+		faces = []uint32{
+			gl.TEXTURE_CUBE_MAP_POSITIVE_X, gl.TEXTURE_CUBE_MAP_NEGATIVE_X,
+			gl.TEXTURE_CUBE_MAP_POSITIVE_Y, gl.TEXTURE_CUBE_MAP_NEGATIVE_Y,
+			gl.TEXTURE_CUBE_MAP_POSITIVE_Z, gl.TEXTURE_CUBE_MAP_NEGATIVE_Z,
+		}
+	}
+
+	if spec.Layers > 0 {
+		tex.target = gl.TEXTURE_2D_ARRAY
+		faces = nil
+	}
+
+	gl.GenTextures(1, &tex.handle)
+	gl.BindTexture(tex.target, tex.handle)
+	for _, face := range faces {
+		gl.TexImage2D(face, 0, spec.Format, int32(fb.width), int32(fb.height), 0,
+			pixels.format, pixels.xtype, nil)
+	}
+	if spec.Layers > 0 {
+		gl.TexImage3D(tex.target, 0, spec.Format, int32(fb.width), int32(fb.height), int32(spec.Layers), 0,
+			pixels.format, pixels.xtype, nil)
+	}
+	gl.TexParameteri(tex.target, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(tex.target, gl.TEXTURE_MAG_FILTER, filter)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(tex.target, gl.TEXTURE_WRAP_R, gl.CLAMP_TO_EDGE)
+	gl.BindTexture(tex.target, 0)
+
+	// attaching the whole cube map instead of one face makes the framebuffer layered
+	switch {
+	case spec.Cube:
+		gl.FramebufferTexture(gl.FRAMEBUFFER, point, tex.handle, 0)
+	case spec.Layers > 0:
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, point, tex.handle, 0, 0)
+	default:
+		gl.FramebufferTexture2D(gl.FRAMEBUFFER, point, tex.target, tex.handle, 0)
+	}
+	img.texture = &tex
+	return img, nil
+}
+
+// SetLayer attaches layer i of every array attachment (see Attachment.Layers) so drawing goes
+// to it, they start out at layer 0. Every array attachment must have more than i layers.
+func (fb *Framebuffer) SetLayer(i int) error {
+	var prev int32
+	gl.GetIntegerv(gl.FRAMEBUFFER_BINDING, &prev)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, fb.handle)
+	defer gl.BindFramebuffer(gl.FRAMEBUFFER, uint32(prev))
+
+	for _, img := range fb.images() {
+		if img.layers == 0 {
+			continue
+		}
+		if i < 0 || i >= img.layers {
+			return errLayerOutOfRange
+		}
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, img.point, img.texture.handle, 0, int32(i))
+	}
+	return nil
+}
+
+// Rect is a rectangle of pixels from (X0, Y0) up to but not including (X1, Y1),
+// with the origin at the bottom left like all GL window coordinates.
+type Rect struct {
+	X0, Y0, X1, Y1 int
+}
+
+// BlitColor copies color attachment i in src to the draw buffers of dst, scaling it from
+// one rectangle to the other with filter (gl.NEAREST or gl.LINEAR). A nil dst is the window.
+func (fb *Framebuffer) BlitColor(i int, dst *Framebuffer, src, dstRect Rect, filter uint32) {
+	fb.blit(dst, gl.COLOR_ATTACHMENT0+uint32(i), src, dstRect, gl.COLOR_BUFFER_BIT, filter)
+}
+
+// BlitDepthStencil copies the depth and/or stencil buffer to dst (nil for the window),
+// mask is gl.DEPTH_BUFFER_BIT and/or gl.STENCIL_BUFFER_BIT. Both must be the same size and
+// format since depth and stencil can not be scaled or converted.
+func (fb *Framebuffer) BlitDepthStencil(dst *Framebuffer, mask uint32) {
+	fb.blit(dst, gl.NONE, fb.Bounds(), fb.Bounds(), mask, gl.NEAREST)
+}
+
+func (fb *Framebuffer) blit(dst *Framebuffer, readBuffer uint32, src, dstRect Rect, mask, filter uint32) {
+	var prevRead, prevDraw int32
+	gl.GetIntegerv(gl.READ_FRAMEBUFFER_BINDING, &prevRead)
+	gl.GetIntegerv(gl.DRAW_FRAMEBUFFER_BINDING, &prevDraw)
+
+	dstHandle := uint32(0)
+	if dst != nil {
+		dstHandle = dst.handle
+	}
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, fb.handle)
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, dstHandle)
+	if readBuffer != gl.NONE {
+		gl.ReadBuffer(readBuffer)
+	}
+
+	gl.BlitFramebuffer(int32(src.X0), int32(src.Y0), int32(src.X1), int32(src.Y1),
+		int32(dstRect.X0), int32(dstRect.Y0), int32(dstRect.X1), int32(dstRect.Y1), mask, filter)
+
+	// the read buffer is framebuffer state, put it back to the first attachment
+	if readBuffer != gl.NONE {
+		gl.ReadBuffer(gl.COLOR_ATTACHMENT0)
+	}
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, uint32(prevRead))
+	gl.BindFramebuffer(gl.DRAW_FRAMEBUFFER, uint32(prevDraw))
+}
+
+// images returns every attachment's image
+func (fb *Framebuffer) images() []attachmentImage {
+	images := append([]attachmentImage{}, fb.color...)
+	if fb.depth != nil {
+		images = append(images, *fb.depth)
+	}
+	if fb.stencil != nil {
+		images = append(images, *fb.stencil)
+	}
+	return images
+}
+
+func (fb *Framebuffer) deleteAttachments() {
+	for _, img := range fb.images() {
+		if img.texture != nil {
+			gl.DeleteTextures(1, &img.texture.handle)
+		}
+		if img.renderbuffer != 0 {
+			gl.DeleteRenderbuffers(1, &img.renderbuffer)
+		}
+	}
+
+	fb.color = nil
+	fb.depth = nil
+	fb.stencil = nil
+}
+
+func (fb *Framebuffer) Delete() {
+	fb.deleteAttachments()
+	gl.DeleteFramebuffers(1, &fb.handle)
+}
@@ -0,0 +1,231 @@
+package gfx
+
+import (
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// DeferredShaderDir is where the G-buffer and the deferred lighting load their shaders from.
+// Like every other shader path it is relative to the working directory.
+var DeferredShaderDir = "shaders/deferred"
+
+// MaxShininess is the highest material shininess a GBuffer can store, higher values are
+// clamped to it. It must match MAX_SHININESS in the deferred shaders.
+const MaxShininess = 256
+
+// the color attachments of the G-buffer, in the order shaders/deferred/geometry.frag writes them
+const (
+	gbufferAlbedo = iota
+	gbufferNormal
+	gbufferMaterial
+)
+
+// GBufferView is one of the things stored in a GBuffer, for GBuffer.DrawDebug.
+type GBufferView int32
+
+// must match the view numbers in shaders/deferred/debug.frag
+const (
+	GBufferAlbedo GBufferView = iota
+	GBufferNormal
+	GBufferSpecular
+	GBufferShininess
+	GBufferDepth
+
+	NumGBufferViews = iota
+)
+
+var gbufferViewNames = [NumGBufferViews]string{"albedo", "normals", "specular", "shininess", "depth"}
+
+func (v GBufferView) String() string {
+	return gbufferViewNames[v]
+}
+
+// GBuffer is the geometry buffer of deferred shading. The scene is drawn into it once and
+// instead of colors it stores what the lighting needs to know about the closest surface at
+// each pixel. Lights are then drawn as separate passes that read it (see DeferredLighting),
+// so the cost of a light depends on how many pixels it covers instead of how much geometry
+// there is.
+//
+//	gbuffer.Begin() // returns the program to draw the opaque scene with
+//	... set the material uniforms and draw ...
+//	gbuffer.End()
+//	gbuffer.BlitDepth() // the window's depth buffer is now the scene's
+//	lighting.Draw(gbuffer, ...)
+//
+// It stores, in view space:
+//
+//	attachment 0, RGBA8: albedo (the diffuse color)
+//	attachment 1, RGBA16F: normal
+//	attachment 2, RGBA8: specular color and shininess / MaxShininess
+//	depth, DEPTH24_STENCIL8: depth, positions are worked out from it and the projection
+type GBuffer struct {
+	fb       *Framebuffer
+	geometry *Program
+	debug    *Program
+
+	// the debug view's positions come from gl_VertexID but the core profile still requires
+	// a vertex array to be bound to draw
+	vao uint32
+}
+
+// NewGBuffer makes a G-buffer of width x height, it must be the size of the window's
+// framebuffer (which is in pixels, not screen units) for BlitDepth to work.
+func NewGBuffer(width, height int) (*GBuffer, error) {
+	gb := GBuffer{}
+
+	var err error
+	gb.geometry, err = newProgramFromFiles(filepath.Join(DeferredShaderDir, "geometry.vert"),
+		filepath.Join(DeferredShaderDir, "geometry.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	gb.debug, err = newProgramFromFiles(filepath.Join(DeferredShaderDir, "fullscreen.vert"),
+		filepath.Join(DeferredShaderDir, "debug.frag"))
+	if err != nil {
+		gb.Delete()
+		return nil, err
+	}
+
+	// the textures sampled by the diffuse maps are converted from sRGB so the albedo is
+	// linear, 8 bits lose a little detail in the darkest colors but it is a lot smaller than
+	// a float format. Normals need the precision and their negative values.
+	gb.fb, err = NewFramebuffer(width, height, FramebufferSpec{
+		Color: []Attachment{
+			gbufferAlbedo:   {Format: gl.RGBA8},
+			gbufferNormal:   {Format: gl.RGBA16F},
+			gbufferMaterial: {Format: gl.RGBA8},
+		},
+		Depth: &Attachment{Format: gl.DEPTH24_STENCIL8},
+	})
+	if err != nil {
+		gb.Delete()
+		return nil, err
+	}
+
+	gl.GenVertexArrays(1, &gb.vao)
+
+	return &gb, nil
+}
+
+func (gb *GBuffer) Width() int {
+	return gb.fb.Width()
+}
+
+func (gb *GBuffer) Height() int {
+	return gb.fb.Height()
+}
+
+// Resize reallocates the G-buffer at width x height, it is empty until the scene is drawn again.
+func (gb *GBuffer) Resize(width, height int) error {
+	return gb.fb.Resize(width, height)
+}
+
+// Begin clears the G-buffer and sets up drawing the opaque scene into it, it returns the
+// program to draw with which is already in use. The program has the "model", "view" and
+// "project" uniforms and a Material struct with diffuse and specular maps and a shininess.
+func (gb *GBuffer) Begin() *Program {
+	gb.fb.Bind()
+
+	// the albedo of pixels nothing is drawn to is black, the lighting skips them anyway
+	gl.ClearColor(0, 0, 0, 0)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	gb.geometry.Use()
+	return gb.geometry
+}
+
+// End goes back to the framebuffer and viewport that were in use before Begin.
+func (gb *GBuffer) End() {
+	gb.fb.UnBind()
+}
+
+// BlitDepth copies the scene's depth to the window so the light volumes are tested against it
+// and anything drawn forward afterwards (ex: transparent surfaces) is hidden behind the
+// opaque scene. The window must have a 24 bit depth and 8 bit stencil buffer, GLFW's default.
+func (gb *GBuffer) BlitDepth() {
+	gb.fb.BlitDepthStencil(nil, gl.DEPTH_BUFFER_BIT)
+}
+
+// BindTextures binds the G-buffer's images to the 4 texture units from firstUnit on and sets
+// the gAlbedo, gNormal, gMaterial and gDepth samplers of prog, which must be in use.
+func (gb *GBuffer) BindTextures(prog *Program, firstUnit uint32) {
+	textures := []struct {
+		name string
+		tex  *Texture
+	}{
+		{"gAlbedo", gb.fb.Color(gbufferAlbedo)},
+		{"gNormal", gb.fb.Color(gbufferNormal)},
+		{"gMaterial", gb.fb.Color(gbufferMaterial)},
+		{"gDepth", gb.fb.Depth()},
+	}
+	for i, t := range textures {
+		t.tex.Bind(firstUnit + uint32(i))
+		t.tex.SetUniform(prog.GetUniformLocation(t.name))
+	}
+}
+
+// UnBindTextures unbinds the images bound by BindTextures.
+func (gb *GBuffer) UnBindTextures() {
+	for i := gbufferAlbedo; i <= gbufferMaterial; i++ {
+		gb.fb.Color(i).UnBind()
+	}
+	gb.fb.Depth().UnBind()
+}
+
+// DrawDebug fills the bound framebuffer with one of the things stored in the G-buffer.
+// project is the projection the scene was drawn with, it is needed to turn depths back into
+// distances which are shown from black at the camera to white at the far plane.
+func (gb *GBuffer) DrawDebug(view GBufferView, project mgl32.Mat4) {
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+
+	// only the albedo is a color, the rest are shown as they are stored instead of being
+	// brightened by the conversion to sRGB
+	srgb := gl.IsEnabled(gl.FRAMEBUFFER_SRGB)
+	if view != GBufferAlbedo {
+		gl.Disable(gl.FRAMEBUFFER_SRGB)
+	}
+
+	gb.debug.Use()
+	gb.BindTextures(gb.debug, gl.TEXTURE0)
+	gl.Uniform1i(gb.debug.GetUniformLocation("view"), int32(view))
+
+	inverseProject := project.Inv()
+	gl.UniformMatrix4fv(gb.debug.GetUniformLocation("inverseProject"), 1, false, &inverseProject[0])
+	gl.Uniform1f(gb.debug.GetUniformLocation("far"), perspectiveFar(project))
+
+	gl.BindVertexArray(gb.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+
+	gb.UnBindTextures()
+
+	if srgb {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	}
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (gb *GBuffer) Delete() {
+	if gb.fb != nil {
+		gb.fb.Delete()
+	}
+	if gb.geometry != nil {
+		gb.geometry.Delete()
+	}
+	if gb.debug != nil {
+		gb.debug.Delete()
+	}
+	gl.DeleteVertexArrays(1, &gb.vao)
+}
+
+// perspectiveFar is the distance to the far plane of a projection made by mgl32.Perspective
+func perspectiveFar(project mgl32.Mat4) float32 {
+	// the third row maps view z to clip z as (A*z + B) and z = -far ends up at depth 1
+	return project[14] / (project[10] + 1)
+}
@@ -0,0 +1,265 @@
+package gfx
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// must match KERNEL_SIZE in shaders/deferred/ssao.frag
+const ssaoKernelSize = 32
+
+// the noise texture is ssaoNoiseSize x ssaoNoiseSize, must match NOISE_SIZE in
+// shaders/deferred/ssao_blur.frag
+const ssaoNoiseSize = 4
+
+// SSAO is screen space ambient occlusion. Ambient light stands in for the light bounced around
+// by everything, creases and corners get less of it since their surroundings block most
+// directions. SSAO estimates how much from the depths and normals in a GBuffer: points are
+// scattered in the hemisphere above each surface and the ones behind the depth drawn where
+// they land on the screen count as blocked.
+//
+//	ssao.Draw(gbuffer, projectTransform)
+//	lighting.Occlusion = ssao.Texture()
+//	lighting.Draw(gbuffer, ...)
+//
+// Every pixel turns the points by a random angle from a small tiling noise texture, which
+// trades banding for noise, and a blur as large as the tile removes the noise again.
+type SSAO struct {
+	// a disabled SSAO leaves the occlusion white, nothing is blocked
+	Enabled bool
+
+	// Radius is how far around a surface is searched for what blocks it in world units, larger
+	// values darken wider areas but miss small creases
+	Radius float32
+
+	// Bias is how far a point must be behind the drawn depth to count as blocked, it keeps
+	// flat surfaces from blocking themselves where the depth is not precise enough
+	Bias float32
+
+	// Strength is a power the unblocked fraction is raised to, above 1 it darkens the
+	// occlusion and below 1 it lightens it
+	Strength float32
+
+	// Blur removes the noise from the random rotations
+	Blur bool
+
+	kernel [ssaoKernelSize]mgl32.Vec3
+	noise  *Texture
+
+	fb     *Framebuffer
+	blurFB *Framebuffer
+
+	ssao  *Program
+	blur  *Program
+	debug *Program
+
+	// the fullscreen triangle's positions come from gl_VertexID but the core profile still
+	// requires a vertex array to be bound to draw
+	vao uint32
+}
+
+// NewSSAO makes the occlusion buffers at width x height, they must be the size of the G-buffer.
+func NewSSAO(width, height int) (*SSAO, error) {
+	s := SSAO{
+		Enabled:  true,
+		Radius:   0.5,
+		Bias:     0.025,
+		Strength: 1,
+		Blur:     true,
+	}
+
+	vert := filepath.Join(DeferredShaderDir, "fullscreen.vert")
+
+	var err error
+	s.ssao, err = newProgramFromFiles(vert, filepath.Join(DeferredShaderDir, "ssao.frag"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.blur, err = newProgramFromFiles(vert, filepath.Join(DeferredShaderDir, "ssao_blur.frag"))
+	if err != nil {
+		s.Delete()
+		return nil, err
+	}
+
+	s.debug, err = newProgramFromFiles(vert, filepath.Join(DeferredShaderDir, "ssao_debug.frag"))
+	if err != nil {
+		s.Delete()
+		return nil, err
+	}
+
+	// one channel is enough for an amount of light
+	for _, fb := range []**Framebuffer{&s.fb, &s.blurFB} {
+		*fb, err = NewFramebuffer(width, height, FramebufferSpec{
+			Color: []Attachment{{Format: gl.R8}},
+		})
+		if err != nil {
+			s.Delete()
+			return nil, err
+		}
+	}
+
+	// the same every run so the occlusion does not change between runs
+	random := rand.New(rand.NewSource(1))
+	s.makeKernel(random)
+	s.makeNoise(random)
+
+	gl.GenVertexArrays(1, &s.vao)
+
+	return &s, nil
+}
+
+// makeKernel scatters the points in the hemisphere above a surface facing +z, most of them close
+// to the surface where what blocks the light matters most
+func (s *SSAO) makeKernel(random *rand.Rand) {
+	for i := range s.kernel {
+		p := mgl32.Vec3{
+			2*random.Float32() - 1,
+			2*random.Float32() - 1,
+			random.Float32(),
+		}.Normalize().Mul(random.Float32())
+
+		t := float32(i) / ssaoKernelSize
+		s.kernel[i] = p.Mul(0.1 + 0.9*t*t)
+	}
+}
+
+// makeNoise makes the tile of random rotations, each texel is a direction around +z that
+// the shader turns the kernel towards
+func (s *SSAO) makeNoise(random *rand.Rand) {
+	var texels []float32
+	for i := 0; i < ssaoNoiseSize*ssaoNoiseSize; i++ {
+		texels = append(texels, 2*random.Float32()-1, 2*random.Float32()-1, 0)
+	}
+
+	s.noise = &Texture{target: gl.TEXTURE_2D}
+	gl.GenTextures(1, &s.noise.handle)
+	gl.BindTexture(gl.TEXTURE_2D, s.noise.handle)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB16F, ssaoNoiseSize, ssaoNoiseSize, 0, gl.RGB, gl.FLOAT, gl.Ptr(texels))
+
+	// repeated across the screen with every texel kept as it is
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.REPEAT)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.REPEAT)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+}
+
+// Resize reallocates the occlusion buffers at width x height, they are empty until Draw.
+func (s *SSAO) Resize(width, height int) error {
+	if err := s.fb.Resize(width, height); err != nil {
+		return err
+	}
+	return s.blurFB.Resize(width, height)
+}
+
+// Texture is the occlusion from the last Draw, 1 where nothing is blocked and 0 where
+// everything is. It is blurred when Blur is on.
+func (s *SSAO) Texture() *Texture {
+	if s.Blur {
+		return s.blurFB.Color(0)
+	}
+	return s.fb.Color(0)
+}
+
+// Draw works out the occlusion of the surfaces in gb, which was drawn with project.
+func (s *SSAO) Draw(gb *GBuffer, project mgl32.Mat4) {
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+	gl.BindVertexArray(s.vao)
+
+	s.fb.Bind()
+	if !s.Enabled {
+		gl.ClearColor(1, 1, 1, 1)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+	} else {
+		s.ssao.Use()
+		gb.BindTextures(s.ssao, gl.TEXTURE0)
+		s.noise.Bind(gl.TEXTURE4)
+		s.noise.SetUniform(s.ssao.GetUniformLocation("noise"))
+		gl.Uniform2f(s.ssao.GetUniformLocation("noiseScale"),
+			float32(s.fb.Width())/ssaoNoiseSize, float32(s.fb.Height())/ssaoNoiseSize)
+
+		for i, p := range s.kernel {
+			setUniformVec3(s.ssao, fmt.Sprintf("samples[%d]", i), p)
+		}
+
+		inverseProject := project.Inv()
+		gl.UniformMatrix4fv(s.ssao.GetUniformLocation("project"), 1, false, &project[0])
+		gl.UniformMatrix4fv(s.ssao.GetUniformLocation("inverseProject"), 1, false, &inverseProject[0])
+		gl.Uniform1f(s.ssao.GetUniformLocation("radius"), s.Radius)
+		gl.Uniform1f(s.ssao.GetUniformLocation("bias"), s.Bias)
+		gl.Uniform1f(s.ssao.GetUniformLocation("strength"), s.Strength)
+
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+
+		gb.UnBindTextures()
+		s.noise.UnBind()
+	}
+	s.fb.UnBind()
+
+	if s.Blur {
+		s.blurFB.Bind()
+		s.blur.Use()
+		input := s.fb.Color(0)
+		input.Bind(gl.TEXTURE0)
+		input.SetUniform(s.blur.GetUniformLocation("ssaoInput"))
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+		input.UnBind()
+		s.blurFB.UnBind()
+	}
+
+	gl.BindVertexArray(0)
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+// DrawDebug fills the bound framebuffer with the occlusion from the last Draw in shades of gray.
+func (s *SSAO) DrawDebug() {
+	depthTest := gl.IsEnabled(gl.DEPTH_TEST)
+	gl.Disable(gl.DEPTH_TEST)
+
+	// an amount rather than a color, it is shown as it is stored like the G-buffer's normals
+	srgb := gl.IsEnabled(gl.FRAMEBUFFER_SRGB)
+	gl.Disable(gl.FRAMEBUFFER_SRGB)
+
+	s.debug.Use()
+	tex := s.Texture()
+	tex.Bind(gl.TEXTURE0)
+	tex.SetUniform(s.debug.GetUniformLocation("occlusion"))
+
+	gl.BindVertexArray(s.vao)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gl.BindVertexArray(0)
+
+	tex.UnBind()
+
+	if srgb {
+		gl.Enable(gl.FRAMEBUFFER_SRGB)
+	}
+	if depthTest {
+		gl.Enable(gl.DEPTH_TEST)
+	}
+}
+
+func (s *SSAO) Delete() {
+	for _, fb := range []*Framebuffer{s.fb, s.blurFB} {
+		if fb != nil {
+			fb.Delete()
+		}
+	}
+	for _, prog := range []*Program{s.ssao, s.blur, s.debug} {
+		if prog != nil {
+			prog.Delete()
+		}
+	}
+	if s.noise != nil {
+		gl.DeleteTextures(1, &s.noise.handle)
+	}
+	gl.DeleteVertexArrays(1, &s.vao)
+}
@@ -0,0 +1,396 @@
+package main
+
+/*
+Adapted from this tutorial: http://www.learnopengl.com/#!Advanced-Lighting/SSAO
+
+Builds on deferred-shading. The ambient term of phong lighting is the same everywhere but in
+reality less of the light bounced around by the scene reaches into corners, creases and the
+ground under things. Screen space ambient occlusion (gfx.SSAO) estimates how much less from the
+depths and normals in the G-buffer and the lighting multiplies the ambient light by it. The
+scene is lit mostly by ambient light, like an overcast day, so the difference shows.
+Only the deferred lighting applies the occlusion. Unlike deferred-shading there is no forward
+pass for transparent surfaces, the unlit light gizmos are the only thing drawn on top.
+
+Press O to toggle the occlusion, B to toggle its blur and V to cycle between the lit scene, the
+occlusion and the images in the G-buffer. Hold up and down to change the radius searched for
+what blocks the light and left and right to change the bias, [ and ] change the strength.
+*/
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/ssao/cam"
+	"github.com/cstegel/opengl-samples-golang/ssao/gfx"
+	"github.com/cstegel/opengl-samples-golang/ssao/scene"
+	"github.com/cstegel/opengl-samples-golang/ssao/win"
+)
+
+const (
+	// how fast holding the radius and bias keys changes them
+	radiusPerSecond = 0.5
+	biasPerSecond   = 0.05
+
+	strengthStep = 0.25
+)
+
+// what fills the window, the G-buffer's images come after these
+const (
+	viewLit = iota
+	viewOcclusion
+	numViews = viewOcclusion + 1 + gfx.NumGBufferViews
+)
+
+// nodes that are animated every frame
+type sceneNodes struct {
+	cubes  []*scene.Node
+	lights []*scene.Node
+}
+
+func buildScene(cube, floor, ball *gfx.VertexArray) (*scene.Scene, sceneNodes) {
+	s := scene.New()
+	nodes := sceneNodes{}
+
+	// a lot of ambient light from the overcast sky and a weak sun
+	sun := s.Add(scene.NewNode("sun"))
+	sun.DirectionalLight = &gfx.DirectionalLight{
+		Direction: mgl32.Vec3{-0.4, -1.0, -0.3},
+		Ambient:   mgl32.Vec3{0.35, 0.35, 0.38},
+		Diffuse:   mgl32.Vec3{0.35, 0.33, 0.3},
+		Specular:  mgl32.Vec3{0.2, 0.2, 0.2},
+	}
+
+	ground := s.Add(scene.NewNode("ground"))
+	ground.SetScale(mgl32.Vec3{40, 1, 40})
+	ground.Mesh = floor
+
+	// walls meeting in a corner with things piled up against them
+	for i, wall := range []struct{ pos, scale mgl32.Vec3 }{
+		{mgl32.Vec3{0, 2.5, -6}, mgl32.Vec3{20, 5, 1}},
+		{mgl32.Vec3{-10, 2.5, 0}, mgl32.Vec3{1, 5, 13}},
+	} {
+		node := s.Add(scene.NewNode(fmt.Sprintf("wall %d", i)))
+		node.SetPosition(wall.pos)
+		node.SetScale(wall.scale)
+		node.Mesh = cube
+	}
+
+	// stairs up the back wall
+	for i := 0; i < 5; i++ {
+		height := float32(i + 1)
+		step := s.Add(scene.NewNode(fmt.Sprintf("step %d", i)))
+		step.SetPosition(mgl32.Vec3{2 + float32(i), height / 2, -5})
+		step.SetScale(mgl32.Vec3{1, height, 1})
+		step.Mesh = cube
+	}
+
+	// a pile of crates in the corner
+	for i, crate := range []struct {
+		pos   mgl32.Vec3
+		size  float32
+		angle float32
+	}{
+		{mgl32.Vec3{-8.5, 1, -4.5}, 2, 0},
+		{mgl32.Vec3{-6.3, 0.75, -4.6}, 1.5, 10},
+		{mgl32.Vec3{-8.6, 0.5, -2.4}, 1, -15},
+		{mgl32.Vec3{-8.4, 2.6, -4.4}, 1.2, 30},
+		{mgl32.Vec3{-5, 0.4, -2}, 0.8, 45},
+	} {
+		node := s.Add(scene.NewNode(fmt.Sprintf("crate %d", i)))
+		node.SetPosition(crate.pos)
+		node.SetScale(mgl32.Vec3{crate.size, crate.size, crate.size})
+		node.Rotate(mgl32.DegToRad(crate.angle), mgl32.Vec3{0, 1, 0})
+		node.Mesh = cube
+	}
+
+	// balls resting on the ground, darkest where they touch it
+	for i, x := range []float32{-3, -1, 1.5} {
+		size := 1 + 0.5*float32(i)
+		node := s.Add(scene.NewNode(fmt.Sprintf("ball %d", i)))
+		node.SetPosition(mgl32.Vec3{x, size / 2, -1})
+		node.SetScale(mgl32.Vec3{size, size, size})
+		node.Mesh = ball
+	}
+
+	// spinning crates hovering over the ground
+	for i := 0; i < 3; i++ {
+		node := s.Add(scene.NewNode(fmt.Sprintf("cube %d", i)))
+		node.SetPosition(mgl32.Vec3{4 + 2*float32(i), 0.9, 1})
+		node.Mesh = cube
+		nodes.cubes = append(nodes.cubes, node)
+	}
+
+	// the gizmo balls are drawn in the light's color
+	for i, color := range []mgl32.Vec3{{1, 0.6, 0.3}, {0.3, 0.6, 1}} {
+		light := s.Add(scene.NewNode(fmt.Sprintf("point light %d", i)))
+		light.SetScale(mgl32.Vec3{0.2, 0.2, 0.2})
+		light.Mesh = ball
+		light.PointLight = &gfx.PointLight{
+			Diffuse:     color,
+			Specular:    color,
+			Attenuation: gfx.AttenuationForDistance(13),
+		}
+		nodes.lights = append(nodes.lights, light)
+	}
+
+	return s, nodes
+}
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "SSAO")
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window) error {
+
+	lightVertShader, err := gfx.NewShaderFromFile("shaders/light.vert", gl.VERTEX_SHADER)
+	if err != nil {
+		return err
+	}
+
+	lightFragShader, err := gfx.NewShaderFromFile("shaders/light.frag", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return err
+	}
+
+	// special shader program so that lights themselves are not affected by lighting
+	lightProgram, err := gfx.NewProgram(lightVertShader, lightFragShader)
+	if err != nil {
+		return err
+	}
+	defer lightProgram.Delete()
+
+	cube, err := gfx.NewCubeMesh().Upload()
+	if err != nil {
+		return err
+	}
+	defer cube.Delete()
+
+	floor, err := gfx.NewPlaneMesh(20).Upload()
+	if err != nil {
+		return err
+	}
+	defer floor.Delete()
+
+	ball, err := gfx.NewSphereMesh(16, 32).Upload()
+	if err != nil {
+		return err
+	}
+	defer ball.Delete()
+
+	diffuseMap, err := gfx.NewTextureFromFile("../images/container2.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	specularMap, err := gfx.NewTextureFromFile("../images/container2_specular.png", gl.REPEAT, gl.REPEAT)
+	if err != nil {
+		return err
+	}
+
+	gbuffer, err := gfx.NewGBuffer(window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+	defer gbuffer.Delete()
+
+	ssao, err := gfx.NewSSAO(window.FramebufferWidth(), window.FramebufferHeight())
+	if err != nil {
+		return err
+	}
+	defer ssao.Delete()
+
+	lighting, err := gfx.NewDeferredLighting()
+	if err != nil {
+		return err
+	}
+	defer lighting.Delete()
+
+	world, nodes := buildScene(cube, floor, ball)
+	view := viewLit
+
+	lens := scene.Camera{Fov: 45, Near: 0.1, Far: 100}
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	camera := cam.NewFpsCamera(mgl32.Vec3{-1, 3, 9}, mgl32.Vec3{0, 1, 0}, -100, -15, window.InputManager())
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		camera.Update(window.SinceLastFrame())
+
+		// a minimized window has an empty framebuffer, the old size is kept until it comes back
+		if window.FramebufferResized() && window.FramebufferWidth() > 0 && window.FramebufferHeight() > 0 {
+			width, height := window.FramebufferWidth(), window.FramebufferHeight()
+			if err := gbuffer.Resize(width, height); err != nil {
+				return err
+			}
+			if err := ssao.Resize(width, height); err != nil {
+				return err
+			}
+		}
+
+		dt := float32(window.SinceLastFrame())
+		input := window.InputManager()
+
+		if input.IsTriggered(win.TOGGLE_SSAO) {
+			ssao.Enabled = !ssao.Enabled
+		}
+		if input.IsTriggered(win.TOGGLE_SSAO_BLUR) {
+			ssao.Blur = !ssao.Blur
+		}
+		if input.IsTriggered(win.NEXT_VIEW) {
+			view = (view + 1) % numViews
+		}
+		if input.IsActive(win.SSAO_RADIUS_UP) {
+			ssao.Radius = mgl32.Clamp(ssao.Radius+radiusPerSecond*dt, 0.05, 3)
+		}
+		if input.IsActive(win.SSAO_RADIUS_DOWN) {
+			ssao.Radius = mgl32.Clamp(ssao.Radius-radiusPerSecond*dt, 0.05, 3)
+		}
+		if input.IsActive(win.SSAO_BIAS_UP) {
+			ssao.Bias = mgl32.Clamp(ssao.Bias+biasPerSecond*dt, 0, 0.5)
+		}
+		if input.IsActive(win.SSAO_BIAS_DOWN) {
+			ssao.Bias = mgl32.Clamp(ssao.Bias-biasPerSecond*dt, 0, 0.5)
+		}
+		if input.IsTriggered(win.MORE_SSAO_STRENGTH) {
+			ssao.Strength = mgl32.Clamp(ssao.Strength+strengthStep, strengthStep, 4)
+		}
+		if input.IsTriggered(win.LESS_SSAO_STRENGTH) {
+			ssao.Strength = mgl32.Clamp(ssao.Strength-strengthStep, strengthStep, 4)
+		}
+
+		// animate the nodes
+		time := float32(glfw.GetTime())
+		for _, node := range nodes.cubes {
+			angle := mgl32.DegToRad(-45 * time)
+			node.SetRotation(mgl32.AnglesToQuat(angle, angle, angle, mgl32.XYZ))
+		}
+		for i, light := range nodes.lights {
+			angle := float64(0.4*time) + float64(i)*math.Pi
+			light.SetPosition(mgl32.Vec3{-2 + 5*float32(math.Cos(angle)), 1.5, 5 * float32(math.Sin(angle))})
+		}
+
+		lights := world.Lights()
+		camTransform := camera.GetTransform()
+		projectTransform := lens.Projection(float32(window.FramebufferWidth()) / float32(window.FramebufferHeight()))
+
+		// background color
+		gl.ClearColor(0.6, 0.65, 0.7, 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		// the opaque scene without lighting into the G-buffer
+		gprog := gbuffer.Begin()
+		diffuseMap.Bind(gl.TEXTURE0)
+		diffuseMap.SetUniform(gprog.GetUniformLocation("material.diffuse"))
+		specularMap.Bind(gl.TEXTURE1)
+		specularMap.SetUniform(gprog.GetUniformLocation("material.specular"))
+		gl.Uniform1f(gprog.GetUniformLocation("material.shininess"), 32.0)
+		gl.UniformMatrix4fv(gprog.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(gprog.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(gprog, func(n *scene.Node) bool {
+			return !isLight(n)
+		})
+		gbuffer.End()
+		diffuseMap.UnBind()
+		specularMap.UnBind()
+
+		// the occlusion only needs the G-buffer
+		ssao.Draw(gbuffer, projectTransform)
+
+		window.SetTitle(fmt.Sprintf("SSAO - %s, radius %.2f, bias %.3f, strength %.2f, blur %s, "+
+			"showing %s, gamma correction %s",
+			onOff(ssao.Enabled), ssao.Radius, ssao.Bias, ssao.Strength, onOff(ssao.Blur),
+			viewName(view), onOff(window.GammaCorrection())))
+
+		switch {
+		case view == viewOcclusion:
+			ssao.DrawDebug()
+			continue
+		case view > viewOcclusion:
+			gbuffer.DrawDebug(gfx.GBufferView(view-viewOcclusion-1), projectTransform)
+			continue
+		}
+
+		// the lighting is tested against the scene's depth
+		gbuffer.BlitDepth()
+		lighting.Occlusion = ssao.Texture()
+		lighting.Draw(gbuffer, lights.Directional, lights.Point, lights.Spot, camTransform, projectTransform)
+
+		lightProgram.Use()
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("view"), 1, false, &camTransform[0])
+		gl.UniformMatrix4fv(lightProgram.GetUniformLocation("project"), 1, false, &projectTransform[0])
+		world.Draw(lightProgram, func(n *scene.Node) bool {
+			if n.PointLight == nil {
+				return false
+			}
+			color := n.PointLight.Diffuse
+			gl.Uniform3f(lightProgram.GetUniformLocation("lightColor"), color.X(), color.Y(), color.Z())
+			return true
+		})
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+// viewName describes what view shows
+func viewName(view int) string {
+	switch view {
+	case viewLit:
+		return "the lit scene"
+	case viewOcclusion:
+		return "the occlusion"
+	default:
+		return "the G-buffer's " + gfx.GBufferView(view-viewOcclusion-1).String()
+	}
+}
+
+func isLight(n *scene.Node) bool {
+	return n.PointLight != nil || n.SpotLight != nil
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
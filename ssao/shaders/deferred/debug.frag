@@ -0,0 +1,47 @@
+#version 410 core
+
+// shows one of the images in the G-buffer
+
+// must match the GBufferView constants in gfx/gbuffer.go
+#define ALBEDO 0
+#define NORMAL 1
+#define SPECULAR 2
+#define SHININESS 3
+#define DEPTH 4
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D gAlbedo;
+uniform sampler2D gNormal;
+uniform sampler2D gMaterial;
+uniform sampler2D gDepth;
+
+uniform int view;
+
+// to turn depths back into distances, the depths of a perspective projection are packed
+// towards the far plane so the image would be almost all white
+uniform mat4 inverseProject;
+uniform float far;
+
+void main()
+{
+	vec3 result = vec3(0.0);
+
+	if (view == ALBEDO) {
+		result = texture(gAlbedo, TexCoords).rgb;
+	} else if (view == NORMAL) {
+		// from [-1, 1] to colors, facing the viewer is blue
+		result = texture(gNormal, TexCoords).xyz * 0.5 + 0.5;
+	} else if (view == SPECULAR) {
+		result = texture(gMaterial, TexCoords).rgb;
+	} else if (view == SHININESS) {
+		result = vec3(texture(gMaterial, TexCoords).a);
+	} else if (view == DEPTH) {
+		float depth = texture(gDepth, TexCoords).r;
+		vec4 viewPos = inverseProject * vec4(vec3(TexCoords, depth) * 2.0 - 1.0, 1.0);
+		result = vec3(-viewPos.z / viewPos.w / far);
+	}
+
+	color = vec4(result, 1.0);
+}
@@ -0,0 +1,74 @@
+#version 410 core
+
+// screen space ambient occlusion, how much of the hemisphere above each surface in the
+// G-buffer is blocked by the surfaces around it (see gfx/ssao.go)
+
+// must match ssaoKernelSize in gfx/ssao.go
+#define KERNEL_SIZE 32
+
+in vec2 TexCoords;
+out float occlusion;
+
+uniform sampler2D gNormal;
+uniform sampler2D gDepth;
+
+// random rotations around the normal repeated across the screen, noiseScale is how many
+// times it fits
+uniform sampler2D noise;
+uniform vec2 noiseScale;
+
+// points in the hemisphere around +z, more of them close to the middle
+uniform vec3 samples[KERNEL_SIZE];
+
+uniform mat4 project;
+uniform mat4 inverseProject;
+
+uniform float radius;    // of the hemisphere in view space units
+uniform float bias;      // how far a sample must be behind a surface to be blocked by it
+uniform float strength;  // the fraction left unblocked is raised to this power
+
+// undo the projection to get back the view space position the depth was written from
+vec3 viewPosition(vec2 uv)
+{
+	float depth = texture(gDepth, uv).r;
+	vec4 viewPos = inverseProject * vec4(vec3(uv, depth) * 2.0 - 1.0, 1.0);
+	return viewPos.xyz / viewPos.w;
+}
+
+void main()
+{
+	// nothing was drawn here so nothing is blocked
+	if (texture(gDepth, TexCoords).r == 1.0) {
+		occlusion = 1.0;
+		return;
+	}
+
+	vec3 position = viewPosition(TexCoords);
+	vec3 normal = normalize(texture(gNormal, TexCoords).xyz);
+
+	// a basis around the normal turned by the noise so neighbouring pixels sample different
+	// points, the banding that would come from using the same points everywhere turns into
+	// noise that the blur removes
+	vec3 randomVec = normalize(texture(noise, TexCoords * noiseScale).xyz);
+	vec3 tangent = normalize(randomVec - normal * dot(randomVec, normal));
+	vec3 bitangent = cross(normal, tangent);
+	mat3 TBN = mat3(tangent, bitangent, normal);
+
+	float blocked = 0.0;
+	for (int i = 0; i < KERNEL_SIZE; i++) {
+		vec3 samplePos = position + TBN * samples[i] * radius;
+
+		// where the sample is on the screen and the surface that was drawn there
+		vec4 offset = project * vec4(samplePos, 1.0);
+		vec2 uv = offset.xy / offset.w * 0.5 + 0.5;
+		float surfaceDepth = viewPosition(uv).z;
+
+		// the surface is in front of the sample so it blocks it, unless it is much further
+		// in front than the radius (ex: an object in front of a far wall) in which case it
+		// fades out
+		float rangeCheck = smoothstep(0.0, 1.0, radius / abs(position.z - surfaceDepth));
+		blocked += (surfaceDepth >= samplePos.z + bias ? 1.0 : 0.0) * rangeCheck;
+	}
+
+	occlusion = pow(1.0 - blocked / float(KERNEL_SIZE), strength);
+}
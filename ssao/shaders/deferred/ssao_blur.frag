@@ -0,0 +1,26 @@
+#version 410 core
+
+// averages the occlusion over the size of the noise texture, which is what makes the noise
+// repeat so it cancels out
+
+// must match ssaoNoiseSize in gfx/ssao.go
+#define NOISE_SIZE 4
+
+in vec2 TexCoords;
+out float occlusion;
+
+uniform sampler2D ssaoInput;
+
+void main()
+{
+	vec2 texelSize = 1.0 / vec2(textureSize(ssaoInput, 0));
+
+	float sum = 0.0;
+	for (int x = -NOISE_SIZE / 2; x < NOISE_SIZE / 2; x++) {
+		for (int y = -NOISE_SIZE / 2; y < NOISE_SIZE / 2; y++) {
+			sum += texture(ssaoInput, TexCoords + vec2(x, y) * texelSize).r;
+		}
+	}
+
+	occlusion = sum / float(NOISE_SIZE * NOISE_SIZE);
+}
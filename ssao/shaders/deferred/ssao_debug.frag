@@ -0,0 +1,13 @@
+#version 410 core
+
+// shows the ambient occlusion, white is open and black is fully blocked
+
+in vec2 TexCoords;
+out vec4 color;
+
+uniform sampler2D occlusion;
+
+void main()
+{
+	color = vec4(vec3(texture(occlusion, TexCoords).r), 1.0);
+}
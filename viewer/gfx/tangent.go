@@ -0,0 +1,112 @@
+package gfx
+
+import (
+	"errors"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+var errMeshTangentInputs = errors.New("generating tangents requires normals and uvs")
+
+// GenerateTangents fills in Tangents for normal mapping.
+//
+// It follows the MikkTSpace conventions so that normal maps baked by common tools
+// line up: per triangle tangent/bitangent directions are projected onto the plane of each
+// corner's vertex normal, normalized and accumulated weighted by the corner angle, the final
+// tangent is orthogonalized against the normal and w stores the bitangent handedness.
+// The bitangent is not stored, shaders rebuild it as w * cross(normal, tangent).
+// Unlike the reference implementation vertices are not welded first, so only vertices that
+// share an index (through Indices) share a tangent frame.
+func (m *Mesh) GenerateTangents() error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	if len(m.Normals) == 0 || len(m.UVs) == 0 {
+		return errMeshTangentInputs
+	}
+
+	n := m.NumVertices()
+	tangents := make([]mgl32.Vec3, n)
+	bitangents := make([]mgl32.Vec3, n)
+
+	for tri := 0; tri < m.NumTriangles(); tri++ {
+		i0, i1, i2 := m.Triangle(tri)
+		idx := [3]uint32{i0, i1, i2}
+		p := [3]mgl32.Vec3{m.Positions[i0], m.Positions[i1], m.Positions[i2]}
+
+		e1 := p[1].Sub(p[0])
+		e2 := p[2].Sub(p[0])
+		duv1 := m.UVs[i1].Sub(m.UVs[i0])
+		duv2 := m.UVs[i2].Sub(m.UVs[i0])
+
+		// solve e1 = duv1.x*T + duv1.y*B, e2 = duv2.x*T + duv2.y*B
+		// only the direction matters since it gets normalized, so use the sign of the
+		// determinant instead of dividing by it (avoids blowing up on tiny uv triangles)
+		det := duv1.X()*duv2.Y() - duv2.X()*duv1.Y()
+		if det == 0 {
+			continue // degenerate uvs, this triangle says nothing about the tangent frame
+		}
+		sdir := e1.Mul(duv2.Y()).Sub(e2.Mul(duv1.Y()))
+		tdir := e2.Mul(duv1.X()).Sub(e1.Mul(duv2.X()))
+		if det < 0 {
+			sdir = sdir.Mul(-1)
+			tdir = tdir.Mul(-1)
+		}
+
+		for k := 0; k < 3; k++ {
+			v := idx[k]
+			norm := m.Normals[v].Normalize()
+
+			t := normalizeOrZero(sdir.Sub(norm.Mul(norm.Dot(sdir))))
+			b := normalizeOrZero(tdir.Sub(norm.Mul(norm.Dot(tdir))))
+
+			weight := cornerAngle(p[k], p[(k+1)%3], p[(k+2)%3])
+			tangents[v] = tangents[v].Add(t.Mul(weight))
+			bitangents[v] = bitangents[v].Add(b.Mul(weight))
+		}
+	}
+
+	m.Tangents = make([]mgl32.Vec4, n)
+	for i := range m.Tangents {
+		norm := m.Normals[i].Normalize()
+
+		// Gram-Schmidt so the tangent is perpendicular to the normal
+		t := tangents[i].Sub(norm.Mul(norm.Dot(tangents[i])))
+		if t.Len() < 1e-6 {
+			t = perpendicular(norm)
+		}
+		t = t.Normalize()
+
+		w := float32(1)
+		if norm.Cross(t).Dot(bitangents[i]) < 0 {
+			w = -1
+		}
+		m.Tangents[i] = t.Vec4(w)
+	}
+
+	return nil
+}
+
+func normalizeOrZero(v mgl32.Vec3) mgl32.Vec3 {
+	if v.Len() < 1e-12 {
+		return mgl32.Vec3{}
+	}
+	return v.Normalize()
+}
+
+// cornerAngle is the angle at corner p between the edges to a and b
+func cornerAngle(p, a, b mgl32.Vec3) float32 {
+	ea := normalizeOrZero(a.Sub(p))
+	eb := normalizeOrZero(b.Sub(p))
+	return float32(math.Acos(float64(mgl32.Clamp(ea.Dot(eb), -1, 1))))
+}
+
+// perpendicular returns some unit vector perpendicular to the unit vector v
+func perpendicular(v mgl32.Vec3) mgl32.Vec3 {
+	axis := mgl32.Vec3{1, 0, 0}
+	if math.Abs(float64(v.X())) > 0.9 {
+		axis = mgl32.Vec3{0, 1, 0}
+	}
+	return axis.Sub(v.Mul(v.Dot(axis))).Normalize()
+}
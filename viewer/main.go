@@ -0,0 +1,184 @@
+package main
+
+/*
+Opens a scene file and shows it, so trying out a new scene does not need any Go code.
+
+	go run . scenes/moon.json
+
+See scene/file.go for the file format. Press V to cycle between the free camera
+and any cameras in the scene.
+*/
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/viewer/gfx"
+	"github.com/cstegel/opengl-samples-golang/viewer/scene"
+	"github.com/cstegel/opengl-samples-golang/viewer/win"
+)
+
+// scene shown when none is given on the command line
+const defaultSceneFile = "scenes/moon.json"
+
+// must match MAX_POINT_LIGHTS in shaders/phong.frag
+const maxPointLights = 8
+
+func init() {
+	// GLFW event handling must be run on the main OS thread
+	runtime.LockOSThread()
+}
+
+func main() {
+	sceneFile := defaultSceneFile
+	if len(os.Args) > 1 {
+		sceneFile = os.Args[1]
+	}
+
+	if err := glfw.Init(); err != nil {
+		log.Fatalln("failed to inifitialize glfw:", err)
+	}
+	defer glfw.Terminate()
+
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+
+	window := win.NewWindow(1280, 720, "Viewer - "+filepath.Base(sceneFile))
+
+	// Initialize Glow (go function bindings)
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	err := programLoop(window, sceneFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func programLoop(window *win.Window, sceneFile string) error {
+
+	world, err := scene.Load(sceneFile, window.InputManager())
+	if err != nil {
+		return err
+	}
+	defer world.Delete()
+
+	// -1 is the free camera, otherwise an index into the scene's cameras
+	cameraIndex := -1
+
+	// ensure that triangles that are "behind" others do not draw over top of them
+	gl.Enable(gl.DEPTH_TEST)
+
+	for !window.ShouldClose() {
+
+		// swaps in last buffer, polls for window events, and generally sets up for a new render frame
+		window.StartFrame()
+
+		// update camera position and direction from input evevnts
+		world.Camera.Update(window.SinceLastFrame())
+		world.Animate(window.SinceLastFrame())
+
+		cameras := world.Cameras()
+		if window.InputManager().IsTriggered(win.SWITCH_CAMERA) {
+			cameraIndex++
+			if cameraIndex >= len(cameras) {
+				cameraIndex = -1
+			}
+		}
+		// a camera node could have been hidden since the last frame
+		if cameraIndex >= len(cameras) {
+			cameraIndex = -1
+		}
+
+		aspect := float32(window.Width()) / float32(window.Height())
+		camTransform := world.Camera.GetTransform()
+		projectTransform := world.Lens.Projection(aspect)
+		if cameraIndex >= 0 {
+			camTransform = cameras[cameraIndex].ViewTransform()
+			projectTransform = cameras[cameraIndex].Camera.Projection(aspect)
+		}
+
+		bg := world.Background
+		gl.ClearColor(bg.X(), bg.Y(), bg.Z(), 1.0)
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT) // depth buffer needed for DEPTH_TEST
+
+		lights := world.Lights()
+
+		// each program draws the nodes that asked for it
+		for _, program := range world.Programs {
+			program.Use()
+			gl.UniformMatrix4fv(program.GetUniformLocation("view"), 1, false, &camTransform[0])
+			gl.UniformMatrix4fv(program.GetUniformLocation("project"), 1, false, &projectTransform[0])
+			setLightUniforms(program, lights, camTransform)
+
+			prog := program
+			world.Draw(program, func(n *scene.Node) bool {
+				if n.Program != prog {
+					return false
+				}
+				if err = n.Material.Bind(prog, "material"); err != nil {
+					return false
+				}
+				// for the unlit shader, lights show their own color
+				color := n.Material.Diffuse
+				if n.PointLight != nil {
+					color = n.PointLight.Diffuse
+				} else if n.SpotLight != nil {
+					color = n.SpotLight.Diffuse
+				}
+				gl.Uniform3f(prog.GetUniformLocation("lightColor"), color.X(), color.Y(), color.Z())
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// end of draw loop
+	}
+
+	return nil
+}
+
+/*
+ * Uploads the first directional light, up to maxPointLights point lights and the first
+ * spot light. The lights convert themselves to view space using the camera transform.
+ */
+func setLightUniforms(program *gfx.Program, lights scene.Lights, camTransform mgl32.Mat4) {
+	gl.Uniform1i(program.GetUniformLocation("hasDirLight"), boolToInt(len(lights.Directional) > 0))
+	if len(lights.Directional) > 0 {
+		lights.Directional[0].SetUniforms(program, "dirLight", camTransform)
+	}
+
+	numPointLights := len(lights.Point)
+	if numPointLights > maxPointLights {
+		numPointLights = maxPointLights
+	}
+	for i := 0; i < numPointLights; i++ {
+		lights.Point[i].SetUniforms(program, fmt.Sprintf("pointLights[%d]", i), camTransform)
+	}
+	gl.Uniform1i(program.GetUniformLocation("numPointLights"), int32(numPointLights))
+
+	gl.Uniform1i(program.GetUniformLocation("hasSpotLight"), boolToInt(len(lights.Spot) > 0))
+	if len(lights.Spot) > 0 {
+		lights.Spot[0].SetUniforms(program, "spotLight", camTransform)
+	}
+}
+
+func boolToInt(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
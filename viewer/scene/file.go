@@ -0,0 +1,825 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/viewer/cam"
+	"github.com/cstegel/opengl-samples-golang/viewer/gfx"
+	"github.com/cstegel/opengl-samples-golang/viewer/win"
+)
+
+/*
+Scene files are JSON objects with these sections, all optional:
+
+	{
+		"camera":     {"position": [0, 0, 3], "yaw": -90, "pitch": 0, "fov": 60, "near": 0.1, "far": 100},
+		"background": [0, 0, 0],
+		"shaders":    [{"name": "toon", "vertex": "toon.vert", "fragment": "toon.frag"}],
+		"meshes":     [{"name": "ball", "primitive": "sphere", "rings": 16, "segments": 32}],
+		"materials":  [{"name": "gold", "preset": "gold"}],
+		"nodes": [
+			{"name": "table", "mesh": "ball", "material": "gold", "position": [0, -1, 0], "scale": 2},
+			{"name": "lamp", "parent": "table", "position": [0, 1, 0],
+			 "light": {"type": "point", "diffuse": [1, 1, 1], "range": 20}}
+		]
+	}
+
+camera is where the free camera starts and its lens. meshes are built in primitives: "cube",
+"plane" (with "uvScale") or "sphere" (with "rings" and "segments"). materials use the format
+of gfx.Material. shaders add to or replace the built in "phong" (lit, the default) and "unlit"
+(flat color) programs and must follow the same uniform names.
+
+nodes form the hierarchy. A parent must be listed before its children. Besides "mesh",
+"material" and "shader" (names from the sections above) a node can have:
+
+	"position": [x, y, z], "rotation": [x, y, z] (degrees), "scale": s or [x, y, z],
+	"lookAt": [x, y, z] (instead of rotation, points -z at a spot in the parent's space),
+	"spin": [x, y, z] (degrees per second around the node's own axes), "hidden": true,
+	"light": {"type": "directional" | "point" | "spot", "ambient", "diffuse", "specular",
+	          "direction" (default [0, 0, -1]), "range", "innerCutoff", "outerCutoff"},
+	"camera": {"fov", "near", "far"}
+
+Paths are relative to the scene file. Errors are reported as file:line:column.
+*/
+
+// Loaded is a scene built from a scene file along with what it needs to be drawn.
+type Loaded struct {
+	*Scene
+
+	Camera     *cam.FpsCamera // free camera, starts where the file says
+	Lens       Camera         // lens for Camera
+	Background mgl32.Vec3
+
+	// every program used by at least one node, in a stable order
+	Programs []*gfx.Program
+
+	meshes   []*gfx.VertexArray
+	spinners []spinner
+}
+
+type spinner struct {
+	node *Node
+	spin mgl32.Vec3 // degrees per second
+}
+
+// FileError is a problem with a scene file at a given position.
+type FileError struct {
+	File   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %v", e.File, e.Line, e.Column, e.Err)
+}
+
+// built in programs, relative to the working directory like the shaders of every sample
+var defaultShaders = []shaderDesc{
+	{Name: "phong", Vertex: "shaders/phong.vert", Fragment: "shaders/phong.frag"},
+	{Name: "unlit", Vertex: "shaders/phong.vert", Fragment: "shaders/light.frag"},
+}
+
+// what Load creates on the GPU, tests replace them since they have no GL context
+var (
+	uploadMesh     = (*gfx.Mesh).Upload
+	compileProgram = compileShaders
+)
+
+// used for nodes with a mesh and no material
+var defaultMaterial = gfx.Material{
+	Name:      "default",
+	Ambient:   mgl32.Vec3{0.8, 0.8, 0.8},
+	Diffuse:   mgl32.Vec3{0.8, 0.8, 0.8},
+	Specular:  mgl32.Vec3{0.5, 0.5, 0.5},
+	Shininess: 32,
+}
+
+// the sections of a scene file before they are decoded
+type fileDesc struct {
+	camera     *entry
+	background *entry
+	shaders    []entry
+	meshes     []entry
+	materials  []entry
+	nodes      []entry
+}
+
+// entry is one JSON value from the file and where it starts
+type entry struct {
+	raw    json.RawMessage
+	offset int
+}
+
+type cameraDesc struct {
+	Position *mgl32.Vec3 `json:"position"`
+	Yaw      *float64    `json:"yaw"`
+	Pitch    float64     `json:"pitch"`
+	Camera
+}
+
+type shaderDesc struct {
+	Name     string `json:"name"`
+	Vertex   string `json:"vertex"`
+	Fragment string `json:"fragment"`
+}
+
+type meshDesc struct {
+	Name      string  `json:"name"`
+	Primitive string  `json:"primitive"`
+	UVScale   float32 `json:"uvScale"`
+	Rings     int     `json:"rings"`
+	Segments  int     `json:"segments"`
+}
+
+type nodeDesc struct {
+	Name     string        `json:"name"`
+	Parent   string        `json:"parent"`
+	Position mgl32.Vec3    `json:"position"`
+	Rotation *mgl32.Vec3   `json:"rotation"`
+	LookAt   *mgl32.Vec3   `json:"lookAt"`
+	Scale    *vec3OrScalar `json:"scale"`
+	Spin     *mgl32.Vec3   `json:"spin"`
+	Hidden   bool          `json:"hidden"`
+	Mesh     string        `json:"mesh"`
+	Material string        `json:"material"`
+	Shader   string        `json:"shader"`
+	Light    *lightDesc    `json:"light"`
+	Camera   *Camera       `json:"camera"`
+}
+
+type lightDesc struct {
+	Type        string      `json:"type"`
+	Direction   *mgl32.Vec3 `json:"direction"`
+	Ambient     mgl32.Vec3  `json:"ambient"`
+	Diffuse     mgl32.Vec3  `json:"diffuse"`
+	Specular    *mgl32.Vec3 `json:"specular"` // defaults to diffuse
+	Range       float32     `json:"range"`
+	InnerCutoff float32     `json:"innerCutoff"`
+	OuterCutoff float32     `json:"outerCutoff"`
+}
+
+// vec3OrScalar is either [x, y, z] or a single number used for all three
+type vec3OrScalar mgl32.Vec3
+
+func (v *vec3OrScalar) UnmarshalJSON(data []byte) error {
+	var s float32
+	if err := json.Unmarshal(data, &s); err == nil {
+		*v = vec3OrScalar{s, s, s}
+		return nil
+	}
+	var vec mgl32.Vec3
+	if err := json.Unmarshal(data, &vec); err != nil {
+		return fmt.Errorf("expected a number or [x, y, z], got %s", data)
+	}
+	*v = vec3OrScalar(vec)
+	return nil
+}
+
+// Load reads a scene file and builds it. The GL context must be current since meshes and
+// shaders are created on the GPU. im is used for the free camera.
+func Load(file string, im *win.InputManager) (*Loaded, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{file: file, dir: filepath.Dir(file), data: data}
+	desc, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, err := p.build(desc, im)
+	if err != nil {
+		if loaded != nil {
+			loaded.Delete()
+		}
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// Animate advances the spinning nodes by dt seconds.
+func (l *Loaded) Animate(dt float64) {
+	for _, s := range l.spinners {
+		degrees := s.spin.Len() * float32(dt)
+		s.node.Rotate(mgl32.DegToRad(degrees), s.spin)
+	}
+}
+
+// Delete frees the meshes and programs created by Load.
+func (l *Loaded) Delete() {
+	for _, mesh := range l.meshes {
+		mesh.Delete()
+	}
+	for _, prog := range l.Programs {
+		prog.Delete()
+	}
+}
+
+type parser struct {
+	file string
+	dir  string
+	data []byte
+}
+
+func (p *parser) errorf(offset int, format string, args ...interface{}) error {
+	line, col := 1, 1
+	if offset > len(p.data) {
+		offset = len(p.data)
+	}
+	for _, c := range p.data[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return &FileError{File: p.file, Line: line, Column: col, Err: fmt.Errorf(format, args...)}
+}
+
+// wrap positions an error from encoding/json, base is where the decoded data starts in the file
+func (p *parser) wrap(err error, base int) error {
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		// Offset is just past the character that is wrong
+		offset := base + int(e.Offset)
+		if offset >= len(p.data) {
+			return p.errorf(len(p.data), "unexpected end of file")
+		}
+		return p.errorf(offset-1, "%v", e)
+	case *json.UnmarshalTypeError:
+		return p.errorf(base+int(e.Offset), "%s should be %v, got %s", e.Field, e.Type, e.Value)
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return p.errorf(len(p.data), "unexpected end of file")
+	}
+	return p.errorf(base, "%v", err)
+}
+
+// skipSpace finds the start of the next value from the end of the last token,
+// which is what json.Decoder.InputOffset returns
+func (p *parser) skipSpace(offset int) int {
+	for offset < len(p.data) && strings.IndexByte(" \t\r\n,:", p.data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func (p *parser) parse() (*fileDesc, error) {
+	desc := fileDesc{}
+	dec := json.NewDecoder(bytes.NewReader(p.data))
+
+	if err := p.expectDelim(dec, '{', "the scene should be a JSON object"); err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for dec.More() {
+		keyOffset := p.skipSpace(int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, p.wrap(err, 0)
+		}
+		key := tok.(string) // object keys are always strings
+
+		if seen[key] {
+			return nil, p.errorf(keyOffset, "duplicate section %q", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "camera":
+			desc.camera, err = p.value(dec)
+		case "background":
+			desc.background, err = p.value(dec)
+		case "shaders":
+			desc.shaders, err = p.array(dec, key)
+		case "meshes":
+			desc.meshes, err = p.array(dec, key)
+		case "materials":
+			desc.materials, err = p.array(dec, key)
+		case "nodes":
+			desc.nodes, err = p.array(dec, key)
+		default:
+			return nil, p.errorf(keyOffset, "unknown section %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, p.wrap(err, 0)
+	}
+	end := p.skipSpace(int(dec.InputOffset()))
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, p.errorf(end, "unexpected data after the scene")
+	}
+
+	return &desc, nil
+}
+
+func (p *parser) expectDelim(dec *json.Decoder, delim json.Delim, msg string) error {
+	offset := p.skipSpace(int(dec.InputOffset()))
+	tok, err := dec.Token()
+	if err != nil {
+		return p.wrap(err, 0)
+	}
+	if tok != delim {
+		return p.errorf(offset, "%s", msg)
+	}
+	return nil
+}
+
+func (p *parser) value(dec *json.Decoder) (*entry, error) {
+	e := entry{offset: p.skipSpace(int(dec.InputOffset()))}
+	if err := dec.Decode(&e.raw); err != nil {
+		return nil, p.wrap(err, 0)
+	}
+	return &e, nil
+}
+
+func (p *parser) array(dec *json.Decoder, section string) ([]entry, error) {
+	if err := p.expectDelim(dec, '[', section+" should be an array"); err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for dec.More() {
+		e, err := p.value(dec)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+
+	// closing ]
+	if _, err := dec.Token(); err != nil {
+		return nil, p.wrap(err, 0)
+	}
+	return entries, nil
+}
+
+// decode unmarshals an entry rejecting unknown fields so typos do not go unnoticed
+func (p *parser) decode(e entry, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(e.raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	const unknownField = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownField) {
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(msg, unknownField))
+		if unquoteErr == nil {
+			return p.errorf(p.keyOffset(e, field), "unknown field %q", field)
+		}
+	}
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		return p.typeError(e, typeErr)
+	}
+	return p.wrap(err, e.offset)
+}
+
+// typeError points at the field rather than the end of its value, nested fields get their top
+// level one and a whole entry of the wrong type gets its start
+func (p *parser) typeError(e entry, err *json.UnmarshalTypeError) error {
+	if err.Field == "" {
+		return p.errorf(e.offset, "expected %v, got %s", err.Type, err.Value)
+	}
+	field := strings.SplitN(err.Field, ".", 2)[0]
+	return p.errorf(p.keyOffset(e, field), "%s should be %v, got %s", err.Field, err.Type, err.Value)
+}
+
+// keyOffset finds where key is in the entry's object, or the start of the entry if it is not
+// a direct member. Used to point errors at the field that caused them.
+func (p *parser) keyOffset(e entry, key string) int {
+	dec := json.NewDecoder(bytes.NewReader(e.raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return e.offset
+	}
+	for dec.More() {
+		offset := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if tok == key {
+			return p.skipSpace(e.offset + offset)
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			break
+		}
+	}
+	return e.offset
+}
+
+// path resolves a file named in the scene file
+func (p *parser) path(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(p.dir, file)
+}
+
+func (p *parser) build(desc *fileDesc, im *win.InputManager) (*Loaded, error) {
+	loaded := Loaded{Scene: New()}
+
+	camDesc := cameraDesc{}
+	if desc.camera != nil {
+		if err := p.decode(*desc.camera, &camDesc); err != nil {
+			return nil, err
+		}
+	}
+	if camDesc.Position == nil {
+		camDesc.Position = &mgl32.Vec3{0, 0, 3}
+	}
+	if camDesc.Yaw == nil {
+		yaw := -90.0
+		camDesc.Yaw = &yaw
+	}
+	loaded.Lens = camDesc.Camera
+	if desc.camera != nil {
+		if err := p.checkCamera(&loaded.Lens, desc.camera.offset); err != nil {
+			return nil, err
+		}
+	} else {
+		setCameraDefaults(&loaded.Lens)
+	}
+	loaded.Camera = cam.NewFpsCamera(*camDesc.Position, mgl32.Vec3{0, 1, 0}, *camDesc.Yaw, camDesc.Pitch, im)
+
+	if desc.background != nil {
+		if err := p.decode(*desc.background, &loaded.Background); err != nil {
+			return nil, err
+		}
+	}
+
+	shaders, err := p.shaders(desc.shaders)
+	if err != nil {
+		return nil, err
+	}
+
+	meshes, err := p.meshes(desc.meshes, &loaded)
+	if err != nil {
+		return &loaded, err
+	}
+
+	materials, err := p.materials(desc.materials)
+	if err != nil {
+		return &loaded, err
+	}
+
+	// programs are only compiled once a node uses them so unused shaders cost nothing
+	programs := map[string]*gfx.Program{}
+	program := func(name string) (*gfx.Program, error) {
+		if prog, ok := programs[name]; ok {
+			return prog, nil
+		}
+		s := shaders[name]
+		prog, err := compileProgram(s.desc)
+		if err != nil {
+			if s.entry == nil {
+				return nil, err
+			}
+			return nil, p.errorf(s.entry.offset, "shader %q: %v", name, err)
+		}
+		programs[name] = prog
+		loaded.Programs = append(loaded.Programs, prog)
+		return prog, nil
+	}
+
+	nodes := map[string]*Node{}
+	for _, e := range desc.nodes {
+		nd := nodeDesc{}
+		if err := p.decode(e, &nd); err != nil {
+			return &loaded, err
+		}
+
+		if nd.Name == "" {
+			return &loaded, p.errorf(e.offset, "node has no name")
+		}
+		if _, exists := nodes[nd.Name]; exists {
+			return &loaded, p.errorf(p.keyOffset(e, "name"), "duplicate node %q", nd.Name)
+		}
+
+		node := NewNode(nd.Name)
+		node.Hidden = nd.Hidden
+
+		parent := loaded.Root
+		if nd.Parent != "" {
+			var ok bool
+			if parent, ok = nodes[nd.Parent]; !ok {
+				return &loaded, p.errorf(p.keyOffset(e, "parent"),
+					"parent %q is not defined above this node", nd.Parent)
+			}
+		}
+		parent.AddChild(node)
+
+		node.SetPosition(nd.Position)
+		if nd.Rotation != nil && nd.LookAt != nil {
+			return &loaded, p.errorf(p.keyOffset(e, "lookAt"), "a node can have rotation or lookAt, not both")
+		}
+		if nd.Rotation != nil {
+			r := nd.Rotation
+			node.SetRotation(mgl32.AnglesToQuat(mgl32.DegToRad(r[0]), mgl32.DegToRad(r[1]),
+				mgl32.DegToRad(r[2]), mgl32.XYZ))
+		}
+		if nd.LookAt != nil {
+			if nd.LookAt.ApproxEqual(nd.Position) {
+				return &loaded, p.errorf(p.keyOffset(e, "lookAt"), "lookAt is the node's own position")
+			}
+			node.LookAt(*nd.LookAt, mgl32.Vec3{0, 1, 0})
+		}
+		if nd.Scale != nil {
+			node.SetScale(mgl32.Vec3(*nd.Scale))
+		}
+		if nd.Spin != nil && nd.Spin.Len() > 0 {
+			loaded.spinners = append(loaded.spinners, spinner{node: node, spin: *nd.Spin})
+		}
+
+		if nd.Mesh != "" {
+			if node.Mesh = meshes[nd.Mesh]; node.Mesh == nil {
+				return &loaded, p.errorf(p.keyOffset(e, "mesh"), "unknown mesh %q", nd.Mesh)
+			}
+
+			node.Material = &defaultMaterial
+			if nd.Material != "" {
+				if node.Material = materials[nd.Material]; node.Material == nil {
+					return &loaded, p.errorf(p.keyOffset(e, "material"), "unknown material %q", nd.Material)
+				}
+			}
+
+			shader := nd.Shader
+			if shader == "" {
+				shader = "phong"
+			}
+			if _, ok := shaders[shader]; !ok {
+				return &loaded, p.errorf(p.keyOffset(e, "shader"), "unknown shader %q", shader)
+			}
+			if node.Program, err = program(shader); err != nil {
+				return &loaded, err
+			}
+		} else if nd.Material != "" || nd.Shader != "" {
+			field := "material"
+			if nd.Material == "" {
+				field = "shader"
+			}
+			return &loaded, p.errorf(p.keyOffset(e, field), "%s needs a mesh to apply to", field)
+		}
+
+		if nd.Light != nil {
+			if err := p.attachLight(node, nd.Light, e); err != nil {
+				return &loaded, err
+			}
+		}
+
+		if nd.Camera != nil {
+			node.Camera = nd.Camera
+			if err := p.checkCamera(node.Camera, p.keyOffset(e, "camera")); err != nil {
+				return &loaded, err
+			}
+		}
+
+		nodes[nd.Name] = node
+	}
+
+	return &loaded, nil
+}
+
+// shaderSource is a shader from the file (entry is set) or a built in one
+type shaderSource struct {
+	desc  shaderDesc
+	entry *entry
+}
+
+func (p *parser) shaders(entries []entry) (map[string]shaderSource, error) {
+	shaders := map[string]shaderSource{}
+	for _, s := range defaultShaders {
+		shaders[s.Name] = shaderSource{desc: s}
+	}
+
+	declared := map[string]bool{}
+	for i := range entries {
+		e := &entries[i]
+		s := shaderDesc{}
+		if err := p.decode(*e, &s); err != nil {
+			return nil, err
+		}
+		if s.Name == "" {
+			return nil, p.errorf(e.offset, "shader has no name")
+		}
+		if declared[s.Name] {
+			return nil, p.errorf(p.keyOffset(*e, "name"), "duplicate shader %q", s.Name)
+		}
+		declared[s.Name] = true
+
+		for _, field := range []struct {
+			key  string
+			file *string
+		}{{"vertex", &s.Vertex}, {"fragment", &s.Fragment}} {
+			if *field.file == "" {
+				return nil, p.errorf(e.offset, "shader %q has no %s file", s.Name, field.key)
+			}
+			*field.file = p.path(*field.file)
+			if _, err := os.Stat(*field.file); err != nil {
+				return nil, p.errorf(p.keyOffset(*e, field.key), "%v", err)
+			}
+		}
+		shaders[s.Name] = shaderSource{desc: s, entry: e}
+	}
+	return shaders, nil
+}
+
+func compileShaders(s shaderDesc) (*gfx.Program, error) {
+	vertShader, err := gfx.NewShaderFromFile(s.Vertex, gl.VERTEX_SHADER)
+	if err != nil {
+		return nil, err
+	}
+	fragShader, err := gfx.NewShaderFromFile(s.Fragment, gl.FRAGMENT_SHADER)
+	if err != nil {
+		return nil, err
+	}
+	return gfx.NewProgram(vertShader, fragShader)
+}
+
+func (p *parser) meshes(entries []entry, loaded *Loaded) (map[string]*gfx.VertexArray, error) {
+	meshes := map[string]*gfx.VertexArray{}
+	for _, e := range entries {
+		md := meshDesc{}
+		if err := p.decode(e, &md); err != nil {
+			return nil, err
+		}
+		if md.Name == "" {
+			return nil, p.errorf(e.offset, "mesh has no name")
+		}
+		if _, exists := meshes[md.Name]; exists {
+			return nil, p.errorf(p.keyOffset(e, "name"), "duplicate mesh %q", md.Name)
+		}
+
+		var mesh *gfx.Mesh
+		switch md.Primitive {
+		case "cube":
+			mesh = gfx.NewCubeMesh()
+		case "plane":
+			if md.UVScale == 0 {
+				md.UVScale = 1
+			}
+			mesh = gfx.NewPlaneMesh(md.UVScale)
+		case "sphere":
+			if md.Rings == 0 {
+				md.Rings = 16
+			}
+			if md.Segments == 0 {
+				md.Segments = 32
+			}
+			mesh = gfx.NewSphereMesh(md.Rings, md.Segments)
+		case "":
+			return nil, p.errorf(e.offset, "mesh %q has no primitive", md.Name)
+		default:
+			return nil, p.errorf(p.keyOffset(e, "primitive"),
+				"unknown primitive %q, expected cube, plane or sphere", md.Primitive)
+		}
+
+		va, err := uploadMesh(mesh)
+		if err != nil {
+			return nil, p.errorf(e.offset, "mesh %q: %v", md.Name, err)
+		}
+		meshes[md.Name] = va
+		loaded.meshes = append(loaded.meshes, va)
+	}
+	return meshes, nil
+}
+
+func (p *parser) materials(entries []entry) (map[string]*gfx.Material, error) {
+	materials := map[string]*gfx.Material{}
+	for _, e := range entries {
+		// gfx.ParseMaterial ignores fields it does not know like json.Unmarshal, checking the
+		// entry strictly first catches typos and leaves an unknown preset as its only error
+		var check gfx.Material
+		if err := p.decode(e, &check); err != nil {
+			return nil, err
+		}
+		mat, err := gfx.ParseMaterial(e.raw, p.dir)
+		if err != nil {
+			return nil, p.errorf(p.keyOffset(e, "preset"), "%v", err)
+		}
+		if mat.Name == "" {
+			return nil, p.errorf(e.offset, "material has no name")
+		}
+		if _, exists := materials[mat.Name]; exists {
+			return nil, p.errorf(p.keyOffset(e, "name"), "duplicate material %q", mat.Name)
+		}
+
+		// textures are loaded on first use, check that they exist now so the error has a position
+		for key, file := range map[string]string{"diffuseMap": mat.DiffuseMap, "specularMap": mat.SpecularMap} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				return nil, p.errorf(p.keyOffset(e, key), "%v", err)
+			}
+		}
+		materials[mat.Name] = mat
+	}
+	return materials, nil
+}
+
+func (p *parser) attachLight(node *Node, ld *lightDesc, e entry) error {
+	at := p.keyOffset(e, "light")
+
+	direction := mgl32.Vec3{0, 0, -1}
+	if ld.Direction != nil {
+		if ld.Direction.Len() == 0 {
+			return p.errorf(at, "light direction can not be [0, 0, 0]")
+		}
+		direction = ld.Direction.Normalize()
+	}
+	specular := ld.Diffuse
+	if ld.Specular != nil {
+		specular = *ld.Specular
+	}
+	if ld.Range < 0 {
+		return p.errorf(at, "light range must be positive")
+	}
+	if ld.Range == 0 {
+		ld.Range = 50
+	}
+
+	switch ld.Type {
+	case "directional":
+		node.DirectionalLight = &gfx.DirectionalLight{
+			Direction: direction,
+			Ambient:   ld.Ambient,
+			Diffuse:   ld.Diffuse,
+			Specular:  specular,
+		}
+	case "point":
+		node.PointLight = &gfx.PointLight{
+			Ambient:     ld.Ambient,
+			Diffuse:     ld.Diffuse,
+			Specular:    specular,
+			Attenuation: gfx.AttenuationForDistance(ld.Range),
+		}
+	case "spot":
+		if ld.OuterCutoff == 0 {
+			ld.OuterCutoff = ld.InnerCutoff + 5
+		}
+		// the light fades out between the cutoffs, the shader divides by the difference
+		if ld.InnerCutoff <= 0 || ld.InnerCutoff >= ld.OuterCutoff || ld.OuterCutoff >= 90 {
+			return p.errorf(at, "spot light needs 0 < innerCutoff < outerCutoff < 90 degrees")
+		}
+		node.SpotLight = &gfx.SpotLight{
+			Direction:   direction,
+			InnerCutoff: ld.InnerCutoff,
+			OuterCutoff: ld.OuterCutoff,
+			Ambient:     ld.Ambient,
+			Diffuse:     ld.Diffuse,
+			Specular:    specular,
+			Attenuation: gfx.AttenuationForDistance(ld.Range),
+		}
+	case "":
+		return p.errorf(at, "light has no type")
+	default:
+		return p.errorf(at, "unknown light type %q, expected directional, point or spot", ld.Type)
+	}
+	return nil
+}
+
+func setCameraDefaults(c *Camera) {
+	if c.Fov == 0 {
+		c.Fov = 60
+	}
+	if c.Near == 0 {
+		c.Near = 0.1
+	}
+	if c.Far == 0 {
+		c.Far = 100
+	}
+}
+
+func (p *parser) checkCamera(c *Camera, offset int) error {
+	setCameraDefaults(c)
+	if c.Fov <= 0 || c.Fov >= 180 {
+		return p.errorf(offset, "camera fov must be between 0 and 180 degrees")
+	}
+	if c.Near <= 0 || c.Far <= c.Near {
+		return p.errorf(offset, "camera needs 0 < near < far")
+	}
+	return nil
+}
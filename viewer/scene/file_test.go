@@ -0,0 +1,399 @@
+package scene
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+
+	"github.com/cstegel/opengl-samples-golang/viewer/gfx"
+)
+
+func init() {
+	// there is no GL context in tests and nothing is drawn, placeholders are enough
+	uploadMesh = func(*gfx.Mesh) (*gfx.VertexArray, error) {
+		return &gfx.VertexArray{}, nil
+	}
+	compileProgram = func(shaderDesc) (*gfx.Program, error) {
+		return &gfx.Program{}, nil
+	}
+}
+
+// loadString is Load for a scene file that is not on disk
+func loadString(data string) (*Loaded, error) {
+	p := parser{file: "test.json", dir: ".", data: []byte(data)}
+	desc, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return p.build(desc, nil)
+}
+
+func TestLoad(t *testing.T) {
+	loaded, err := loadString(`{
+  "camera": {"position": [1, 2, 3], "yaw": 0, "fov": 45},
+  "background": [0.1, 0.2, 0.3],
+  "meshes": [{"name": "box", "primitive": "cube"}],
+  "materials": [{"name": "gold", "preset": "gold"}],
+  "nodes": [
+    {"name": "table", "mesh": "box", "material": "gold", "position": [0, -1, 0], "scale": 2},
+    {"name": "lamp", "parent": "table", "position": [0, 1, 0], "mesh": "box", "shader": "unlit",
+     "light": {"type": "point", "diffuse": [1, 1, 1], "range": 20}},
+    {"name": "spinner", "spin": [0, 90, 0], "hidden": true},
+    {"name": "eye", "parent": "spinner", "lookAt": [0, 0, -1], "camera": {"fov": 75}}
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Lens.Fov != 45 || loaded.Lens.Near != 0.1 || loaded.Lens.Far != 100 {
+		t.Errorf("lens is %+v, want fov 45 with the default near and far", loaded.Lens)
+	}
+	if loaded.Background != (mgl32.Vec3{0.1, 0.2, 0.3}) {
+		t.Errorf("background is %v", loaded.Background)
+	}
+
+	table := loaded.Find("table")
+	lamp := loaded.Find("lamp")
+	if table == nil || lamp == nil {
+		t.Fatal("table or lamp is missing")
+	}
+	if table.Parent() != loaded.Root || lamp.Parent() != table {
+		t.Error("lamp should be under table and table under the root")
+	}
+	if table.Scale() != (mgl32.Vec3{2, 2, 2}) {
+		t.Errorf("table scale is %v, want 2 on every axis", table.Scale())
+	}
+	if table.Mesh == nil || table.Material == nil || table.Material.Name != "gold" {
+		t.Error("table should have the box mesh and the gold material")
+	}
+	if lamp.PointLight == nil || lamp.Material != &defaultMaterial {
+		t.Error("lamp should have a point light and the default material")
+	}
+	if !lamp.WorldPosition().ApproxEqual(mgl32.Vec3{0, 1, 0}) {
+		t.Errorf("lamp is at %v, want [0 1 0]", lamp.WorldPosition())
+	}
+
+	spinner := loaded.Find("spinner")
+	if spinner == nil || !spinner.Hidden || len(loaded.spinners) != 1 {
+		t.Error("spinner should be hidden and spinning")
+	}
+	if eye := loaded.Find("eye"); eye == nil || eye.Camera == nil || eye.Camera.Fov != 75 {
+		t.Error("eye should have a camera with a fov of 75")
+	}
+
+	// the phong program for table and the unlit one for lamp
+	if len(loaded.Programs) != 2 {
+		t.Errorf("%d programs were compiled, want 2", len(loaded.Programs))
+	}
+}
+
+func TestLoadSceneFiles(t *testing.T) {
+	for _, file := range []string{"../scenes/moon.json"} {
+		if _, err := Load(file, nil); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		pos  string // line:column
+		msg  string
+	}{
+		{
+			"not an object",
+			`[]`,
+			"1:1", "the scene should be a JSON object",
+		},
+		{
+			"unknown section",
+			`{
+  "camera": {},
+  "lights": []
+}`,
+			"3:3", `unknown section "lights"`,
+		},
+		{
+			"duplicate section",
+			`{"nodes": [],
+  "nodes": []}`,
+			"2:3", `duplicate section "nodes"`,
+		},
+		{
+			"section is not an array",
+			`{"meshes": {"name": "box"}}`,
+			"1:12", "meshes should be an array",
+		},
+		{
+			"syntax error",
+			`{
+  "nodes": [
+    {"name": "a" "position": [0, 0, 0]}
+  ]
+}`,
+			"3:18", "invalid character",
+		},
+		{
+			"unexpected end of file",
+			`{
+  "nodes": [`,
+			"2:13", "unexpected end of file",
+		},
+		{
+			"data after the scene",
+			`{} {}`,
+			"1:4", "unexpected data after the scene",
+		},
+		{
+			"unknown field",
+			`{
+  "nodes": [
+    {"name": "a",
+     "positon": [0, 0, 0]}
+  ]
+}`,
+			"4:6", `unknown field "positon"`,
+		},
+		{
+			"unknown nested field",
+			`{
+  "nodes": [
+    {"name": "a", "light": {"type": "point", "rnage": 5}}
+  ]
+}`,
+			"3:5", `unknown field "rnage"`,
+		},
+		{
+			"unknown camera field",
+			`{
+  "camera": {"fov": 60, "zoom": 2}
+}`,
+			"2:25", `unknown field "zoom"`,
+		},
+		{
+			"bad type",
+			`{
+  "nodes": [
+    {"name": "a", "position": "up"}
+  ]
+}`,
+			"3:19", "position should be mgl32.Vec3, got string",
+		},
+		{
+			"bad nested type",
+			`{
+  "nodes": [
+    {"name": "a",
+     "light": {"type": "point", "range": "far"}}
+  ]
+}`,
+			"4:6", "light.range should be float32, got string",
+		},
+		{
+			"bad scale",
+			`{
+  "nodes": [{"name": "a", "scale": "big"}]
+}`,
+			"2:13", "expected a number or [x, y, z]",
+		},
+		{
+			"bad background",
+			`{
+  "background": "black"
+}`,
+			"2:17", "expected mgl32.Vec3, got string",
+		},
+		{
+			"unknown mesh",
+			`{
+  "nodes": [
+    {"name": "a", "mesh": "box"}
+  ]
+}`,
+			"3:19", `unknown mesh "box"`,
+		},
+		{
+			"unknown material",
+			`{
+  "meshes": [{"name": "box", "primitive": "cube"}],
+  "nodes": [
+    {"name": "a", "mesh": "box", "material": "gold"}
+  ]
+}`,
+			"4:34", `unknown material "gold"`,
+		},
+		{
+			"unknown shader",
+			`{
+  "meshes": [{"name": "box", "primitive": "cube"}],
+  "nodes": [
+    {"name": "a", "mesh": "box", "shader": "toon"}
+  ]
+}`,
+			"4:34", `unknown shader "toon"`,
+		},
+		{
+			"material without a mesh",
+			`{
+  "nodes": [{"name": "a", "material": "gold"}]
+}`,
+			"2:27", "material needs a mesh",
+		},
+		{
+			"unknown preset",
+			`{
+  "materials": [
+    {"name": "tin", "preset": "tin"}
+  ]
+}`,
+			"3:21", `unknown material preset "tin"`,
+		},
+		{
+			"bad material type",
+			`{
+  "materials": [
+    {"name": "tin",
+     "shininess": "high"}
+  ]
+}`,
+			"4:6", "shininess should be float32, got string",
+		},
+		{
+			"unknown material field",
+			`{
+  "materials": [
+    {"name": "tin", "preset": "chrome",
+     "shinyness": 20}
+  ]
+}`,
+			"4:6", `unknown field "shinyness"`,
+		},
+		{
+			"unknown primitive",
+			`{
+  "meshes": [{"name": "box", "primitive": "box"}]
+}`,
+			"2:30", `unknown primitive "box"`,
+		},
+		{
+			"missing shader file",
+			`{
+  "shaders": [{"name": "toon", "vertex": "toon.vert", "fragment": "toon.frag"}]
+}`,
+			"2:32", "toon.vert",
+		},
+		{
+			"missing parent",
+			`{
+  "nodes": [
+    {"name": "a", "parent": "nobody"}
+  ]
+}`,
+			"3:19", `parent "nobody" is not defined above this node`,
+		},
+		{
+			"parent listed after its child",
+			`{
+  "nodes": [
+    {"name": "child", "parent": "a"},
+    {"name": "a"}
+  ]
+}`,
+			"3:23", `parent "a" is not defined above this node`,
+		},
+		{
+			"cycle",
+			`{
+  "nodes": [
+    {"name": "a", "parent": "b"},
+    {"name": "b", "parent": "a"}
+  ]
+}`,
+			"3:19", `parent "b" is not defined above this node`,
+		},
+		{
+			"own parent",
+			`{
+  "nodes": [
+    {"name": "a", "parent": "a"}
+  ]
+}`,
+			"3:19", `parent "a" is not defined above this node`,
+		},
+		{
+			"duplicate node",
+			`{
+  "nodes": [
+    {"name": "a"},
+    {"name": "a"}
+  ]
+}`,
+			"4:6", `duplicate node "a"`,
+		},
+		{
+			"node without a name",
+			`{
+  "nodes": [
+    {"position": [0, 0, 0]}
+  ]
+}`,
+			"3:5", "node has no name",
+		},
+		{
+			"rotation and lookAt",
+			`{
+  "nodes": [{"name": "a", "rotation": [0, 0, 0], "lookAt": [0, 0, -1]}]
+}`,
+			"2:50", "rotation or lookAt",
+		},
+		{
+			"bad spot light",
+			`{
+  "nodes": [
+    {"name": "a",
+     "light": {"type": "spot", "innerCutoff": 30, "outerCutoff": 20}}
+  ]
+}`,
+			"4:6", "innerCutoff < outerCutoff",
+		},
+		{
+			"equal spot light cutoffs",
+			`{
+  "nodes": [
+    {"name": "a",
+     "light": {"type": "spot", "innerCutoff": 20, "outerCutoff": 20}}
+  ]
+}`,
+			"4:6", "innerCutoff < outerCutoff",
+		},
+		{
+			"bad camera",
+			`{
+  "camera": {"near": 10, "far": 1}
+}`,
+			"2:13", "0 < near < far",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := loadString(test.data)
+		if err == nil {
+			t.Errorf("%s: loaded without an error", test.name)
+			continue
+		}
+		fileErr, ok := err.(*FileError)
+		if !ok {
+			t.Errorf("%s: got %T %q, want a *FileError", test.name, err, err)
+			continue
+		}
+
+		prefix := "test.json:" + test.pos + ": "
+		if msg := fileErr.Error(); !strings.HasPrefix(msg, prefix) || !strings.Contains(msg, test.msg) {
+			t.Errorf("%s: got %q, want %q at %s", test.name, msg, test.msg, prefix)
+		}
+	}
+}